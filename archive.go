@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/harry/rename-by-tmdb/internal/models"
+	"github.com/harry/rename-by-tmdb/internal/services"
+	"github.com/harry/rename-by-tmdb/internal/utils"
+)
+
+// parseGroupIDs 解析逗号分隔的词组ID列表
+func parseGroupIDs(input string) ([]int, error) {
+	var ids []int
+	for _, part := range strings.Split(input, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		id, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, fmt.Errorf("无效的词组ID '%s': %v", part, err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// runExport export 命令：将词组及其替换规则导出到JSON文件
+func runExport(args []string) error {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	output := flags.String("o", "wordgroups.json", "导出文件路径")
+	groups := flags.String("group", "", "要导出的词组ID，多个用逗号分隔，不指定时导出所有词组")
+	flags.Parse(args)
+
+	groupIDs, err := parseGroupIDs(*groups)
+	if err != nil {
+		return err
+	}
+
+	wordGroupService, err := newWordGroupService()
+	if err != nil {
+		return fmt.Errorf("创建词组服务失败: %v", err)
+	}
+
+	archive, err := wordGroupService.ExportWordGroups(groupIDs)
+	if err != nil {
+		return fmt.Errorf("导出失败: %v", err)
+	}
+
+	data, err := json.MarshalIndent(archive, "", "  ")
+	if err != nil {
+		return fmt.Errorf("JSON编码失败: %v", err)
+	}
+	if err := os.WriteFile(*output, data, 0644); err != nil {
+		return fmt.Errorf("写入导出文件失败: %v", err)
+	}
+
+	units := 0
+	for _, group := range archive.Groups {
+		units += len(group.Units)
+	}
+	fmt.Printf("已导出 %d 个词组、%d 条规则到：%s\n", len(archive.Groups), units, *output)
+	return nil
+}
+
+// printImportResults 显示导入结果及词组ID映射
+func printImportResults(results []services.ImportGroupResult) {
+	actionNames := map[string]string{
+		"created":  "新建",
+		"skipped":  "跳过",
+		"merged":   "合并",
+		"replaced": "替换",
+	}
+
+	fmt.Printf("\n=== 导入结果 ===\n")
+	for _, result := range results {
+		target := "-"
+		if result.TargetID != 0 {
+			target = strconv.Itoa(result.TargetID)
+		}
+		fmt.Printf("%s：%s（ID: %d → %s）\n", actionNames[result.Action], result.Title, result.SourceID, target)
+		if result.Sync != nil {
+			fmt.Printf("  新增 %d 条，更新 %d 条，删除 %d 条，未变化 %d 条\n",
+				result.Sync.Created, result.Sync.Updated, result.Sync.Deleted, result.Sync.Unchanged)
+		}
+	}
+}
+
+// runImport import 命令：将导出文件中的词组导入当前服务器
+func runImport(args []string) error {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	input := flags.String("f", "wordgroups.json", "导出文件路径")
+	strategyFlag := flags.String("strategy", "", "词组已存在时的处理方式：skip、merge 或 replace，不指定时询问")
+	flags.Parse(args)
+
+	data, err := os.ReadFile(*input)
+	if err != nil {
+		return fmt.Errorf("读取导出文件失败: %v", err)
+	}
+	var archive models.Archive
+	if err := json.Unmarshal(data, &archive); err != nil {
+		return fmt.Errorf("解析导出文件失败: %v", err)
+	}
+	if archive.Version != models.ArchiveVersion {
+		return fmt.Errorf("不支持的导出文件版本: %d", archive.Version)
+	}
+
+	strategyName := *strategyFlag
+	if strategyName == "" {
+		strategyName, err = utils.GetImportStrategy()
+		if err != nil {
+			return fmt.Errorf("错误: %v", err)
+		}
+	}
+	strategy, err := services.ParseImportStrategy(strategyName)
+	if err != nil {
+		return err
+	}
+
+	wordGroupService, err := newWordGroupService()
+	if err != nil {
+		return fmt.Errorf("创建词组服务失败: %v", err)
+	}
+	fmt.Printf("从 %s 导入 %d 个词组到 %s\n", archive.Server, len(archive.Groups), wordGroupService.BaseURL())
+
+	tracker, audit := newRunRecorders(wordGroupService)
+	results, err := wordGroupService.ImportWordGroups(&archive, strategy)
+	printImportResults(results)
+	if err != nil {
+		return tracker.handleFailure(wordGroupService, fmt.Errorf("导入失败: %v", err))
+	}
+	printRunID(tracker, audit)
+	return nil
+}
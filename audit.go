@@ -0,0 +1,329 @@
+package main
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/harry/rename-by-tmdb/internal/models"
+	"github.com/harry/rename-by-tmdb/internal/services"
+	"github.com/harry/rename-by-tmdb/internal/utils"
+)
+
+// auditLog 将本次运行在服务器上创建和删除的词组、规则追加写入本地JSONL审计日志
+type auditLog struct {
+	path      string
+	runID     string
+	server    string
+	tmdbID    string
+	mediaType string
+}
+
+// newRunID 生成运行ID，格式为 时间-随机串，如 20240101-120000-1a2b3c4d
+func newRunID() string {
+	random := make([]byte, 4)
+	rand.Read(random)
+	return fmt.Sprintf("%s-%s", time.Now().Format("20060102-150405"), hex.EncodeToString(random))
+}
+
+// newAuditLog 创建本次运行的审计日志
+func newAuditLog(server string) (*auditLog, error) {
+	path, err := utils.GetAuditLogPath()
+	if err != nil {
+		return nil, err
+	}
+	return &auditLog{
+		path:   path,
+		runID:  newRunID(),
+		server: server,
+	}, nil
+}
+
+// setMedia 设置之后记录所属的TMDB条目
+func (a *auditLog) setMedia(tmdbID, mediaType string) {
+	a.tmdbID = tmdbID
+	a.mediaType = mediaType
+}
+
+// write 追加一条审计记录，写入失败只提示不中断上传
+func (a *auditLog) write(entry models.AuditEntry) {
+	entry.Time = time.Now()
+	entry.RunID = a.runID
+	entry.Server = a.server
+	if entry.TMDBID == "" {
+		entry.TMDBID = a.tmdbID
+		entry.MediaType = a.mediaType
+	}
+
+	if err := appendAuditEntry(a.path, entry); err != nil {
+		fmt.Printf("警告：写入审计日志失败: %v\n", err)
+	}
+}
+
+// GroupCreated 实现 services.ChangeRecorder
+func (a *auditLog) GroupCreated(group models.WordGroup) {
+	entry := models.AuditEntry{
+		Action:  models.AuditCreateGroup,
+		GroupID: group.ID,
+		Title:   group.Title,
+	}
+	// 词组标题中包含TMDB ID时以标题为准（apply 时没有运行上下文）
+	if tmdbID, mediaType, ok := utils.ParseTMDBToken(group.Title); ok {
+		entry.TMDBID, entry.MediaType = tmdbID, mediaType
+	}
+	a.write(entry)
+}
+
+// UnitCreated 实现 services.ChangeRecorder
+func (a *auditLog) UnitCreated(unit models.WordUnit) {
+	entry := models.AuditEntry{
+		Action:  models.AuditCreateUnit,
+		GroupID: unit.WordGroupID,
+		UnitID:  unit.ID,
+		Unit:    &unit,
+	}
+	if tmdbID, mediaType, ok := utils.ParseTMDBToken(unit.Replace); ok {
+		entry.TMDBID, entry.MediaType = tmdbID, mediaType
+	}
+	if note, err := models.ParseUnitNote(unit.Note); err == nil && note != nil {
+		entry.Params = note
+	}
+	a.write(entry)
+}
+
+// unitDeleted 记录删除的规则，undoOf 为被撤销的运行ID
+func (a *auditLog) unitDeleted(unit models.WordUnit, undoOf string) {
+	a.write(models.AuditEntry{
+		Action:  models.AuditDeleteUnit,
+		GroupID: unit.WordGroupID,
+		UnitID:  unit.ID,
+		UndoOf:  undoOf,
+	})
+}
+
+// groupDeleted 记录删除的词组，undoOf 为被撤销的运行ID
+func (a *auditLog) groupDeleted(group models.WordGroup, undoOf string) {
+	a.write(models.AuditEntry{
+		Action:  models.AuditDeleteGroup,
+		GroupID: group.ID,
+		Title:   group.Title,
+		UndoOf:  undoOf,
+	})
+}
+
+// appendAuditEntry 以追加方式写入一条审计记录
+func appendAuditEntry(path string, entry models.AuditEntry) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("创建审计日志目录失败: %v", err)
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("JSON编码失败: %v", err)
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("打开审计日志失败: %v", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("写入审计日志失败: %v", err)
+	}
+	return nil
+}
+
+// readAuditEntries 读取审计日志中的所有记录
+func readAuditEntries(path string) ([]models.AuditEntry, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("打开审计日志失败: %v", err)
+	}
+	defer file.Close()
+
+	var entries []models.AuditEntry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var entry models.AuditEntry
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			return nil, fmt.Errorf("解析审计日志第 %d 行失败: %v", lineNum, err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("读取审计日志失败: %v", err)
+	}
+	return entries, nil
+}
+
+// newRunRecorders 为词组服务设置本次运行的回滚记录器和审计日志
+func newRunRecorders(wordGroupService *services.WordGroupService) (*runTracker, *auditLog) {
+	tracker := &runTracker{}
+	wordGroupService.AddRecorder(tracker)
+
+	audit, err := newAuditLog(wordGroupService.BaseURL())
+	if err != nil {
+		fmt.Printf("警告：无法启用审计日志: %v\n", err)
+		return tracker, nil
+	}
+	tracker.audit = audit
+	wordGroupService.AddRecorder(audit)
+	return tracker, audit
+}
+
+// printRunID 提示本次运行的ID，用于撤销
+func printRunID(tracker *runTracker, audit *auditLog) {
+	if audit == nil || tracker.isEmpty() {
+		return
+	}
+	fmt.Printf("\n本次运行ID：%s（可使用 rename-by-tmdb undo %s 撤销本次创建的词组和规则）\n", audit.runID, audit.runID)
+}
+
+// printRecentRuns 显示审计日志中最近的运行
+func printRecentRuns(entries []models.AuditEntry, limit int) {
+	var runIDs []string
+	created := make(map[string]int)
+	for _, entry := range entries {
+		if entry.Action != models.AuditCreateGroup && entry.Action != models.AuditCreateUnit {
+			continue
+		}
+		if _, exists := created[entry.RunID]; !exists {
+			runIDs = append(runIDs, entry.RunID)
+		}
+		created[entry.RunID]++
+	}
+
+	if len(runIDs) == 0 {
+		fmt.Println("审计日志中没有任何上传记录")
+		return
+	}
+	if len(runIDs) > limit {
+		runIDs = runIDs[len(runIDs)-limit:]
+	}
+	fmt.Println("最近的运行：")
+	for _, runID := range runIDs {
+		fmt.Printf("  %s  创建 %d 项\n", runID, created[runID])
+	}
+}
+
+// runUndo undo 命令：删除指定运行创建的规则和词组（已撤销或已回滚的部分会跳过）
+func runUndo(args []string) error {
+	flags := flag.NewFlagSet("undo", flag.ExitOnError)
+	assumeYes := flags.Bool("y", false, "不再确认直接执行")
+	flags.Parse(args)
+
+	path, err := utils.GetAuditLogPath()
+	if err != nil {
+		return err
+	}
+	entries, err := readAuditEntries(path)
+	if err != nil {
+		return err
+	}
+
+	if flags.NArg() != 1 {
+		fmt.Println("用法：rename-by-tmdb undo [-y] <运行ID>")
+		printRecentRuns(entries, 10)
+		return nil
+	}
+	runID := flags.Arg(0)
+
+	// 收集该运行创建的内容，排除之后已被撤销的部分
+	deletedUnits := make(map[int]bool)
+	deletedGroups := make(map[int]bool)
+	for _, entry := range entries {
+		if entry.UndoOf != runID {
+			continue
+		}
+		switch entry.Action {
+		case models.AuditDeleteUnit:
+			deletedUnits[entry.UnitID] = true
+		case models.AuditDeleteGroup:
+			deletedGroups[entry.GroupID] = true
+		}
+	}
+
+	tracker := &runTracker{}
+	server := ""
+	found := false
+	for _, entry := range entries {
+		if entry.RunID != runID {
+			continue
+		}
+		found = true
+		server = entry.Server
+		switch entry.Action {
+		case models.AuditCreateGroup:
+			if !deletedGroups[entry.GroupID] {
+				tracker.GroupCreated(models.WordGroup{ID: entry.GroupID, Title: entry.Title})
+			}
+		case models.AuditCreateUnit:
+			if !deletedUnits[entry.UnitID] && entry.Unit != nil {
+				tracker.UnitCreated(*entry.Unit)
+			}
+		}
+	}
+	if !found {
+		return fmt.Errorf("审计日志中没有运行 %s 的记录", runID)
+	}
+	if tracker.isEmpty() {
+		fmt.Printf("运行 %s 创建的内容已全部撤销\n", runID)
+		return nil
+	}
+
+	wordGroupService, err := newWordGroupServiceForURL(server)
+	if err != nil {
+		return fmt.Errorf("创建词组服务失败: %v", err)
+	}
+	if wordGroupService.BaseURL() != server {
+		return fmt.Errorf("运行 %s 的服务器（%s）与当前配置的服务器（%s）不一致", runID, server, wordGroupService.BaseURL())
+	}
+
+	fmt.Printf("\n=== 撤销运行 %s ===\n", runID)
+	for _, group := range tracker.createdGroups {
+		fmt.Printf("  - 删除词组：%s（ID: %d，词组中还有其他规则时保留）\n", group.Title, group.ID)
+	}
+	for _, unit := range tracker.createdUnits {
+		fmt.Printf("  - 删除规则：%s → %s（ID: %d）\n", unit.BeReplaced, unit.Replace, unit.ID)
+	}
+	if !*assumeYes {
+		confirmed, err := utils.GetConfirmation("\n确认删除以上词组和规则？(y/N): ")
+		if err != nil {
+			return fmt.Errorf("错误: %v", err)
+		}
+		if !confirmed {
+			fmt.Println("已取消")
+			return nil
+		}
+	}
+
+	// 删除记录写入新的运行ID，并标明撤销的运行
+	audit, err := newAuditLog(server)
+	if err != nil {
+		return err
+	}
+	tracker.audit = audit
+	tracker.undoOf = runID
+
+	if failed := tracker.rollback(wordGroupService); failed > 0 {
+		return fmt.Errorf("撤销未完成，有 %d 项删除失败，可重新运行 undo %s 重试", failed, runID)
+	}
+	fmt.Printf("\n运行 %s 已撤销\n", runID)
+	return nil
+}
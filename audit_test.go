@@ -0,0 +1,80 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/harry/rename-by-tmdb/internal/models"
+	"github.com/harry/rename-by-tmdb/internal/services"
+	"github.com/harry/rename-by-tmdb/internal/utils"
+)
+
+// TestCreateWordUnitsRecordsUnresolved 获取规则ID失败时，已添加的规则仍记入回滚记录和审计日志
+func TestCreateWordUnitsRecordsUnresolved(t *testing.T) {
+	added := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/wordUnit/add"):
+			added++
+			// 第一条规则返回ID，第二条不返回，需要获取词组规则找回ID
+			if added == 1 {
+				w.Write([]byte(`{"code":20000,"data":{"id":11}}`))
+			} else {
+				w.Write([]byte(`{"code":20000,"data":null}`))
+			}
+		case strings.HasSuffix(r.URL.Path, "/wordUnit/page"):
+			w.Write([]byte(`{"code":50000,"message":"服务器错误"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	auditPath := filepath.Join(t.TempDir(), "audit.jsonl")
+	t.Setenv("AUDIT_LOG", auditPath)
+	wordGroupService, err := services.NewWordGroupServiceForProfile(utils.ServerProfile{
+		Name:       "test",
+		APIBaseURL: server.URL,
+		AuthToken:  "token",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tracker, audit := newRunRecorders(wordGroupService)
+	if audit == nil {
+		t.Fatal("审计日志未启用")
+	}
+
+	units := []models.WordUnit{
+		services.NewWordUnit(0, "A.S01E01", "B.S01E01", "", "", 0),
+		services.NewWordUnit(0, "A.S01E02", "B.S01E02", "", "", 0),
+	}
+	created, err := wordGroupService.CreateWordUnits(1, units)
+	if err == nil || !strings.Contains(err.Error(), "获取规则ID失败") {
+		t.Fatalf("CreateWordUnits() error = %v, 期望获取规则ID失败", err)
+	}
+	if len(created) != 2 || created[0].ID != 11 || created[1].ID != 0 {
+		t.Fatalf("CreateWordUnits() = %+v", created)
+	}
+
+	if len(tracker.createdUnits) != 2 {
+		t.Errorf("回滚记录中有 %d 条规则，期望 2 条", len(tracker.createdUnits))
+	}
+
+	entries, err := readAuditEntries(auditPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var logged []string
+	for _, entry := range entries {
+		if entry.Action == models.AuditCreateUnit && entry.RunID == audit.runID {
+			logged = append(logged, entry.Unit.BeReplaced)
+		}
+	}
+	if strings.Join(logged, ",") != "A.S01E01,A.S01E02" {
+		t.Errorf("审计日志中的规则 = %v，期望两条都已记录", logged)
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+
+	"github.com/harry/rename-by-tmdb/internal/models"
+	"github.com/harry/rename-by-tmdb/internal/utils"
+)
+
+// handleBlockWords 按配置的屏蔽词列表生成屏蔽词规则，与替换规则一起上传到同一词组
+func handleBlockWords(out io.Writer, naming mediaNaming) ([]generatedRule, error) {
+	patterns, err := utils.GetBlockWordPatterns()
+	if err != nil {
+		return nil, err
+	}
+
+	var rules []generatedRule
+	fmt.Fprintf(out, "\n=== 屏蔽词规则 ===\n")
+	for _, pattern := range patterns {
+		// 服务器的正则引擎支持的语法比Go更多，无法编译时只给出提示
+		if _, err := regexp.Compile(pattern); err != nil {
+			fmt.Fprintf(out, "注意：屏蔽词 %s 无法按Go正则语法解析，请确认服务器支持: %v\n", pattern, err)
+		}
+		fmt.Fprintf(out, "屏蔽词：%s\n", pattern)
+
+		rule, err := buildRule(naming, models.UnitNote{Mode: models.NoteModeBlock, Pattern: pattern})
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+
+	return rules, nil
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/harry/rename-by-tmdb/internal/config"
+	"github.com/harry/rename-by-tmdb/internal/models"
+	"github.com/harry/rename-by-tmdb/internal/services"
+	"github.com/harry/rename-by-tmdb/internal/utils"
+)
+
+// groupRow 表示列表中的一个词组
+type groupRow struct {
+	ID        int    `json:"id"`
+	Title     string `json:"title"`
+	TMDBID    string `json:"tmdbId,omitempty"`
+	MediaType string `json:"mediaType,omitempty"`
+}
+
+// filterWordGroups 按关键词、TMDB ID和类型筛选词组，条件为空表示不限
+func filterWordGroups(groups []models.WordGroup, keyword, tmdbID, mediaType string) []groupRow {
+	keyword = strings.ToLower(keyword)
+
+	var rows []groupRow
+	for _, group := range groups {
+		if keyword != "" && !strings.Contains(strings.ToLower(group.Title), keyword) {
+			continue
+		}
+
+		groupTMDBID, groupType, _ := utils.ParseTMDBToken(group.Title)
+		if tmdbID != "" && groupTMDBID != tmdbID {
+			continue
+		}
+		if mediaType != "" && groupType != mediaType {
+			continue
+		}
+
+		rows = append(rows, groupRow{
+			ID:        group.ID,
+			Title:     group.Title,
+			TMDBID:    groupTMDBID,
+			MediaType: groupType,
+		})
+	}
+	return rows
+}
+
+// printGroups 以表格显示词组
+func printGroups(rows []groupRow) {
+	writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(writer, "ID\tTMDB ID\t类型\t标题")
+	for _, row := range rows {
+		fmt.Fprintf(writer, "%d\t%s\t%s\t%s\n", row.ID, row.TMDBID, row.MediaType, row.Title)
+	}
+	writer.Flush()
+	fmt.Printf("\n共 %d 个词组\n", len(rows))
+}
+
+// printUnits 以表格显示替换规则
+func printUnits(units []models.WordUnit) {
+	writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(writer, "ID\tBeReplaced\tReplace\tOffset\tEnabled\tType")
+	for _, unit := range units {
+		fmt.Fprintf(writer, "%d\t%s\t%s\t%s\t%t\t%d(%s)\n",
+			unit.ID, unit.BeReplaced, unit.Replace, unit.Offset, unit.Enabled, int(unit.Type), unit.Type)
+	}
+	writer.Flush()
+	fmt.Printf("\n共 %d 条规则\n", len(units))
+}
+
+// printJSON 以JSON格式输出
+func printJSON(value interface{}) error {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return fmt.Errorf("JSON编码失败: %v", err)
+	}
+	fmt.Println(string(data))
+	return nil
+}
+
+// run 执行list命令：不指定词组时列出词组，指定词组时列出其中的替换规则
+func run() error {
+	keyword := flag.String("k", "", "按关键词筛选词组标题")
+	tmdbID := flag.String("tmdbid", "", "按TMDB ID筛选词组")
+	mediaType := flag.String("type", "", "按类型筛选词组（movie 或 tv）")
+	groupID := flag.Int("group", 0, "显示指定ID词组中的替换规则")
+	jsonOutput := flag.Bool("json", false, "以JSON格式输出")
+	server := flag.String("server", "", "服务器名称（MS_SERVERS 中的名称或 default）")
+	configFile := flag.String("config", "", "配置文件，默认为用户配置目录下的 rename-by-tmdb/config.yaml")
+	flag.Parse()
+
+	// 加载环境变量和配置文件，JSON输出时不显示加载提示
+	loadEnv := utils.LoadEnv
+	if *jsonOutput {
+		loadEnv = utils.LoadEnvQuiet
+	}
+	if _, _, err := config.Init(*configFile, nil, loadEnv); err != nil {
+		return fmt.Errorf("错误: %v", err)
+	}
+
+	if *mediaType != "" && *mediaType != "movie" && *mediaType != "tv" {
+		return fmt.Errorf("无效的类型 '%s'，应为 movie 或 tv", *mediaType)
+	}
+
+	profiles, err := utils.SelectServerProfiles(*server)
+	if err != nil {
+		return err
+	}
+	if len(profiles) != 1 {
+		return fmt.Errorf("list 只能查看一个服务器")
+	}
+	wordGroupService, err := services.NewWordGroupServiceForProfile(profiles[0])
+	if err != nil {
+		return fmt.Errorf("创建词组服务失败: %v", err)
+	}
+
+	if *groupID != 0 {
+		units, err := wordGroupService.ListWordUnits(*groupID)
+		if err != nil {
+			return fmt.Errorf("获取词组规则失败: %v", err)
+		}
+		if *jsonOutput {
+			return printJSON(units)
+		}
+		printUnits(units)
+		return nil
+	}
+
+	// 关键词交给服务器搜索，TMDB ID未指定关键词时作为搜索关键词以减少返回的词组
+	searchKeyword := *keyword
+	if searchKeyword == "" && *tmdbID != "" {
+		searchKeyword = fmt.Sprintf("tmdbid=%s;", *tmdbID)
+	}
+	groups, err := wordGroupService.ListWordGroups(searchKeyword)
+	if err != nil {
+		return fmt.Errorf("获取词组列表失败: %v", err)
+	}
+
+	rows := filterWordGroups(groups, *keyword, *tmdbID, *mediaType)
+	if *jsonOutput {
+		if rows == nil {
+			rows = []groupRow{}
+		}
+		return printJSON(rows)
+	}
+	printGroups(rows)
+	return nil
+}
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/harry/rename-by-tmdb/internal/config"
+	"github.com/harry/rename-by-tmdb/internal/utils"
+)
+
+// configPath 使用的配置文件路径，由全局参数 --config 指定或在用户配置目录下查找
+var configPath string
+
+// configSettings 各配置项最终生效的值及其来源
+var configSettings []config.Setting
+
+// configFlags 全局参数中与配置有关的部分
+type configFlags struct {
+	Path       string            // --config 指定的配置文件
+	Overrides  map[string]string // --set 覆盖的配置项
+	Preset     string            // --preset 使用的预设
+	SavePreset string            // --save-preset 保存本次回答的预设名称
+	Recipe     string            // --recipe 重放的配方文件或名称
+}
+
+// extractConfigFlags 从命令行参数中取出全局参数 --config 文件、--set 键=值（可出现多次）、--preset 名称、
+// --save-preset 名称 和 --recipe 配方，返回其余参数
+func extractConfigFlags(args []string) ([]string, configFlags, error) {
+	var rest []string
+	flags := configFlags{Overrides: make(map[string]string)}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if !strings.HasPrefix(arg, "-") || (name != "config" && name != "set" && name != "preset" && name != "save-preset" && name != "recipe") {
+			rest = append(rest, arg)
+			continue
+		}
+		if !hasValue {
+			if i+1 >= len(args) {
+				return nil, configFlags{}, fmt.Errorf("--%s 需要指定参数", name)
+			}
+			value = args[i+1]
+			i++
+		}
+
+		switch name {
+		case "config":
+			flags.Path = value
+		case "preset":
+			flags.Preset = value
+		case "save-preset":
+			flags.SavePreset = value
+		case "recipe":
+			flags.Recipe = value
+		case "set":
+			key, setValue, ok := strings.Cut(value, "=")
+			if !ok || key == "" {
+				return nil, configFlags{}, fmt.Errorf("--set 的格式应为 键=值，如 --set tmdb_api.language=en-US")
+			}
+			flags.Overrides[key] = setValue
+		}
+	}
+	return rest, flags, nil
+}
+
+// loadPreset 从配置文件或内置预设中读取预设，作为剧集交互中各问题的默认回答
+func loadPreset(name string) error {
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return err
+	}
+	answers, err := cfg.Preset(name)
+	if err != nil {
+		return err
+	}
+	utils.SetPresetAnswers(answers)
+	fmt.Printf("使用预设 %s：\n%s\n\n", name, utils.FormatAnswers(answers))
+	return nil
+}
+
+// savePreset 将本次运行的回答保存为配置文件中的预设
+func savePreset(name string) error {
+	answers := utils.RecordedAnswers()
+	if len(answers) == 0 {
+		return fmt.Errorf("本次运行没有可保存的回答")
+	}
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return err
+	}
+	if err := cfg.SetPreset(name, answers); err != nil {
+		return err
+	}
+	if err := config.Save(configPath, cfg); err != nil {
+		return err
+	}
+	fmt.Printf("\n已将本次回答保存为预设 %s（%s）\n", name, configPath)
+	return nil
+}
+
+// runConfig config 命令：show 显示各配置项的值和来源（隐藏密钥），set 修改配置文件中的配置项，presets 列出预设
+func runConfig(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("用法：rename-by-tmdb config show | config set 键 值 | config presets")
+	}
+
+	switch args[0] {
+	case "show":
+		return runConfigShow(args[1:])
+	case "set":
+		return runConfigSet(args[1:])
+	case "presets":
+		return runConfigPresets()
+	default:
+		return fmt.Errorf("未知的子命令 '%s'，应为 show、set 或 presets", args[0])
+	}
+}
+
+// runConfigShow 显示各配置项最终生效的值及其来源
+func runConfigShow(args []string) error {
+	flags := flag.NewFlagSet("config show", flag.ExitOnError)
+	showSecrets := flags.Bool("secrets", false, "显示密钥的完整内容")
+	flags.Parse(args)
+
+	fmt.Printf("配置文件：%s\n", configPath)
+	fmt.Println("优先级：命令行参数 > 环境变量（含 .env） > 配置文件 > 默认值")
+	fmt.Println()
+	for _, setting := range configSettings {
+		value := setting.Value
+		if setting.Secret && !*showSecrets {
+			value = config.Redact(value)
+		}
+		if setting.Source == "" {
+			fmt.Printf("%-26s （未设置）\n", setting.Key)
+		} else {
+			fmt.Printf("%-26s %s（%s）\n", setting.Key, value, setting.Source)
+		}
+		fmt.Printf("  %s，环境变量 %s\n", setting.Usage, setting.Env)
+	}
+
+	profiles, err := utils.GetServerProfiles()
+	if err != nil {
+		return err
+	}
+	fmt.Println()
+	fmt.Println("服务器（环境变量优先于配置文件中的 servers）：")
+	if len(profiles) == 0 {
+		fmt.Println("  （未配置）")
+	}
+	for _, profile := range profiles {
+		token, password := profile.AuthToken, profile.Password
+		if !*showSecrets {
+			token, password = config.Redact(token), config.Redact(password)
+		}
+		fmt.Printf("  %s\n", profile.Name)
+		fmt.Printf("    api_base_url  %s\n", orUnset(profile.APIBaseURL))
+		fmt.Printf("    auth_token    %s\n", orUnset(token))
+		fmt.Printf("    username      %s\n", orUnset(profile.Username))
+		fmt.Printf("    password      %s\n", orUnset(password))
+	}
+	return nil
+}
+
+// orUnset 值为空时返回（未设置）
+func orUnset(value string) string {
+	if value == "" {
+		return "（未设置）"
+	}
+	return value
+}
+
+// runConfigSet 修改配置文件中的配置项，值为空字符串时删除该配置项
+func runConfigSet(args []string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("用法：rename-by-tmdb config set 键 值（如 config set tmdb_api.language en-US）")
+	}
+	key, value := args[0], args[1]
+
+	field, err := config.FindField(key)
+	if err != nil {
+		return err
+	}
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return err
+	}
+	if err := cfg.Set(field.Key, value); err != nil {
+		return err
+	}
+	if err := config.Save(configPath, cfg); err != nil {
+		return err
+	}
+
+	if value == "" {
+		fmt.Printf("已删除配置项 %s（%s）\n", field.Key, configPath)
+	} else {
+		fmt.Printf("已设置 %s（%s）\n", field.Key, configPath)
+	}
+	for _, setting := range configSettings {
+		if setting.Key == field.Key && (setting.Source == config.SourceFlag || setting.Source == config.SourceEnv) {
+			fmt.Printf("注意：当前%s中的 %s 优先于配置文件\n", setting.Source, field.Env)
+		}
+	}
+	return nil
+}
+
+// runConfigPresets 列出内置预设和配置文件中的预设及其回答
+func runConfigPresets() error {
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return err
+	}
+	for _, name := range cfg.PresetNames() {
+		answers, _ := cfg.Preset(name)
+		source := "内置"
+		if _, exists := cfg.Presets[name]; exists {
+			source = "配置文件"
+		}
+		fmt.Printf("%s（%s）\n%s\n\n", name, source, utils.FormatAnswers(answers))
+	}
+	fmt.Println("问题标识：")
+	for _, answerKey := range utils.AnswerKeys {
+		fmt.Printf("  %-17s %s\n", answerKey.Key, answerKey.Usage)
+	}
+	return nil
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/harry/rename-by-tmdb/internal/models"
+	"github.com/harry/rename-by-tmdb/internal/services"
+	"github.com/harry/rename-by-tmdb/internal/utils"
+)
+
+// namingPrefixRegexp 从命名格式中解析名称和年份：名称.年份.{[tmdbid=ID;type=类型]}
+var namingPrefixRegexp = regexp.MustCompile(`^(.*?)\.(\d{4})?\.?\{\[tmdbid=`)
+
+// groupDrift 表示词组标题与TMDB当前信息不一致
+type groupDrift struct {
+	Group     models.WordGroup
+	TMDBID    string
+	MediaType string
+	OldName   string
+	OldYear   string
+	NewName   string
+	NewYear   string
+	NewTitle  string
+}
+
+// fetchNamingInfo 按TMDB当前信息获取名称（空格替换为点号）和年份
+func fetchNamingInfo(tmdbService *services.TMDBService, tmdbID, mediaType string) (string, string, error) {
+	var name, original, date string
+	if mediaType == "movie" {
+		movie, err := tmdbService.FetchMovieInfo(tmdbID)
+		if err != nil {
+			return "", "", err
+		}
+		name, original, date = movie.Title, movie.OriginalTitle, movie.ReleaseDate
+	} else {
+		show, err := tmdbService.FetchShowInfo(tmdbID)
+		if err != nil {
+			return "", "", err
+		}
+		name, original, date = show.Name, show.OriginalName, show.FirstAirDate
+	}
+
+	year := ""
+	if len(date) >= 4 {
+		year = date[:4]
+	}
+	return utils.FormatName(name, original), year, nil
+}
+
+// findGroupDrifts 比较服务器上所有带 tmdbid 的词组与TMDB当前信息
+func findGroupDrifts(tmdbService *services.TMDBService, wordGroupService *services.WordGroupService) ([]groupDrift, error) {
+	groups, err := wordGroupService.ListWordGroups("tmdbid=")
+	if err != nil {
+		return nil, fmt.Errorf("获取词组列表失败: %v", err)
+	}
+
+	var drifts []groupDrift
+	for _, group := range groups {
+		tmdbID, mediaType, ok := utils.ParseTMDBToken(group.Title)
+		if !ok {
+			continue
+		}
+		matches := namingPrefixRegexp.FindStringSubmatch(group.Title)
+		if matches == nil {
+			fmt.Printf("词组 %s（ID: %d）的标题不是标准命名格式，跳过\n", group.Title, group.ID)
+			continue
+		}
+
+		newName, newYear, err := fetchNamingInfo(tmdbService, tmdbID, mediaType)
+		if err != nil {
+			fmt.Printf("获取 tmdbid=%s 的TMDB信息失败: %v\n", tmdbID, err)
+			continue
+		}
+
+		newTitle := fmt.Sprintf("%s.%s.{[tmdbid=%s;type=%s]}", newName, newYear, tmdbID, mediaType)
+		if newTitle == group.Title {
+			continue
+		}
+		drifts = append(drifts, groupDrift{
+			Group:     group,
+			TMDBID:    tmdbID,
+			MediaType: mediaType,
+			OldName:   matches[1],
+			OldYear:   matches[2],
+			NewName:   newName,
+			NewYear:   newYear,
+			NewTitle:  newTitle,
+		})
+	}
+
+	return drifts, nil
+}
+
+// rewriteReplace 将替换词中的旧名称和旧年份替换为新的，返回是否有修改
+func (d *groupDrift) rewriteReplace(replace string) (string, bool) {
+	rewritten := replace
+	if strings.HasPrefix(rewritten, d.OldName+".") {
+		rewritten = d.NewName + strings.TrimPrefix(rewritten, d.OldName)
+	}
+	if d.OldYear != "" && d.NewYear != "" && d.OldYear != d.NewYear {
+		// 年份位于 tmdbid 标记之前，只替换标记前最后一处
+		tokenIndex := strings.Index(rewritten, ".{[tmdbid=")
+		if tokenIndex >= 0 {
+			head := rewritten[:tokenIndex]
+			if yearIndex := strings.LastIndex(head, "."+d.OldYear); yearIndex >= 0 {
+				head = head[:yearIndex] + "." + d.NewYear + head[yearIndex+len(d.OldYear)+1:]
+				rewritten = head + rewritten[tokenIndex:]
+			}
+		}
+	}
+	return rewritten, rewritten != replace
+}
+
+// rewriteFront 将前定位词开头的旧名称替换为新的（如 旧名称.S01E → 新名称.S01E）
+func (d *groupDrift) rewriteFront(front string) string {
+	if strings.HasPrefix(front, d.OldName+".") {
+		return d.NewName + strings.TrimPrefix(front, d.OldName)
+	}
+	return front
+}
+
+// rewriteBack 将后定位词中的旧年份替换为新的（如 .2019. → .2020.）
+func (d *groupDrift) rewriteBack(back string) string {
+	if d.OldYear == "" || d.NewYear == "" {
+		return back
+	}
+	return strings.ReplaceAll(back, "."+d.OldYear+".", "."+d.NewYear+".")
+}
+
+// rewriteUnit 按TMDB当前的名称和年份改写规则的替换词和前后定位词，返回改写后的规则和是否有修改
+// 备注中有生成参数的规则按新的命名重新生成，其他规则直接替换其中的旧名称和旧年份
+func (d *groupDrift) rewriteUnit(unit models.WordUnit) (models.WordUnit, bool) {
+	rewritten := unit
+	rewritten.Replace, _ = d.rewriteReplace(unit.Replace)
+	rewritten.Front = d.rewriteFront(unit.Front)
+	rewritten.Back = d.rewriteBack(unit.Back)
+
+	if note, err := models.ParseUnitNote(unit.Note); err == nil && note != nil {
+		naming := mediaNaming{Name: d.NewName, Year: d.NewYear, TMDBID: d.TMDBID, MediaType: d.MediaType}
+		if rule, err := buildRule(naming, *note); err == nil {
+			rewritten.Replace = rule.Unit.Replace
+			rewritten.Front = rule.Unit.Front
+			rewritten.Back = rule.Unit.Back
+		}
+	}
+
+	changed := rewritten.Replace != unit.Replace || rewritten.Front != unit.Front || rewritten.Back != unit.Back
+	return rewritten, changed
+}
+
+// fixGroupDrift 修改词组标题并改写其规则的替换词和前后定位词
+func fixGroupDrift(wordGroupService *services.WordGroupService, drift *groupDrift, units []models.WordUnit) error {
+	group := drift.Group
+	group.Title = drift.NewTitle
+	if err := wordGroupService.UpdateWordGroup(group); err != nil {
+		return fmt.Errorf("修改词组标题失败: %v", err)
+	}
+	fmt.Printf("词组标题已修改：%s\n", drift.NewTitle)
+
+	for _, unit := range units {
+		unit, changed := drift.rewriteUnit(unit)
+		if !changed {
+			continue
+		}
+		if err := wordGroupService.UpdateWordUnit(unit); err != nil {
+			return fmt.Errorf("更新规则 %s 失败: %v", unit.BeReplaced, err)
+		}
+		fmt.Printf("已更新：%s → %s\n", unit.BeReplaced, unit.Replace)
+	}
+	return nil
+}
+
+// runDrift drift 命令：检查词组标题与TMDB当前名称、年份是否一致，并可修改标题和替换词
+func runDrift(tmdbService *services.TMDBService, args []string) error {
+	flags := flag.NewFlagSet("drift", flag.ExitOnError)
+	dryRun := flags.Bool("n", false, "只列出不一致的词组，不做修改")
+	assumeYes := flags.Bool("y", false, "不再逐个确认直接修改")
+	flags.Parse(args)
+
+	wordGroupService, err := newWordGroupService()
+	if err != nil {
+		return fmt.Errorf("创建词组服务失败: %v", err)
+	}
+
+	drifts, err := findGroupDrifts(tmdbService, wordGroupService)
+	if err != nil {
+		return err
+	}
+	if len(drifts) == 0 {
+		fmt.Println("所有词组的标题与TMDB当前信息一致")
+		return nil
+	}
+
+	fmt.Printf("\n=== 发现 %d 个词组与TMDB当前信息不一致 ===\n", len(drifts))
+	fixed := 0
+	for i := range drifts {
+		drift := &drifts[i]
+		fmt.Printf("\n词组 ID: %d\n", drift.Group.ID)
+		fmt.Printf("  当前标题：%s\n", drift.Group.Title)
+		fmt.Printf("  TMDB标题：%s\n", drift.NewTitle)
+
+		units, err := wordGroupService.ListWordUnits(drift.Group.ID)
+		if err != nil {
+			return fmt.Errorf("获取词组规则失败: %v", err)
+		}
+		for _, unit := range units {
+			rewritten, changed := drift.rewriteUnit(unit)
+			if !changed {
+				continue
+			}
+			fmt.Printf("  ~ %s\n    → %s\n", unit.Replace, rewritten.Replace)
+			if rewritten.Front != unit.Front || rewritten.Back != unit.Back {
+				fmt.Printf("    前后定位词：%s … %s → %s … %s\n", unit.Front, unit.Back, rewritten.Front, rewritten.Back)
+			}
+		}
+
+		if *dryRun {
+			continue
+		}
+		if !*assumeYes {
+			confirmed, err := utils.GetConfirmation("是否修改该词组的标题和替换词？(y/N): ")
+			if err != nil {
+				return fmt.Errorf("错误: %v", err)
+			}
+			if !confirmed {
+				continue
+			}
+		}
+
+		if err := fixGroupDrift(wordGroupService, drift, units); err != nil {
+			return err
+		}
+		fixed++
+	}
+
+	if !*dryRun {
+		fmt.Printf("\n已修改 %d 个词组\n", fixed)
+	}
+	return nil
+}
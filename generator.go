@@ -0,0 +1,201 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/harry/rename-by-tmdb/internal/models"
+	"github.com/harry/rename-by-tmdb/internal/services"
+	"github.com/harry/rename-by-tmdb/internal/utils"
+)
+
+// episodeMarkerPattern 匹配集数前可选的集数标记，如 E、Ep、Episode
+const episodeMarkerPattern = "(?:E|Ep|EP|[Ee]pisode|[Ee]p)?"
+
+// partMarkerPattern 匹配part标记
+const partMarkerPattern = "(?:[Pp]art|PART|Part)"
+
+// sameDatePartPattern 同日多集按part区分时匹配的part标记
+const sameDatePartPattern = "(?:[Pp]art|PART)"
+
+// episodeHintPattern 同日多集按集数区分时匹配集数前的标记
+const episodeHintPattern = "(?:E|Ep|EP|[Ee]pisode|[Ee]p|第)"
+
+// mediaNaming 表示替换词中使用的名称、年份和TMDB标记
+type mediaNaming struct {
+	Name      string // 名称，空格已替换为点号
+	Year      string
+	TMDBID    string
+	MediaType string // movie 或 tv
+}
+
+// token 返回TMDB标记：{[tmdbid=ID;type=类型]}
+func (n mediaNaming) token() string {
+	return fmt.Sprintf("{[tmdbid=%s;type=%s]}", n.TMDBID, n.MediaType)
+}
+
+// title 返回命名格式：名称.年份.{[tmdbid=ID;type=类型]}
+func (n mediaNaming) title() string {
+	return fmt.Sprintf("%s.%s.%s", n.Name, n.Year, n.token())
+}
+
+// parseNaming 从词组标题中解析命名信息
+func parseNaming(title string) (mediaNaming, error) {
+	tmdbID, mediaType, ok := utils.ParseTMDBToken(title)
+	if !ok {
+		return mediaNaming{}, fmt.Errorf("标题中没有 tmdbid 标记")
+	}
+	matches := namingPrefixRegexp.FindStringSubmatch(title)
+	if matches == nil {
+		return mediaNaming{}, fmt.Errorf("标题不是标准命名格式")
+	}
+	return mediaNaming{Name: matches[1], Year: matches[2], TMDBID: tmdbID, MediaType: mediaType}, nil
+}
+
+// partSeasonPattern 返回part模式被替换词中的季数部分，默认季数可有可无，要求季数标记时必须包含季数
+func partSeasonPattern(note models.UnitNote) string {
+	if note.HasSeason {
+		return fmt.Sprintf("S%02d", note.Season)
+	}
+	return fmt.Sprintf("(?:S%02d)?", note.Season)
+}
+
+// buildRule 按生成参数生成一条替换规则，生成参数写入规则备注，以便之后用新的生成器重新生成
+func buildRule(naming mediaNaming, note models.UnitNote) (generatedRule, error) {
+	note.Version = models.GeneratorVersion
+	note.TMDBID = naming.TMDBID
+	note.MediaType = naming.MediaType
+
+	var rule generatedRule
+	title := regexp.QuoteMeta(note.FileTitle)
+	switch note.Mode {
+	case models.NoteModeMovie:
+		replace := fmt.Sprintf("%s.%s.%s", naming.Name, naming.Year, naming.token())
+		if note.PartInfo != "" {
+			replace = fmt.Sprintf("%s.%s.%s.%s", naming.Name, naming.Year, note.PartInfo, naming.token())
+		}
+		rule = newRule("电影", fmt.Sprintf("%s.*", title), replace, "", "", 0)
+
+	case models.NoteModeRange:
+		var beReplaced string
+		if note.HasSeason {
+			beReplaced = fmt.Sprintf("%s.*S%02d%s(%s)",
+				title, note.Season, episodeMarkerPattern, utils.GenerateRangePattern(note.Start, note.End, note.Digits))
+		} else {
+			beReplaced = fmt.Sprintf("%s.*?(?:S\\d{2})?%s(%s)",
+				title, episodeMarkerPattern, utils.GenerateRangePattern(note.Start, note.End, note.Digits))
+		}
+		replace := fmt.Sprintf("%s.S%02dE\\1.%s.%s", naming.Name, note.Season, naming.Year, naming.token())
+
+		// 只在有偏移量时设置前后定位词
+		var prefix, suffix string
+		if note.Offset != 0 {
+			prefix = fmt.Sprintf("%s.S%02dE", naming.Name, note.Season)
+			suffix = fmt.Sprintf(".%s.", naming.Year)
+		}
+		rule = newRule(fmt.Sprintf("第 %d 季", note.Season), beReplaced, replace, prefix, suffix, note.Offset)
+
+	case models.NoteModeMulti:
+		var beReplaced string
+		if note.HasSeason {
+			beReplaced = fmt.Sprintf("%s.*S%02d%s",
+				title, note.Season, utils.GenerateMultiEpisodePattern(note.Start, note.End))
+		} else {
+			beReplaced = fmt.Sprintf("%s.*?(?:S\\d{2})?%s",
+				title, utils.GenerateMultiEpisodePattern(note.Start, note.End))
+		}
+
+		// 偏移量同时作用于区间两端：原文件集数 + 偏移量 = TMDB集数
+		actualStart := note.Start + note.Offset
+		actualEnd := note.End + note.Offset
+		replace := fmt.Sprintf("%s.S%02dE%02d-E%02d.%s.%s",
+			naming.Name, note.Season, actualStart, actualEnd, naming.Year, naming.token())
+		rule = newRule(fmt.Sprintf("第 %d 季区间 %d-%d 多集", note.Season, actualStart, actualEnd), beReplaced, replace, "", "", 0)
+
+	case models.NoteModePart:
+		beReplaced := fmt.Sprintf("%s.*?%s%s%0*d.*?%s%d.*",
+			title, partSeasonPattern(note), episodeMarkerPattern, note.Digits, note.Episode, partMarkerPattern, note.Part)
+		// 替换词使用原集数，由偏移量得到实际集数
+		replace := fmt.Sprintf("%s.S%02dE%0*d.%s.%s",
+			naming.Name, note.Season, note.Digits, note.Episode, naming.Year, naming.token())
+
+		var prefix, suffix string
+		if note.Offset > 0 {
+			prefix = fmt.Sprintf("%s.S%02dE", naming.Name, note.Season)
+			suffix = fmt.Sprintf(".%s.", naming.Year)
+		}
+		rule = newRule(fmt.Sprintf("第 %d 季第 %d 集part%d", note.Season, note.Episode, note.Part), beReplaced, replace, prefix, suffix, note.Offset)
+
+	case models.NoteModePartRange:
+		beReplaced := fmt.Sprintf("%s.*?%s%s(%s)(?!.*%s)",
+			title, partSeasonPattern(note), episodeMarkerPattern, utils.GenerateRangePattern(note.Start, note.End, note.Digits), partMarkerPattern)
+		replace := fmt.Sprintf("%s.S%02dE\\1.%s.%s", naming.Name, note.Season, naming.Year, naming.token())
+
+		var prefix, suffix string
+		if note.Offset > 0 {
+			prefix = fmt.Sprintf("%s.S%02dE", naming.Name, note.Season)
+			suffix = fmt.Sprintf(".%s.", naming.Year)
+		}
+		rule = newRule(fmt.Sprintf("第 %d 季区间 %d-%d 非part集数", note.Season, note.Start, note.End), beReplaced, replace, prefix, suffix, note.Offset)
+
+	case models.NoteModeDate:
+		formats, err := utils.ParseDateFormats(strings.Join(note.DateFormats, ";"))
+		if err != nil {
+			return generatedRule{}, err
+		}
+		datePattern, err := utils.GenerateDatePattern(note.AirDate, formats, note.Tolerance)
+		if err != nil {
+			return generatedRule{}, err
+		}
+
+		beReplaced := fmt.Sprintf("%s.*%s.*", title, datePattern)
+		replace := fmt.Sprintf("%s.S%02dE%02d.%s.%s", naming.Name, note.Season, note.Episode, naming.Year, naming.token())
+
+		// 同日多集按所选方式区分
+		switch note.SameDate {
+		case "merge":
+			if note.End <= note.Episode {
+				return generatedRule{}, fmt.Errorf("合并的结束集数 %d 必须大于起始集数 %d", note.End, note.Episode)
+			}
+			replace = fmt.Sprintf("%s.S%02dE%02d-E%02d.%s.%s",
+				naming.Name, note.Season, note.Episode, note.End, naming.Year, naming.token())
+		case "part":
+			beReplaced = fmt.Sprintf("%s.*%s.*?%s%d.*", title, datePattern, sameDatePartPattern, note.Part)
+		case "episode":
+			// 集数提示可以在日期之前或之后（如 Show.E01.20240101、Show.20240101.E01）
+			beReplaced = fmt.Sprintf("%s(?=.*?%s0*%d(?:[^0-9]|$)).*%s.*",
+				title, episodeHintPattern, note.Episode, datePattern)
+		}
+		rule = newRule(fmt.Sprintf("第 %d 季第 %d 集", note.Season, note.Episode), beReplaced, replace, "", "", 0)
+
+	case models.NoteModeSpecial:
+		markerPattern, err := specialMarkerPattern(note.Marker, note.Single)
+		if err != nil {
+			return generatedRule{}, err
+		}
+		beReplaced := fmt.Sprintf("%s.*?%s.*", title, markerPattern)
+		replace := fmt.Sprintf("%s.S00E%02d.%s.%s", naming.Name, note.Episode, naming.Year, naming.token())
+		rule = newRule(fmt.Sprintf("特别篇 %s", note.Marker), beReplaced, replace, "", "", 0)
+
+	case models.NoteModeTitle:
+		if len(note.Titles) == 0 {
+			return generatedRule{}, fmt.Errorf("集名匹配规则没有集名")
+		}
+		beReplaced := fmt.Sprintf("%s.*?%s.*", title, utils.GenerateTitlePattern(note.Titles))
+		replace := fmt.Sprintf("%s.S%02dE%02d.%s.%s", naming.Name, note.Season, note.Episode, naming.Year, naming.token())
+		rule = newRule(fmt.Sprintf("第 %d 季第 %d 集", note.Season, note.Episode), beReplaced, replace, "", "", 0)
+
+	case models.NoteModeBlock:
+		rule = generatedRule{
+			Label: fmt.Sprintf("屏蔽词（%s）", note.Pattern),
+			Unit:  services.NewBlockWordUnit(0, note.Pattern, true),
+		}
+
+	default:
+		return generatedRule{}, fmt.Errorf("未知的生成模式 '%s'", note.Mode)
+	}
+
+	rule.Unit.Note = note.Encode()
+	return rule, nil
+}
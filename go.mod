@@ -2,4 +2,11 @@ module github.com/harry/rename-by-tmdb
 
 go 1.22.5
 
-require github.com/joho/godotenv v1.5.1
+require (
+	github.com/dlclark/regexp2 v1.7.0
+	github.com/joho/godotenv v1.5.1
+	golang.org/x/term v0.22.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require golang.org/x/sys v0.22.0 // indirect
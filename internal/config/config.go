@@ -1,14 +1,441 @@
 package config
 
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/harry/rename-by-tmdb/internal/utils"
+	"gopkg.in/yaml.v3"
+)
+
 // Config 配置结构
 type Config struct {
 	TMDBAPI struct {
-		Key      string `json:"key"`
-		Language string `json:"language"`
-	} `json:"tmdb_api"`
+		Key      string `json:"key" yaml:"key,omitempty"`
+		Language string `json:"language" yaml:"language,omitempty"`
+	} `json:"tmdb_api" yaml:"tmdb_api,omitempty"`
 	RemoteServer struct {
-		BaseURL    string `json:"base_url"`
-		AuthToken  string `json:"auth_token"`
-		UploadMode bool   `json:"upload_mode"`
-	} `json:"remote_server"`
+		BaseURL    string `json:"base_url" yaml:"base_url,omitempty"`
+		AuthToken  string `json:"auth_token" yaml:"auth_token,omitempty"`
+		UploadMode bool   `json:"upload_mode" yaml:"upload_mode,omitempty"`
+	} `json:"remote_server" yaml:"remote_server,omitempty"`
+	Defaults struct {
+		Padding        *bool  `json:"padding" yaml:"padding,omitempty"`                 // 集数是否补0站位的默认选择
+		NamingTemplate string `json:"naming_template" yaml:"naming_template,omitempty"` // 命名格式中名称部分的模板
+	} `json:"defaults" yaml:"defaults,omitempty"`
+	Cache struct {
+		TTL string `json:"ttl" yaml:"ttl,omitempty"` // 登录令牌缓存的有效期，如 720h
+	} `json:"cache" yaml:"cache,omitempty"`
+	// Presets 剧集交互的预设回答，键为预设名称，值为问题标识到回答的映射
+	Presets map[string]map[string]string `json:"presets" yaml:"presets,omitempty"`
+	// Servers MS服务器，键为服务器名称（default 为默认服务器），对应的环境变量优先
+	Servers map[string]ServerConfig `json:"servers" yaml:"servers,omitempty"`
+}
+
+// ServerConfig 配置文件中的一个MS服务器
+type ServerConfig struct {
+	APIBaseURL string `json:"api_base_url" yaml:"api_base_url,omitempty"`
+	AuthToken  string `json:"auth_token" yaml:"auth_token,omitempty"`
+	Username   string `json:"username" yaml:"username,omitempty"`
+	Password   string `json:"password" yaml:"password,omitempty"`
+}
+
+// ServerProfiles 返回配置文件中的服务器，按名称排序
+func (c *Config) ServerProfiles() []utils.ServerProfile {
+	var names []string
+	for name := range c.Servers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var profiles []utils.ServerProfile
+	for _, name := range names {
+		server := c.Servers[name]
+		profiles = append(profiles, utils.ServerProfile{
+			Name:       name,
+			APIBaseURL: server.APIBaseURL,
+			AuthToken:  server.AuthToken,
+			Username:   server.Username,
+			Password:   server.Password,
+		})
+	}
+	return profiles
+}
+
+// BuiltinPresets 内置的预设，配置文件中同名的预设优先
+var BuiltinPresets = map[string]map[string]string{
+	// 动画：文件名不含季数，集数跨季连续并补0，生成所有季
+	"anime": {
+		utils.AnswerDateMode:        "n",
+		utils.AnswerMultiEpisode:    "n",
+		utils.AnswerSpecials:        "n",
+		utils.AnswerEpisodeTitles:   "n",
+		utils.AnswerPartEpisodes:    "n",
+		utils.AnswerHasSeason:       "n",
+		utils.AnswerSeasons:         "all",
+		utils.AnswerIncludeSpecials: "n",
+		utils.AnswerOffset:          "0",
+		utils.AnswerPadZero:         "y",
+		utils.AnswerContinuous:      "y",
+	},
+	// 综艺：按播出日期判断集数
+	"variety": {
+		utils.AnswerDateMode:        "y",
+		utils.AnswerDateFormats:     "YYYYMMDD;YYMMDD",
+		utils.AnswerDateTolerance:   "1",
+		utils.AnswerSameDate:        "1",
+		utils.AnswerHasSeason:       "n",
+		utils.AnswerSeasons:         "all",
+		utils.AnswerIncludeSpecials: "n",
+	},
+	// 欧美剧：文件名包含季数，每季集数从1开始
+	"western": {
+		utils.AnswerDateMode:      "n",
+		utils.AnswerMultiEpisode:  "n",
+		utils.AnswerSpecials:      "n",
+		utils.AnswerEpisodeTitles: "n",
+		utils.AnswerPartEpisodes:  "n",
+		utils.AnswerHasSeason:     "y",
+		utils.AnswerOffset:        "0",
+		utils.AnswerPadZero:       "y",
+		utils.AnswerContinuous:    "n",
+	},
+}
+
+// Preset 按名称查找预设，配置文件中的预设优先于内置预设
+func (c *Config) Preset(name string) (map[string]string, error) {
+	if answers, exists := c.Presets[name]; exists {
+		return answers, nil
+	}
+	if answers, exists := BuiltinPresets[name]; exists {
+		return answers, nil
+	}
+	return nil, fmt.Errorf("预设 '%s' 不存在，可用的预设：%s", name, strings.Join(c.PresetNames(), "、"))
+}
+
+// PresetNames 返回所有预设的名称（含内置预设），按名称排序
+func (c *Config) PresetNames() []string {
+	var names []string
+	for name := range BuiltinPresets {
+		names = append(names, name)
+	}
+	for name := range c.Presets {
+		if _, builtin := BuiltinPresets[name]; !builtin {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SetPreset 保存预设，同名预设被覆盖
+func (c *Config) SetPreset(name string, answers map[string]string) error {
+	if name == "" {
+		return fmt.Errorf("预设名称不能为空")
+	}
+	if err := utils.ValidateAnswers(answers); err != nil {
+		return err
+	}
+	if c.Presets == nil {
+		c.Presets = make(map[string]map[string]string)
+	}
+	c.Presets[name] = answers
+	return nil
+}
+
+// 配置项的来源，按优先级从高到低排列
+const (
+	SourceFlag    = "命令行参数"
+	SourceEnv     = "环境变量"
+	SourceFile    = "配置文件"
+	SourceDefault = "默认值"
+)
+
+// Field 表示一个配置项：配置文件中的键及对应的环境变量
+type Field struct {
+	Key     string // 配置文件中的键，如 tmdb_api.key
+	Env     string
+	Secret  bool // 显示时隐藏
+	Default string
+	Usage   string
+	get     func(c *Config) string
+	set     func(c *Config, value string) error
+}
+
+// Setting 表示一个配置项最终生效的值及其来源
+type Setting struct {
+	Field
+	Value  string
+	Source string
+}
+
+// Fields 所有配置项
+var Fields = []Field{
+	{
+		Key: "tmdb_api.key", Env: "TMDB_API_KEY", Secret: true, Usage: "TMDB API密钥",
+		get: func(c *Config) string { return c.TMDBAPI.Key },
+		set: func(c *Config, value string) error { c.TMDBAPI.Key = value; return nil },
+	},
+	{
+		Key: "tmdb_api.language", Env: "TMDB_LANGUAGE", Default: "zh-CN", Usage: "获取TMDB名称和集名使用的语言",
+		get: func(c *Config) string { return c.TMDBAPI.Language },
+		set: func(c *Config, value string) error { c.TMDBAPI.Language = value; return nil },
+	},
+	{
+		Key: "remote_server.base_url", Env: "API_BASE_URL", Usage: "MS服务器地址",
+		get: func(c *Config) string { return c.RemoteServer.BaseURL },
+		set: func(c *Config, value string) error { c.RemoteServer.BaseURL = value; return nil },
+	},
+	{
+		Key: "remote_server.auth_token", Env: "AUTH_TOKEN", Secret: true, Usage: "MS服务器令牌",
+		get: func(c *Config) string { return c.RemoteServer.AuthToken },
+		set: func(c *Config, value string) error { c.RemoteServer.AuthToken = value; return nil },
+	},
+	{
+		Key: "remote_server.upload_mode", Env: "UPLOAD_MS", Default: "false", Usage: "是否上传规则到MS服务器（true/false）",
+		get: func(c *Config) string {
+			if c.RemoteServer.UploadMode {
+				return "true"
+			}
+			return ""
+		},
+		set: func(c *Config, value string) error {
+			if value == "" {
+				c.RemoteServer.UploadMode = false
+				return nil
+			}
+			upload, err := strconv.ParseBool(value)
+			if err != nil {
+				return fmt.Errorf("无效的布尔值 '%s'", value)
+			}
+			c.RemoteServer.UploadMode = upload
+			return nil
+		},
+	},
+	{
+		Key: "defaults.padding", Env: "PAD_ZERO", Default: "true", Usage: "集数是否补0站位的默认选择（true/false）",
+		get: func(c *Config) string {
+			if c.Defaults.Padding == nil {
+				return ""
+			}
+			return strconv.FormatBool(*c.Defaults.Padding)
+		},
+		set: func(c *Config, value string) error {
+			if value == "" {
+				c.Defaults.Padding = nil
+				return nil
+			}
+			padding, err := strconv.ParseBool(value)
+			if err != nil {
+				return fmt.Errorf("无效的布尔值 '%s'", value)
+			}
+			c.Defaults.Padding = &padding
+			return nil
+		},
+	},
+	{
+		Key: "defaults.naming_template", Env: "NAMING_TEMPLATE", Default: "{name}", Usage: "命名格式中名称部分的模板，可使用 {name}（TMDB名称）和 {original}（原始名称）",
+		get: func(c *Config) string { return c.Defaults.NamingTemplate },
+		set: func(c *Config, value string) error {
+			if value == "" {
+				c.Defaults.NamingTemplate = ""
+				return nil
+			}
+			if err := utils.ValidateNamingTemplate(value); err != nil {
+				return err
+			}
+			c.Defaults.NamingTemplate = value
+			return nil
+		},
+	},
+	{
+		Key: "cache.ttl", Env: "CACHE_TTL", Usage: "登录令牌缓存的有效期（如 720h），为空时不过期",
+		get: func(c *Config) string { return c.Cache.TTL },
+		set: func(c *Config, value string) error {
+			if value == "" {
+				c.Cache.TTL = ""
+				return nil
+			}
+			if _, err := time.ParseDuration(value); err != nil {
+				return fmt.Errorf("无效的时长 '%s'（如 24h、720h）", value)
+			}
+			c.Cache.TTL = value
+			return nil
+		},
+	},
+}
+
+// FindField 按配置文件中的键或环境变量名查找配置项
+func FindField(key string) (Field, error) {
+	for _, field := range Fields {
+		if field.Key == key || field.Env == key {
+			return field, nil
+		}
+	}
+	return Field{}, fmt.Errorf("未知的配置项 '%s'", key)
+}
+
+// Set 设置配置项的值并校验格式，值为空字符串时删除该配置项
+func (c *Config) Set(key, value string) error {
+	field, err := FindField(key)
+	if err != nil {
+		return err
+	}
+	return field.set(c, value)
+}
+
+// FindPath 查找配置文件：依次尝试用户配置目录下的 config.yaml 和 config.yml，都不存在时返回默认路径和 false
+func FindPath() (string, bool, error) {
+	configDir, err := utils.GetConfigDir()
+	if err != nil {
+		return "", false, err
+	}
+	for _, name := range []string{"config.yaml", "config.yml"} {
+		path := filepath.Join(configDir, name)
+		if _, err := os.Stat(path); err == nil {
+			return path, true, nil
+		}
+	}
+	return filepath.Join(configDir, "config.yaml"), false, nil
+}
+
+// Load 读取配置文件，文件不存在时返回空配置
+func Load(path string) (*Config, error) {
+	cfg := &Config{}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取配置文件失败: %v", err)
+	}
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("解析配置文件 %s 失败: %v", path, err)
+	}
+
+	// 配置文件中的值与 config set 使用相同的校验
+	for _, field := range Fields {
+		if value := field.get(cfg); value != "" {
+			if err := field.set(cfg, value); err != nil {
+				return nil, fmt.Errorf("配置文件 %s 中的 %s: %v", path, field.Key, err)
+			}
+		}
+	}
+	for name, answers := range cfg.Presets {
+		if err := utils.ValidateAnswers(answers); err != nil {
+			return nil, fmt.Errorf("配置文件 %s 中的预设 %s: %v", path, name, err)
+		}
+	}
+	if err := utils.ValidateServerNames(cfg.ServerProfiles()); err != nil {
+		return nil, fmt.Errorf("配置文件 %s 中的 servers: %v", path, err)
+	}
+	return cfg, nil
+}
+
+// Save 保存配置文件，文件可能包含密钥，仅当前用户可读写
+func Save(path string, cfg *Config) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("创建配置目录失败: %v", err)
+	}
+	data, err := yaml.Marshal(cfg)
+	if err != nil {
+		return fmt.Errorf("YAML编码失败: %v", err)
+	}
+
+	// 先写入临时文件再替换，避免写入中断导致配置文件损坏
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return fmt.Errorf("写入配置文件失败: %v", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("写入配置文件失败: %v", err)
+	}
+	return nil
+}
+
+// Apply 按 命令行参数 > 环境变量（含 .env） > 配置文件 > 默认值 的优先级确定每个配置项，并写入对应的环境变量
+// overrides 为命令行参数指定的配置项，键为配置文件中的键或环境变量名；配置文件中的服务器交给 GetServerProfiles，由其按环境变量优先合并
+func Apply(cfg *Config, overrides map[string]string) ([]Setting, error) {
+	flagValues := make(map[string]string)
+	for key, value := range overrides {
+		field, err := FindField(key)
+		if err != nil {
+			return nil, err
+		}
+		if err := field.set(&Config{}, value); err != nil {
+			return nil, fmt.Errorf("%s: %v", field.Key, err)
+		}
+		flagValues[field.Key] = value
+	}
+
+	utils.SetConfigServers(cfg.ServerProfiles())
+
+	var settings []Setting
+	for _, field := range Fields {
+		setting := Setting{Field: field}
+		if value, exists := flagValues[field.Key]; exists {
+			setting.Value, setting.Source = value, SourceFlag
+		} else if value := os.Getenv(field.Env); value != "" {
+			setting.Value, setting.Source = value, SourceEnv
+		} else if value := field.get(cfg); value != "" {
+			setting.Value, setting.Source = value, SourceFile
+		} else if field.Default != "" {
+			setting.Value, setting.Source = field.Default, SourceDefault
+		} else {
+			settings = append(settings, setting)
+			continue
+		}
+		if err := os.Setenv(field.Env, setting.Value); err != nil {
+			return nil, fmt.Errorf("设置环境变量 %s 失败: %v", field.Env, err)
+		}
+		settings = append(settings, setting)
+	}
+	return settings, nil
+}
+
+// Redact 隐藏密钥，只保留末尾4个字符
+func Redact(value string) string {
+	if value == "" {
+		return ""
+	}
+	if len(value) <= 4 {
+		return "****"
+	}
+	return strings.Repeat("*", 4) + value[len(value)-4:]
+}
+
+// Init 加载 .env 和配置文件，按优先级确定所有配置项并写入环境变量，返回使用的配置文件路径
+// path 为空时查找用户配置目录下的配置文件；配置文件存在时 .env 可以不存在
+func Init(path string, overrides map[string]string, loadEnv func() error) (string, []Setting, error) {
+	found := false
+	if path == "" {
+		var err error
+		path, found, err = FindPath()
+		if err != nil {
+			return "", nil, err
+		}
+	} else if _, err := os.Stat(path); err == nil {
+		found = true
+	} else if !os.IsNotExist(err) {
+		return "", nil, fmt.Errorf("读取配置文件失败: %v", err)
+	}
+
+	if err := loadEnv(); err != nil && !found {
+		return path, nil, fmt.Errorf("%v\n\n请确保 .env 文件存在并包含必要的环境变量，或使用 config set 写入配置文件 %s", err, path)
+	}
+
+	cfg, err := Load(path)
+	if err != nil {
+		return path, nil, err
+	}
+	settings, err := Apply(cfg, overrides)
+	if err != nil {
+		return path, nil, err
+	}
+	return path, settings, nil
 }
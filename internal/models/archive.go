@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// ArchiveVersion 导出文件格式版本
+const ArchiveVersion = 1
+
+// Archive 表示导出的词组及其替换规则，用于备份和在服务器之间迁移
+type Archive struct {
+	Version    int            `json:"version"`
+	ExportedAt time.Time      `json:"exportedAt"`
+	Server     string         `json:"server"` // 导出时的服务器
+	Groups     []ArchiveGroup `json:"groups"`
+}
+
+// ArchiveGroup 表示导出的一个词组，ID 为导出服务器上的词组ID
+type ArchiveGroup struct {
+	ID            int        `json:"id"`
+	Title         string     `json:"title"`
+	WordGroupType int        `json:"wordGroupType"`
+	Units         []WordUnit `json:"units"`
+}
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// 审计日志操作类型
+const (
+	AuditCreateGroup = "create_group"
+	AuditCreateUnit  = "create_unit"
+	AuditDeleteGroup = "delete_group"
+	AuditDeleteUnit  = "delete_unit"
+)
+
+// AuditEntry 表示审计日志中的一条记录（JSONL格式，每行一条）
+type AuditEntry struct {
+	Time      time.Time `json:"time"`
+	RunID     string    `json:"runId"`
+	Action    string    `json:"action"`
+	Server    string    `json:"server"`
+	TMDBID    string    `json:"tmdbId,omitempty"`
+	MediaType string    `json:"mediaType,omitempty"`
+	GroupID   int       `json:"groupId"`
+	Title     string    `json:"title,omitempty"`  // 词组标题
+	UnitID    int       `json:"unitId,omitempty"` // 规则ID
+	Unit      *WordUnit `json:"unit,omitempty"`   // 规则参数
+	Params    *UnitNote `json:"params,omitempty"` // 规则的生成参数（模式、季数、集数区间、偏移量等）
+	UndoOf    string    `json:"undoOf,omitempty"` // 删除记录所撤销的运行ID
+}
@@ -0,0 +1,116 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// GeneratorVersion 规则生成器的版本，生成的被替换词或替换词有变化时递增
+const GeneratorVersion = 3
+
+// 生成规则的模式，写入规则备注，重新生成时据此选择生成方式
+const (
+	NoteModeMovie     = "movie"     // 电影
+	NoteModeRange     = "range"     // 按季的集数区间
+	NoteModeMulti     = "multi"     // 多集文件的集数区间
+	NoteModePart      = "part"      // part模式的单个part
+	NoteModePartRange = "partRange" // part模式中part集数之间的区间
+	NoteModeDate      = "date"      // 按播出日期匹配单集
+	NoteModeSpecial   = "special"   // 特别篇标记
+	NoteModeTitle     = "title"     // 按集名匹配单集
+	NoteModeBlock     = "block"     // 屏蔽词
+)
+
+// UnitNote 表示写入规则备注的生成参数，可按这些参数用当前的生成器重新生成规则
+type UnitNote struct {
+	Version     int      `json:"v"`
+	TMDBID      string   `json:"tmdbid,omitempty"`
+	MediaType   string   `json:"type,omitempty"`
+	Mode        string   `json:"mode"`
+	FileTitle   string   `json:"fileTitle,omitempty"`
+	Season      int      `json:"season,omitempty"`
+	HasSeason   bool     `json:"hasSeason,omitempty"` // 原文件名是否包含季数
+	Start       int      `json:"start,omitempty"`     // 原文件中的起始集数
+	End         int      `json:"end,omitempty"`       // 原文件中的结束集数
+	Episode     int      `json:"episode,omitempty"`   // TMDB集数
+	Part        int      `json:"part,omitempty"`
+	Offset      int      `json:"offset,omitempty"`
+	Digits      int      `json:"digits,omitempty"`
+	AirDate     string   `json:"airDate,omitempty"`
+	DateFormats []string `json:"dateFormats,omitempty"`
+	Tolerance   int      `json:"tolerance,omitempty"`
+	SameDate    string   `json:"sameDate,omitempty"` // 同日多集的区分方式：merge、part 或 episode
+	Marker      string   `json:"marker,omitempty"`   // 特别篇标记
+	Single      bool     `json:"single,omitempty"`   // 同类特别篇标记只有一个，编号可省略
+	Titles      []string `json:"titles,omitempty"`   // 集名
+	Pattern     string   `json:"pattern,omitempty"`  // 屏蔽词
+	PartInfo    string   `json:"partInfo,omitempty"` // 电影的part信息
+	Recipe      string   `json:"recipe,omitempty"`   // 生成规则的配方名称，可用 --recipe 重放
+	// RecipeData 配方内容，只记录在词组的第一条规则中，配方目录中没有该配方时可从服务器读取
+	RecipeData *RecipeNote `json:"recipeData,omitempty"`
+}
+
+// RecipeNote 表示记录在规则备注中的配方内容
+type RecipeNote struct {
+	ToolVersion string            `json:"toolVersion"`
+	MediaType   string            `json:"mediaType"`
+	TMDBID      string            `json:"tmdbid"`
+	FileTitle   string            `json:"fileTitle"`
+	Answers     map[string]string `json:"answers,omitempty"`
+}
+
+// Key 返回规则的标识，标识相同的规则负责相同的集数，同步时据此匹配现有规则
+func (n UnitNote) Key() string {
+	var fields []interface{}
+	switch n.Mode {
+	case NoteModeMovie:
+		fields = []interface{}{n.FileTitle}
+	case NoteModeRange:
+		fields = []interface{}{n.FileTitle, n.Season}
+	case NoteModeMulti:
+		fields = []interface{}{n.FileTitle, n.Season, n.Start + n.Offset, n.End + n.Offset}
+	case NoteModePart:
+		fields = []interface{}{n.FileTitle, n.Season, n.Episode, n.Part}
+	case NoteModePartRange:
+		fields = []interface{}{n.FileTitle, n.Season, n.Start}
+	case NoteModeDate, NoteModeTitle:
+		fields = []interface{}{n.FileTitle, n.Season, n.Episode}
+	case NoteModeSpecial:
+		fields = []interface{}{n.FileTitle, strings.ToUpper(n.Marker)}
+	case NoteModeBlock:
+		fields = []interface{}{n.Pattern}
+	}
+
+	key := n.Mode
+	for _, field := range fields {
+		key += fmt.Sprintf("\x00%v", field)
+	}
+	return key
+}
+
+// Encode 将生成参数编码为规则备注
+func (n UnitNote) Encode() string {
+	data, err := json.Marshal(n)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// ParseUnitNote 解析规则备注中的生成参数，备注为空或不是生成参数时返回 nil
+func ParseUnitNote(note string) (*UnitNote, error) {
+	note = strings.TrimSpace(note)
+	if !strings.HasPrefix(note, "{") {
+		return nil, nil
+	}
+
+	var parsed UnitNote
+	if err := json.Unmarshal([]byte(note), &parsed); err != nil {
+		return nil, fmt.Errorf("解析规则备注失败: %v", err)
+	}
+	if parsed.Mode == "" {
+		return nil, nil
+	}
+	return &parsed, nil
+}
@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+// PlanVersion 计划文件格式版本
+const PlanVersion = 1
+
+// Plan 表示待执行的变更计划，由 plan 命令生成、apply 命令执行
+type Plan struct {
+	Version   int         `json:"version"`
+	CreatedAt time.Time   `json:"createdAt"`
+	Server    string      `json:"server"`
+	Groups    []GroupPlan `json:"groups"`
+}
+
+// GroupPlan 表示一个词组的变更
+type GroupPlan struct {
+	Title   string `json:"title"`
+	Action  string `json:"action"` // create：新建词组；update：修改已存在的词组
+	GroupID int    `json:"groupId,omitempty"`
+	// Fingerprint 规划时词组现有规则的指纹，执行前用于检测服务器状态是否变化
+	Fingerprint string     `json:"fingerprint,omitempty"`
+	Create      []WordUnit `json:"create,omitempty"`
+	Update      []WordUnit `json:"update,omitempty"`
+	Disable     []WordUnit `json:"disable,omitempty"`
+	Delete      []WordUnit `json:"delete,omitempty"`
+	// Unrecognized 没有生成参数且无法反向解析的旧规则，仅用于提示，同时按过期规则处理
+	Unrecognized []UnrecognizedUnit `json:"unrecognized,omitempty"`
+}
+
+// UnrecognizedUnit 表示无法反向解析出生成参数的规则及原因
+type UnrecognizedUnit struct {
+	Unit   WordUnit `json:"unit"`
+	Reason string   `json:"reason"`
+}
+
+// 词组变更类型
+const (
+	GroupActionCreate = "create"
+	GroupActionUpdate = "update"
+)
+
+// IsEmpty 判断词组变更是否为空
+func (g *GroupPlan) IsEmpty() bool {
+	return g.Action == GroupActionUpdate &&
+		len(g.Create) == 0 && len(g.Update) == 0 && len(g.Disable) == 0 && len(g.Delete) == 0
+}
@@ -3,7 +3,11 @@ package models
 // TMDBEpisode 表示TMDB的一集信息
 type TMDBEpisode struct {
 	EpisodeNumber int    `json:"episode_number"`
+	SeasonNumber  int    `json:"season_number"`
+	Name          string `json:"name"`
 	AirDate       string `json:"air_date"`
+	// Translations 其他语言的集名，键为语言代码（如 en-US），由 TMDBService 按需填充
+	Translations map[string]string `json:"-"`
 }
 
 // TMDBSeason 表示TMDB的一季信息
@@ -15,6 +19,7 @@ type TMDBSeason struct {
 // TMDBShow 表示TMDB的剧集信息
 type TMDBShow struct {
 	Name         string       `json:"name"`
+	OriginalName string       `json:"original_name"`
 	FirstAirDate string       `json:"first_air_date"`
 	Type         string       `json:"type"`
 	Seasons      []TMDBSeason `json:"seasons"`
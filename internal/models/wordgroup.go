@@ -1,6 +1,9 @@
 package models
 
-import "encoding/json"
+import (
+	"encoding/json"
+	"fmt"
+)
 
 // WordGroup 表示词组信息
 type WordGroup struct {
@@ -9,19 +12,48 @@ type WordGroup struct {
 	WordGroupType int    `json:"wordGroupType"`
 }
 
+// WordUnitType 表示替换规则的类型
+type WordUnitType int
+
+const (
+	// WordUnitTypeBlock 屏蔽词：从文件名中删除匹配的内容，如广告标记【www.xxx.com】
+	WordUnitTypeBlock WordUnitType = 100
+	// WordUnitTypeReplace 替换：将被替换词替换为替换词
+	WordUnitTypeReplace WordUnitType = 200
+	// WordUnitTypeReplaceOffset 替换+集数偏移
+	WordUnitTypeReplaceOffset WordUnitType = 300
+	// WordUnitTypeOffset 集数偏移：按前后定位词定位集数并偏移
+	WordUnitTypeOffset WordUnitType = 400
+)
+
+// String 返回规则类型的名称
+func (t WordUnitType) String() string {
+	switch t {
+	case WordUnitTypeBlock:
+		return "屏蔽词"
+	case WordUnitTypeReplace:
+		return "替换"
+	case WordUnitTypeReplaceOffset:
+		return "替换+偏移"
+	case WordUnitTypeOffset:
+		return "集数偏移"
+	}
+	return fmt.Sprintf("未知类型(%d)", int(t))
+}
+
 // WordUnit 表示替换规则
 type WordUnit struct {
-	ID          int    `json:"id"`
-	WordGroupID int    `json:"wordGroupId"`
-	BeReplaced  string `json:"beReplaced"`
-	Replace     string `json:"replace"`
-	Front       string `json:"front"`
-	Back        string `json:"back"`
-	Offset      string `json:"offset"`
-	Enabled     bool   `json:"enabled"`
-	Type        int    `json:"type"`
-	Regex       bool   `json:"regex"`
-	Note        string `json:"note"`
+	ID          int          `json:"id"`
+	WordGroupID int          `json:"wordGroupId"`
+	BeReplaced  string       `json:"beReplaced"`
+	Replace     string       `json:"replace"`
+	Front       string       `json:"front"`
+	Back        string       `json:"back"`
+	Offset      string       `json:"offset"`
+	Enabled     bool         `json:"enabled"`
+	Type        WordUnitType `json:"type"`
+	Regex       bool         `json:"regex"`
+	Note        string       `json:"note"`
 }
 
 // APIResponse 表示API通用响应格式
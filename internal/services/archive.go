@@ -0,0 +1,157 @@
+package services
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/harry/rename-by-tmdb/internal/models"
+)
+
+// ImportStrategy 表示导入时词组已存在的处理方式
+type ImportStrategy string
+
+const (
+	// ImportSkip 跳过已存在的词组
+	ImportSkip ImportStrategy = "skip"
+	// ImportMerge 新增缺失的规则、更新变化的规则，保留词组中其他规则
+	ImportMerge ImportStrategy = "merge"
+	// ImportReplace 使词组中的规则与导出文件完全一致，删除多余的规则
+	ImportReplace ImportStrategy = "replace"
+)
+
+// ParseImportStrategy 解析导入策略
+func ParseImportStrategy(value string) (ImportStrategy, error) {
+	switch strategy := ImportStrategy(value); strategy {
+	case ImportSkip, ImportMerge, ImportReplace:
+		return strategy, nil
+	}
+	return "", fmt.Errorf("无效的导入策略 '%s'，应为 skip、merge 或 replace", value)
+}
+
+// ImportGroupResult 表示一个词组的导入结果
+type ImportGroupResult struct {
+	Title    string
+	SourceID int    // 导出服务器上的词组ID
+	TargetID int    // 当前服务器上的词组ID，跳过且不存在时为0
+	Action   string // created、skipped、merged、replaced
+	Sync     *SyncResult
+}
+
+// ExportWordGroups 导出词组及其替换规则，groupIDs 为空时导出所有词组
+func (s *WordGroupService) ExportWordGroups(groupIDs []int) (*models.Archive, error) {
+	groups, err := s.ListWordGroups("")
+	if err != nil {
+		return nil, fmt.Errorf("获取词组列表失败: %v", err)
+	}
+
+	selected := make(map[int]bool)
+	for _, id := range groupIDs {
+		selected[id] = true
+	}
+
+	archive := &models.Archive{
+		Version:    models.ArchiveVersion,
+		ExportedAt: time.Now(),
+		Server:     s.apiBaseURL,
+	}
+	for _, group := range groups {
+		if len(selected) > 0 && !selected[group.ID] {
+			continue
+		}
+		delete(selected, group.ID)
+
+		units, err := s.ListWordUnits(group.ID)
+		if err != nil {
+			return nil, fmt.Errorf("获取词组 %s 的规则失败: %v", group.Title, err)
+		}
+		archive.Groups = append(archive.Groups, models.ArchiveGroup{
+			ID:            group.ID,
+			Title:         group.Title,
+			WordGroupType: group.WordGroupType,
+			Units:         units,
+		})
+	}
+
+	if len(selected) > 0 {
+		var missing []string
+		for _, id := range groupIDs {
+			if selected[id] {
+				missing = append(missing, strconv.Itoa(id))
+			}
+		}
+		return nil, fmt.Errorf("词组 ID %s 不存在", strings.Join(missing, ", "))
+	}
+	return archive, nil
+}
+
+// ImportWordGroups 将导出文件中的词组导入当前服务器，按标题匹配已存在的词组，并将规则映射到当前服务器的词组ID
+// 出错时返回已完成部分的结果
+func (s *WordGroupService) ImportWordGroups(archive *models.Archive, strategy ImportStrategy) ([]ImportGroupResult, error) {
+	if archive.Version != models.ArchiveVersion {
+		return nil, fmt.Errorf("不支持的导出文件版本: %d", archive.Version)
+	}
+
+	var results []ImportGroupResult
+	for _, archived := range archive.Groups {
+		result := ImportGroupResult{Title: archived.Title, SourceID: archived.ID}
+
+		existing, err := s.FindWordGroup(archived.Title)
+		if err != nil {
+			return results, fmt.Errorf("查找词组 %s 失败: %v", archived.Title, err)
+		}
+
+		if existing == nil {
+			group, err := s.CreateWordGroupWithType(archived.Title, archived.WordGroupType)
+			if err != nil {
+				return results, fmt.Errorf("创建词组 %s 失败: %v", archived.Title, err)
+			}
+			result.TargetID = group.ID
+			result.Action = "created"
+			result.Sync = &SyncResult{}
+			created, err := s.CreateWordUnits(group.ID, archived.Units)
+			result.Sync.Created = len(created)
+			if err != nil {
+				results = append(results, result)
+				return results, err
+			}
+			results = append(results, result)
+			continue
+		}
+
+		result.TargetID = existing.ID
+		if strategy == ImportSkip {
+			result.Action = "skipped"
+			results = append(results, result)
+			continue
+		}
+
+		// 合并和替换都按 WordUnitKey 同步规则（与生成规则的同步相同），区别在于是否删除词组中多余的规则
+		obsoleteAction := ObsoleteKeep
+		result.Action = "merged"
+		if strategy == ImportReplace {
+			obsoleteAction = ObsoleteDelete
+			result.Action = "replaced"
+		}
+
+		current, err := s.ListWordUnits(existing.ID)
+		if err != nil {
+			return results, fmt.Errorf("获取词组 %s 的规则失败: %v", existing.Title, err)
+		}
+		generated := make([]models.WordUnit, 0, len(archived.Units))
+		for _, unit := range archived.Units {
+			unit.ID = 0
+			unit.WordGroupID = existing.ID
+			generated = append(generated, unit)
+		}
+
+		result.Sync, err = s.ApplySyncPlan(existing.ID, DiffWordUnits(current, generated), obsoleteAction)
+		results = append(results, result)
+		if err != nil {
+			return results, fmt.Errorf("同步词组 %s 失败: %v", existing.Title, err)
+		}
+	}
+
+	return results, nil
+}
@@ -0,0 +1,164 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/harry/rename-by-tmdb/internal/utils"
+)
+
+// authError 表示服务器因令牌无效或过期拒绝请求
+type authError struct {
+	message string
+}
+
+func (e *authError) Error() string {
+	return e.message
+}
+
+// isAuthFailure 判断响应是否表示令牌无效或过期
+// 50008：非法令牌；50012：已在其他客户端登录；50014：令牌过期
+func isAuthFailure(statusCode, code int) bool {
+	switch {
+	case statusCode == 401 || statusCode == 403:
+		return true
+	case code == 401 || code == 403 || code == 50008 || code == 50012 || code == 50014:
+		return true
+	}
+	return false
+}
+
+// cachedToken 表示保存在本地的登录令牌，按服务器地址保存
+type cachedToken struct {
+	Username string    `json:"username"`
+	Token    string    `json:"token"`
+	LoggedIn time.Time `json:"loggedIn"`
+}
+
+// readTokenCache 读取本地保存的所有登录令牌
+func readTokenCache() (map[string]cachedToken, error) {
+	path, err := utils.GetTokenCachePath()
+	if err != nil {
+		return nil, err
+	}
+
+	tokens := make(map[string]cachedToken)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return tokens, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取令牌文件失败: %v", err)
+	}
+	if err := json.Unmarshal(data, &tokens); err != nil {
+		return nil, fmt.Errorf("解析令牌文件失败: %v", err)
+	}
+	return tokens, nil
+}
+
+// writeTokenCache 保存登录令牌，文件仅当前用户可读写
+func writeTokenCache(tokens map[string]cachedToken) error {
+	path, err := utils.GetTokenCachePath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("创建令牌目录失败: %v", err)
+	}
+
+	data, err := json.MarshalIndent(tokens, "", "  ")
+	if err != nil {
+		return fmt.Errorf("JSON编码失败: %v", err)
+	}
+
+	// 先写入临时文件再替换，避免写入中断导致令牌文件损坏
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return fmt.Errorf("写入令牌文件失败: %v", err)
+	}
+	if err := os.Chmod(tmpPath, 0600); err != nil {
+		return fmt.Errorf("设置令牌文件权限失败: %v", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("写入令牌文件失败: %v", err)
+	}
+	return nil
+}
+
+// loadCachedToken 读取本地保存的该服务器的令牌，设置了用户名时只使用该用户的令牌，没有或已过期时返回空字符串
+func (s *WordGroupService) loadCachedToken() string {
+	tokens, err := readTokenCache()
+	if err != nil {
+		fmt.Printf("警告：%v\n", err)
+		return ""
+	}
+	cached, exists := tokens[s.apiBaseURL]
+	if !exists || (s.username != "" && cached.Username != s.username) {
+		return ""
+	}
+	// 超过 CACHE_TTL 的令牌视为过期，需要重新登录
+	if ttl := utils.GetCacheTTL(); ttl > 0 && time.Since(cached.LoggedIn) > ttl {
+		return ""
+	}
+	return cached.Token
+}
+
+// saveToken 保存登录令牌
+func (s *WordGroupService) saveToken(token string) error {
+	tokens, err := readTokenCache()
+	if err != nil {
+		// 令牌文件损坏时直接覆盖
+		tokens = make(map[string]cachedToken)
+	}
+	tokens[s.apiBaseURL] = cachedToken{
+		Username: s.username,
+		Token:    token,
+		LoggedIn: time.Now(),
+	}
+	return writeTokenCache(tokens)
+}
+
+// canLogin 判断是否可以用用户名和密码登录
+func (s *WordGroupService) canLogin() bool {
+	return s.username != "" && s.password != ""
+}
+
+// Login 使用用户名和密码登录，获取新的令牌并保存到本地
+func (s *WordGroupService) Login() error {
+	if !s.canLogin() {
+		return fmt.Errorf("服务器 %s 未设置用户名和密码", s.name)
+	}
+
+	body := map[string]string{
+		"username": s.username,
+		"password": s.password,
+	}
+	data, err := s.sendRequest("POST", "/user/login", body, false)
+	if err != nil {
+		return fmt.Errorf("登录失败: %v", err)
+	}
+
+	var result struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"accessToken"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return fmt.Errorf("解析登录响应失败: %v", err)
+	}
+	token := result.Token
+	if token == "" {
+		token = result.AccessToken
+	}
+	if token == "" {
+		return fmt.Errorf("登录失败: 服务器未返回令牌")
+	}
+
+	s.authToken = token
+	if err := s.saveToken(token); err != nil {
+		fmt.Printf("警告：保存令牌失败: %v\n", err)
+	}
+	return nil
+}
@@ -0,0 +1,195 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/harry/rename-by-tmdb/internal/models"
+)
+
+// ObsoleteAction 表示同步时对过期规则的处理方式
+type ObsoleteAction int
+
+const (
+	// ObsoleteDisable 禁用过期规则
+	ObsoleteDisable ObsoleteAction = iota
+	// ObsoleteDelete 删除过期规则
+	ObsoleteDelete
+	// ObsoleteKeep 保留过期规则不做处理
+	ObsoleteKeep
+)
+
+// SyncPlan 表示生成的规则与词组中现有规则的差异
+type SyncPlan struct {
+	Create    []models.WordUnit // 需要新增的规则
+	Update    []models.WordUnit // 需要更新的规则（已带上现有规则的ID）
+	Obsolete  []models.WordUnit // 词组中存在但本次未生成的规则
+	Unchanged []models.WordUnit // 内容一致无需处理的规则
+}
+
+// wordUnitEqual 比较两条规则的内容是否一致（不比较ID和所属词组）
+func wordUnitEqual(a, b models.WordUnit) bool {
+	return a.BeReplaced == b.BeReplaced &&
+		a.Replace == b.Replace &&
+		a.Front == b.Front &&
+		a.Back == b.Back &&
+		a.Offset == b.Offset &&
+		a.Enabled == b.Enabled &&
+		a.Type == b.Type &&
+		a.Regex == b.Regex &&
+		noteEqual(a.Note, b.Note)
+}
+
+// noteEqual 比较规则备注，不比较配方名称和配方内容：二者随本次的回答变化，只有配方不同的规则无需更新
+func noteEqual(a, b string) bool {
+	if a == b {
+		return true
+	}
+	noteA, errA := models.ParseUnitNote(a)
+	noteB, errB := models.ParseUnitNote(b)
+	if errA != nil || errB != nil || noteA == nil || noteB == nil {
+		return false
+	}
+	noteA.Recipe, noteB.Recipe = "", ""
+	noteA.RecipeData, noteB.RecipeData = nil, nil
+	return noteA.Encode() == noteB.Encode()
+}
+
+// WordUnitKey 返回同步时识别规则的键：备注中有生成参数的规则以生成参数的标识为键；
+// 其他规则以类型、被替换词、前后定位词和偏移量为键，被替换词相同的多条规则（如不同季的偏移规则）不会被视为同一条
+func WordUnitKey(unit models.WordUnit) string {
+	if note, err := models.ParseUnitNote(unit.Note); err == nil && note != nil {
+		return "note:" + note.Key()
+	}
+	return fmt.Sprintf("unit:%d\x00%s\x00%s\x00%s\x00%s", unit.Type, unit.BeReplaced, unit.Front, unit.Back, unit.Offset)
+}
+
+// DiffWordUnits 以 WordUnitKey 为键比较现有规则与生成的规则
+func DiffWordUnits(existing, generated []models.WordUnit) *SyncPlan {
+	return DiffWordUnitsWithKey(existing, generated, WordUnitKey)
+}
+
+// DiffWordUnitsWithKey 比较现有规则与生成的规则，现有规则以existingKey为键，生成的规则以 WordUnitKey 为键
+// 用于按反向解析的生成参数匹配没有备注的旧规则；同一个键有多条规则时按顺序一一对应，多出的现有规则视为过期
+func DiffWordUnitsWithKey(existing, generated []models.WordUnit, existingKey func(models.WordUnit) string) *SyncPlan {
+	plan := &SyncPlan{}
+
+	// 每个键对应的现有规则在 existing 中的下标，按顺序与生成的规则一一对应
+	existingByKey := make(map[string][]int)
+	for i, unit := range existing {
+		key := existingKey(unit)
+		existingByKey[key] = append(existingByKey[key], i)
+	}
+
+	matched := make([]bool, len(existing))
+	for _, unit := range generated {
+		key := WordUnitKey(unit)
+		candidates := existingByKey[key]
+		if len(candidates) == 0 {
+			plan.Create = append(plan.Create, unit)
+			continue
+		}
+
+		current := existing[candidates[0]]
+		matched[candidates[0]] = true
+		existingByKey[key] = candidates[1:]
+		unit.ID = current.ID
+		unit.WordGroupID = current.WordGroupID
+		if wordUnitEqual(current, unit) {
+			plan.Unchanged = append(plan.Unchanged, current)
+		} else {
+			plan.Update = append(plan.Update, unit)
+		}
+	}
+
+	// 没有对应生成规则的现有规则视为过期
+	for i, unit := range existing {
+		if !matched[i] {
+			plan.Obsolete = append(plan.Obsolete, unit)
+		}
+	}
+
+	return plan
+}
+
+// SyncResult 表示同步的执行结果
+type SyncResult struct {
+	Created   int
+	Updated   int
+	Deleted   int
+	Disabled  int
+	Unchanged int
+}
+
+// SyncWordUnits 将词组中的规则同步为生成的规则：新增缺失的、更新变化的，并按obsoleteAction处理过期的
+func (s *WordGroupService) SyncWordUnits(groupID int, generated []models.WordUnit, obsoleteAction ObsoleteAction) (*SyncResult, error) {
+	existing, err := s.ListWordUnits(groupID)
+	if err != nil {
+		return nil, fmt.Errorf("获取词组现有规则失败: %v", err)
+	}
+
+	plan := DiffWordUnits(existing, generated)
+	return s.ApplySyncPlan(groupID, plan, obsoleteAction)
+}
+
+// ApplySyncPlan 执行同步计划，出错时返回已完成部分的结果
+func (s *WordGroupService) ApplySyncPlan(groupID int, plan *SyncPlan, obsoleteAction ObsoleteAction) (*SyncResult, error) {
+	result := &SyncResult{Unchanged: len(plan.Unchanged)}
+
+	created, err := s.CreateWordUnits(groupID, plan.Create)
+	result.Created = len(created)
+	if err != nil {
+		return result, err
+	}
+
+	for _, unit := range plan.Update {
+		if err := s.UpdateWordUnit(unit); err != nil {
+			return result, fmt.Errorf("更新规则 %s 失败: %v", unit.BeReplaced, err)
+		}
+		result.Updated++
+	}
+
+	for _, unit := range plan.Obsolete {
+		switch obsoleteAction {
+		case ObsoleteDelete:
+			if err := s.DeleteWordUnit(unit.ID); err != nil {
+				return result, fmt.Errorf("删除规则 %s 失败: %v", unit.BeReplaced, err)
+			}
+			result.Deleted++
+		case ObsoleteDisable:
+			if !unit.Enabled {
+				result.Unchanged++
+				continue
+			}
+			unit.Enabled = false
+			if err := s.UpdateWordUnit(unit); err != nil {
+				return result, fmt.Errorf("禁用规则 %s 失败: %v", unit.BeReplaced, err)
+			}
+			result.Disabled++
+		default:
+			result.Unchanged++
+		}
+	}
+
+	return result, nil
+}
+
+// FingerprintWordUnits 计算词组中规则的指纹（与顺序无关），用于检测服务器状态是否变化
+func FingerprintWordUnits(units []models.WordUnit) string {
+	sorted := make([]models.WordUnit, len(units))
+	copy(sorted, units)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].ID < sorted[j].ID
+	})
+
+	hash := sha256.New()
+	for _, unit := range sorted {
+		data, _ := json.Marshal(unit)
+		hash.Write(data)
+		hash.Write([]byte{'\n'})
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
@@ -0,0 +1,120 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/harry/rename-by-tmdb/internal/models"
+)
+
+// noteUnit 返回带生成参数备注的规则
+func noteUnit(id int, beReplaced string, note models.UnitNote) models.WordUnit {
+	unit := NewWordUnit(1, beReplaced, "Show.S01E\\1", "", "", 0)
+	unit.ID = id
+	unit.Note = note.Encode()
+	return unit
+}
+
+func TestDiffWordUnitsWithKey(t *testing.T) {
+	season1 := models.UnitNote{Mode: models.NoteModeRange, FileTitle: "Show", Season: 1}
+	season2 := models.UnitNote{Mode: models.NoteModeRange, FileTitle: "Show", Season: 2}
+	withRecipe := season1
+	withRecipe.Recipe = "tv-1-abc"
+	withRecipe.RecipeData = &models.RecipeNote{TMDBID: "1"}
+
+	offsetA := NewWordUnit(1, "Show", "", "E", ".", 1)
+	offsetB := NewWordUnit(1, "Show", "", "E", ".", 2)
+
+	tests := []struct {
+		name      string
+		existing  []models.WordUnit
+		generated []models.WordUnit
+		create    []string // 各类规则的被替换词
+		update    []string
+		obsolete  []string
+		unchanged []string
+	}{
+		{
+			name:      "内容一致",
+			existing:  []models.WordUnit{noteUnit(1, "A", season1)},
+			generated: []models.WordUnit{noteUnit(0, "A", season1)},
+			unchanged: []string{"A"},
+		},
+		{
+			name:      "按生成参数匹配并更新",
+			existing:  []models.WordUnit{noteUnit(1, "A", season1)},
+			generated: []models.WordUnit{noteUnit(0, "A2", season1)},
+			update:    []string{"A2"},
+		},
+		{
+			name:      "只有配方不同时不更新",
+			existing:  []models.WordUnit{noteUnit(1, "A", season1)},
+			generated: []models.WordUnit{noteUnit(0, "A", withRecipe)},
+			unchanged: []string{"A"},
+		},
+		{
+			name:      "新增和过期",
+			existing:  []models.WordUnit{noteUnit(1, "A", season1)},
+			generated: []models.WordUnit{noteUnit(0, "B", season2)},
+			create:    []string{"B"},
+			obsolete:  []string{"A"},
+		},
+		{
+			name:      "同一个键的多条现有规则一一对应，多出的视为过期",
+			existing:  []models.WordUnit{noteUnit(1, "A", season1), noteUnit(2, "A", season1)},
+			generated: []models.WordUnit{noteUnit(0, "A", season1)},
+			unchanged: []string{"A"},
+			obsolete:  []string{"A"},
+		},
+		{
+			name:      "被替换词相同但偏移量不同的规则不是同一条",
+			existing:  []models.WordUnit{offsetA},
+			generated: []models.WordUnit{offsetA, offsetB},
+			unchanged: []string{"Show"},
+			create:    []string{"Show"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := DiffWordUnitsWithKey(tt.existing, tt.generated, WordUnitKey)
+			check := func(kind string, got []models.WordUnit, want []string) {
+				if len(got) != len(want) {
+					t.Errorf("%s = %+v, 期望 %v", kind, got, want)
+					return
+				}
+				for i := range got {
+					if got[i].BeReplaced != want[i] {
+						t.Errorf("%s[%d] = %s, 期望 %s", kind, i, got[i].BeReplaced, want[i])
+					}
+				}
+			}
+			check("Create", plan.Create, tt.create)
+			check("Update", plan.Update, tt.update)
+			check("Obsolete", plan.Obsolete, tt.obsolete)
+			check("Unchanged", plan.Unchanged, tt.unchanged)
+		})
+	}
+}
+
+// TestDiffWordUnitsWithKeyUpdateID 更新的规则带上现有规则的ID和所属词组，重复键按顺序对应
+func TestDiffWordUnitsWithKeyUpdateID(t *testing.T) {
+	first := NewWordUnit(7, "Show", "", "E", ".", 1)
+	first.ID = 10
+	second := first
+	second.ID = 11
+
+	generated := []models.WordUnit{first, first}
+	generated[0].Replace, generated[1].Replace = "X", "Y"
+	generated[0].ID, generated[1].ID = 0, 0
+	generated[0].WordGroupID, generated[1].WordGroupID = 0, 0
+
+	plan := DiffWordUnitsWithKey([]models.WordUnit{first, second}, generated, WordUnitKey)
+	if len(plan.Update) != 2 {
+		t.Fatalf("Update = %+v", plan.Update)
+	}
+	for i, wantID := range []int{10, 11} {
+		if plan.Update[i].ID != wantID || plan.Update[i].WordGroupID != 7 {
+			t.Errorf("Update[%d] = ID %d 词组 %d, 期望 ID %d 词组 7", i, plan.Update[i].ID, plan.Update[i].WordGroupID, wantID)
+		}
+	}
+}
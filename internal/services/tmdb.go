@@ -2,6 +2,7 @@ package services
 
 import (
 	"bytes"
+	"container/list"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -9,6 +10,7 @@ import (
 	"os"
 
 	"github.com/harry/rename-by-tmdb/internal/models"
+	"github.com/harry/rename-by-tmdb/internal/utils"
 )
 
 // MediaType 表示媒体类型
@@ -23,7 +25,47 @@ const (
 
 // TMDBService 处理TMDB API相关的操作
 type TMDBService struct {
-	apiKey string
+	apiKey    string
+	language  string         // 获取名称和集名使用的语言
+	responses *responseCache // 已获取的响应，调用 EnableResponseCache 后启用
+}
+
+// responseCache 按请求地址（包含ID和语言）缓存TMDB响应，最多保留 limit 条，超出时丢弃最久未使用的响应
+type responseCache struct {
+	limit   int
+	entries map[string]*list.Element
+	order   *list.List // 最近使用的响应在前
+}
+
+// cachedResponse 缓存的一条响应
+type cachedResponse struct {
+	url  string
+	data []byte
+}
+
+// get 获取缓存的响应，并标记为最近使用
+func (c *responseCache) get(url string) ([]byte, bool) {
+	element, exists := c.entries[url]
+	if !exists {
+		return nil, false
+	}
+	c.order.MoveToFront(element)
+	return element.Value.(*cachedResponse).data, true
+}
+
+// put 缓存响应，超出上限时丢弃最久未使用的响应
+func (c *responseCache) put(url string, data []byte) {
+	if element, exists := c.entries[url]; exists {
+		element.Value.(*cachedResponse).data = data
+		c.order.MoveToFront(element)
+		return
+	}
+	c.entries[url] = c.order.PushFront(&cachedResponse{url: url, data: data})
+	for c.order.Len() > c.limit {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cachedResponse).url)
+	}
 }
 
 // NewTMDBService 创建新的TMDB服务实例
@@ -32,7 +74,16 @@ func NewTMDBService() (*TMDBService, error) {
 	if apiKey == "" {
 		return nil, fmt.Errorf("TMDB_API_KEY 环境变量为空")
 	}
-	return &TMDBService{apiKey: apiKey}, nil
+	return &TMDBService{apiKey: apiKey, language: utils.GetTMDBLanguage()}, nil
+}
+
+// EnableResponseCache 缓存最近的 limit 条响应，用于同一次运行中反复生成相同规则的场景（如向导每一步重新生成预览）
+func (s *TMDBService) EnableResponseCache(limit int) {
+	s.responses = &responseCache{
+		limit:   limit,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+	}
 }
 
 // checkTMDBResponse 检查TMDB API响应
@@ -61,33 +112,56 @@ func (s *TMDBService) checkTMDBResponse(resp *http.Response) error {
 	return fmt.Errorf("TMDB API错误: [%d] %s", tmdbErr.StatusCode, tmdbErr.StatusMessage)
 }
 
+// fetch 发送GET请求并解析JSON响应，启用了响应缓存时相同的请求只发送一次
+func (s *TMDBService) fetch(url string, v interface{}) error {
+	var data []byte
+	cached := false
+	if s.responses != nil {
+		data, cached = s.responses.get(url)
+	}
+	if !cached {
+		req, err := http.NewRequest("GET", url, nil)
+		if err != nil {
+			return fmt.Errorf("创建TMDB请求失败: %v", err)
+		}
+
+		req.Header.Set("accept", "application/json")
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", s.apiKey))
+
+		client := &http.Client{}
+		resp, err := client.Do(req)
+		if err != nil {
+			return fmt.Errorf("发送TMDB请求失败: %v", err)
+		}
+		defer resp.Body.Close()
+
+		// 检查响应状态
+		if err := s.checkTMDBResponse(resp); err != nil {
+			return err
+		}
+
+		data, err = io.ReadAll(resp.Body)
+		if err != nil {
+			return fmt.Errorf("读取TMDB响应失败: %v", err)
+		}
+		if s.responses != nil {
+			s.responses.put(url, data)
+		}
+	}
+
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("解析TMDB响应失败: %v", err)
+	}
+	return nil
+}
+
 // FetchMovieInfo 获取电影信息
 func (s *TMDBService) FetchMovieInfo(movieID string) (*models.TMDBMovie, error) {
-	url := fmt.Sprintf("https://api.tmdb.org/3/movie/%s?language=zh-CN", movieID)
-
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, fmt.Errorf("创建TMDB请求失败: %v", err)
-	}
-
-	req.Header.Set("accept", "application/json")
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", s.apiKey))
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("发送TMDB请求失败: %v", err)
-	}
-	defer resp.Body.Close()
-
-	// 检查响应状态
-	if err := s.checkTMDBResponse(resp); err != nil {
-		return nil, err
-	}
+	url := fmt.Sprintf("https://api.tmdb.org/3/movie/%s?language=%s", movieID, s.language)
 
 	var movie models.TMDBMovie
-	if err := json.NewDecoder(resp.Body).Decode(&movie); err != nil {
-		return nil, fmt.Errorf("解析TMDB响应失败: %v", err)
+	if err := s.fetch(url, &movie); err != nil {
+		return nil, err
 	}
 
 	return &movie, nil
@@ -95,31 +169,11 @@ func (s *TMDBService) FetchMovieInfo(movieID string) (*models.TMDBMovie, error)
 
 // FetchShowInfo 获取剧集信息
 func (s *TMDBService) FetchShowInfo(seriesID string) (*models.TMDBShow, error) {
-	url := fmt.Sprintf("https://api.tmdb.org/3/tv/%s?language=zh-CN", seriesID)
-
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, fmt.Errorf("创建TMDB请求失败: %v", err)
-	}
-
-	req.Header.Set("accept", "application/json")
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", s.apiKey))
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("发送TMDB请求失败: %v", err)
-	}
-	defer resp.Body.Close()
-
-	// 检查响应状态
-	if err := s.checkTMDBResponse(resp); err != nil {
-		return nil, err
-	}
+	url := fmt.Sprintf("https://api.tmdb.org/3/tv/%s?language=%s", seriesID, s.language)
 
 	var show models.TMDBShow
-	if err := json.NewDecoder(resp.Body).Decode(&show); err != nil {
-		return nil, fmt.Errorf("解析TMDB响应失败: %v", err)
+	if err := s.fetch(url, &show); err != nil {
+		return nil, err
 	}
 
 	return &show, nil
@@ -127,32 +181,49 @@ func (s *TMDBService) FetchShowInfo(seriesID string) (*models.TMDBShow, error) {
 
 // FetchSeasonDetails 获取季度详细信息
 func (s *TMDBService) FetchSeasonDetails(seriesID string, seasonNumber int) (*models.TMDBSeason, error) {
-	url := fmt.Sprintf("https://api.tmdb.org/3/tv/%s/season/%d?language=zh-CN", seriesID, seasonNumber)
+	return s.FetchSeasonDetailsInLanguage(seriesID, seasonNumber, s.language)
+}
 
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, fmt.Errorf("创建TMDB请求失败: %v", err)
+// FetchSeasonDetailsInLanguage 获取指定语言的季度详细信息
+func (s *TMDBService) FetchSeasonDetailsInLanguage(seriesID string, seasonNumber int, language string) (*models.TMDBSeason, error) {
+	url := fmt.Sprintf("https://api.tmdb.org/3/tv/%s/season/%d?language=%s", seriesID, seasonNumber, language)
+
+	var season models.TMDBSeason
+	if err := s.fetch(url, &season); err != nil {
+		return nil, err
 	}
 
-	req.Header.Set("accept", "application/json")
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", s.apiKey))
+	return &season, nil
+}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+// FetchSeasonWithTranslations 获取季度详细信息，并将其他语言的集名填充到每一集的Translations中
+func (s *TMDBService) FetchSeasonWithTranslations(seriesID string, seasonNumber int, languages []string) (*models.TMDBSeason, error) {
+	season, err := s.FetchSeasonDetails(seriesID, seasonNumber)
 	if err != nil {
-		return nil, fmt.Errorf("发送TMDB请求失败: %v", err)
-	}
-	defer resp.Body.Close()
-
-	// 检查响应状态
-	if err := s.checkTMDBResponse(resp); err != nil {
 		return nil, err
 	}
 
-	var season models.TMDBSeason
-	if err := json.NewDecoder(resp.Body).Decode(&season); err != nil {
-		return nil, fmt.Errorf("解析TMDB响应失败: %v", err)
-	}
-
-	return &season, nil
+	for _, language := range languages {
+		translated, err := s.FetchSeasonDetailsInLanguage(seriesID, seasonNumber, language)
+		if err != nil {
+			return nil, fmt.Errorf("获取%s集名失败: %v", language, err)
+		}
+
+		names := make(map[int]string)
+		for _, episode := range translated.Episodes {
+			names[episode.EpisodeNumber] = episode.Name
+		}
+		for i := range season.Episodes {
+			name, exists := names[season.Episodes[i].EpisodeNumber]
+			if !exists || name == "" {
+				continue
+			}
+			if season.Episodes[i].Translations == nil {
+				season.Episodes[i].Translations = make(map[string]string)
+			}
+			season.Episodes[i].Translations[language] = name
+		}
+	}
+
+	return season, nil
 }
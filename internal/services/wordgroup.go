@@ -3,63 +3,127 @@ package services
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
-	"os"
+	"net/url"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/harry/rename-by-tmdb/internal/models"
+	"github.com/harry/rename-by-tmdb/internal/utils"
 )
 
+// ChangeRecorder 记录词组服务在服务器上创建的词组和规则，用于回滚等
+type ChangeRecorder interface {
+	GroupCreated(group models.WordGroup)
+	UnitCreated(unit models.WordUnit)
+}
+
 // WordGroupService 处理词组相关的操作
 type WordGroupService struct {
+	name       string
 	apiBaseURL string
 	authToken  string
+	username   string
+	password   string
+	recorders  []ChangeRecorder
 }
 
-// NewWordGroupService 创建新的词组服务实例
+// NewWordGroupService 使用 API_BASE_URL/AUTH_TOKEN 配置的服务器创建词组服务实例
 func NewWordGroupService() (*WordGroupService, error) {
-	apiBaseURL := os.Getenv("API_BASE_URL")
-	if apiBaseURL == "" {
-		return nil, fmt.Errorf("API_BASE_URL 未设置")
+	return NewWordGroupServiceForProfile(utils.DefaultServerProfile())
+}
+
+// NewWordGroupServiceForProfile 使用指定的服务器配置创建词组服务实例
+// 未设置固定令牌时使用本地保存的登录令牌；设置了用户名和密码时，令牌失效后自动重新登录
+func NewWordGroupServiceForProfile(profile utils.ServerProfile) (*WordGroupService, error) {
+	if err := profile.Validate(); err != nil {
+		return nil, err
 	}
+	apiBaseURL := profile.APIBaseURL
 	// 确保URL以/api/v1结尾
 	if !strings.HasSuffix(apiBaseURL, "/api/v1") {
 		apiBaseURL = strings.TrimRight(apiBaseURL, "/") + "/api/v1"
 	}
 
-	authToken := os.Getenv("AUTH_TOKEN")
-	if authToken == "" {
-		return nil, fmt.Errorf("AUTH_TOKEN 未设置")
+	service := &WordGroupService{
+		name:       profile.Name,
+		apiBaseURL: apiBaseURL,
+		authToken:  profile.AuthToken,
+		username:   profile.Username,
+		password:   profile.Password,
 	}
+	if service.authToken == "" {
+		service.authToken = service.loadCachedToken()
+	}
+	if service.authToken == "" && !service.canLogin() {
+		return nil, fmt.Errorf("服务器 %s 未设置 AUTH_TOKEN 或用户名和密码，也没有已保存的登录令牌，请先运行 login", profile.Name)
+	}
+	return service, nil
+}
 
-	return &WordGroupService{
-		apiBaseURL: apiBaseURL,
-		authToken:  authToken,
-	}, nil
+// Name 返回服务器名称
+func (s *WordGroupService) Name() string {
+	return s.name
 }
 
-// CreateWordGroup 创建词组
-func (s *WordGroupService) CreateWordGroup(title string) (*models.WordGroup, error) {
-	url := fmt.Sprintf("%s/wordGroup/add", s.apiBaseURL)
+// AddRecorder 添加变更记录器，之后创建的词组和规则都会通知所有记录器
+func (s *WordGroupService) AddRecorder(recorder ChangeRecorder) {
+	s.recorders = append(s.recorders, recorder)
+}
 
-	body := map[string]string{
-		"title": title,
+// BaseURL 返回服务器API地址
+func (s *WordGroupService) BaseURL() string {
+	return s.apiBaseURL
+}
+
+// doRequest 发送API请求并检查响应，返回响应中的data字段
+// 没有令牌或令牌失效时，如果设置了用户名和密码则登录后重试一次
+func (s *WordGroupService) doRequest(method, path string, payload interface{}) (json.RawMessage, error) {
+	if s.authToken == "" {
+		if err := s.Login(); err != nil {
+			return nil, err
+		}
 	}
 
-	jsonData, err := json.Marshal(body)
-	if err != nil {
-		return nil, fmt.Errorf("JSON编码失败: %v", err)
+	data, err := s.sendRequest(method, path, payload, true)
+	var authErr *authError
+	if !errors.As(err, &authErr) || !s.canLogin() {
+		return data, err
+	}
+
+	fmt.Printf("服务器 %s 的令牌已失效，重新登录...\n", s.name)
+	if err := s.Login(); err != nil {
+		return nil, err
+	}
+	return s.sendRequest(method, path, payload, true)
+}
+
+// sendRequest 发送一次API请求并检查响应，withAuth 表示是否携带令牌
+func (s *WordGroupService) sendRequest(method, path string, payload interface{}, withAuth bool) (json.RawMessage, error) {
+	requestURL := fmt.Sprintf("%s%s", s.apiBaseURL, path)
+
+	var body io.Reader
+	if payload != nil {
+		jsonData, err := json.Marshal(payload)
+		if err != nil {
+			return nil, fmt.Errorf("JSON编码失败: %v", err)
+		}
+		body = bytes.NewBuffer(jsonData)
 	}
 
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequest(method, requestURL, body)
 	if err != nil {
 		return nil, fmt.Errorf("创建请求失败: %v", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", s.authToken)
+	if withAuth {
+		req.Header.Set("Authorization", s.authToken)
+	}
 
 	client := &http.Client{}
 	resp, err := client.Do(req)
@@ -75,9 +139,16 @@ func (s *WordGroupService) CreateWordGroup(title string) (*models.WordGroup, err
 
 	var apiResp models.APIResponse
 	if err := json.Unmarshal(respBody, &apiResp); err != nil {
+		if isAuthFailure(resp.StatusCode, 0) {
+			return nil, &authError{message: fmt.Sprintf("令牌无效或已过期，状态码: %d", resp.StatusCode)}
+		}
 		return nil, fmt.Errorf("解析响应失败: %v", err)
 	}
 
+	if withAuth && isAuthFailure(resp.StatusCode, apiResp.Code) {
+		return nil, &authError{message: fmt.Sprintf("令牌无效或已过期: [%d] %s", apiResp.Code, apiResp.Message)}
+	}
+
 	if resp.StatusCode != http.StatusOK {
 		return nil, fmt.Errorf("API请求失败，状态码: %d，错误信息: %s", resp.StatusCode, apiResp.Message)
 	}
@@ -86,87 +157,312 @@ func (s *WordGroupService) CreateWordGroup(title string) (*models.WordGroup, err
 		return nil, fmt.Errorf("API返回错误: [%d] %s", apiResp.Code, apiResp.Message)
 	}
 
-	var response models.WordGroupResponse
-	if err := json.Unmarshal(respBody, &response); err != nil {
+	return apiResp.Data, nil
+}
+
+// CreateWordGroup 创建词组
+func (s *WordGroupService) CreateWordGroup(title string) (*models.WordGroup, error) {
+	return s.CreateWordGroupWithType(title, 0)
+}
+
+// CreateWordGroupWithType 创建指定类型的词组，groupType 为0时使用服务器默认的类型
+func (s *WordGroupService) CreateWordGroupWithType(title string, groupType int) (*models.WordGroup, error) {
+	body := map[string]interface{}{
+		"title": title,
+	}
+	if groupType != 0 {
+		body["wordGroupType"] = groupType
+	}
+
+	data, err := s.doRequest("POST", "/wordGroup/add", body)
+	if err != nil {
+		return nil, err
+	}
+
+	var group models.WordGroup
+	if err := json.Unmarshal(data, &group); err != nil {
 		return nil, fmt.Errorf("解析响应数据失败: %v", err)
 	}
 
-	return &response.Data, nil
+	for _, recorder := range s.recorders {
+		recorder.GroupCreated(group)
+	}
+	return &group, nil
 }
 
-// AddWordUnit 添加替换规则
-func (s *WordGroupService) AddWordUnit(groupID int, beReplaced, replace, front, back string, offset int) error {
-	url := fmt.Sprintf("%s/wordUnit/add", s.apiBaseURL)
+// UpdateWordGroup 更新词组（如修改标题）
+func (s *WordGroupService) UpdateWordGroup(group models.WordGroup) error {
+	if group.ID == 0 {
+		return fmt.Errorf("更新词组时ID不能为空")
+	}
+	_, err := s.doRequest("POST", "/wordGroup/update", group)
+	return err
+}
+
+// DeleteWordGroup 删除词组
+func (s *WordGroupService) DeleteWordGroup(id int) error {
+	_, err := s.doRequest("POST", "/wordGroup/delete", map[string][]int{"ids": {id}})
+	return err
+}
+
+// FormatOffset 将集数偏移量转换为服务器使用的偏移量字符串，如 EP+1、EP-1，0返回空字符串
+func FormatOffset(offset int) string {
+	if offset > 0 {
+		return fmt.Sprintf("EP+%d", offset)
+	}
+	if offset < 0 {
+		return fmt.Sprintf("EP%d", offset) // 负数已经包含负号
+	}
+	return ""
+}
 
-	// 设置偏移量字符串和规则类型
-	var offsetStr string
-	ruleType := 200 // 默认类型，用于无偏移的情况
+// ParseOffset 解析服务器使用的偏移量字符串，如 EP+1、EP-1，空字符串返回0
+func ParseOffset(offset string) (int, error) {
+	offset = strings.TrimSpace(offset)
+	if offset == "" {
+		return 0, nil
+	}
+	if !strings.HasPrefix(offset, "EP") {
+		return 0, fmt.Errorf("无效的偏移量 '%s'", offset)
+	}
+	value, err := strconv.Atoi(strings.TrimPrefix(offset[2:], "+"))
+	if err != nil {
+		return 0, fmt.Errorf("无效的偏移量 '%s'", offset)
+	}
+	return value, nil
+}
 
+// NewWordUnit 构建替换规则，有偏移量时类型为替换+偏移，否则为替换
+func NewWordUnit(groupID int, beReplaced, replace, front, back string, offset int) models.WordUnit {
+	ruleType := models.WordUnitTypeReplace // 默认类型，用于无偏移的情况
 	if offset != 0 {
-		// 有偏移量时
-		ruleType = 300
-		if offset > 0 {
-			offsetStr = fmt.Sprintf("EP+%d", offset)
-		} else {
-			offsetStr = fmt.Sprintf("EP%d", offset) // 负数已经包含负号
-		}
+		ruleType = models.WordUnitTypeReplaceOffset
 	}
 
-	wordUnit := models.WordUnit{
+	return models.WordUnit{
 		ID:          0,
 		WordGroupID: groupID,
 		BeReplaced:  beReplaced,
 		Replace:     replace,
 		Front:       front,
 		Back:        back,
-		Offset:      offsetStr,
+		Offset:      FormatOffset(offset),
 		Enabled:     true,
 		Type:        ruleType,
 		Regex:       true,
 		Note:        "",
 	}
+}
 
-	jsonData, err := json.Marshal(wordUnit)
-	if err != nil {
-		return fmt.Errorf("JSON编码失败: %v", err)
+// NewPlainReplaceUnit 构建不使用正则的替换规则，被替换词按原文匹配
+func NewPlainReplaceUnit(groupID int, beReplaced, replace string) models.WordUnit {
+	unit := NewWordUnit(groupID, beReplaced, replace, "", "", 0)
+	unit.Regex = false
+	return unit
+}
+
+// NewBlockWordUnit 构建屏蔽词规则，从文件名中删除匹配的内容
+func NewBlockWordUnit(groupID int, pattern string, regex bool) models.WordUnit {
+	return models.WordUnit{
+		WordGroupID: groupID,
+		BeReplaced:  pattern,
+		Enabled:     true,
+		Type:        models.WordUnitTypeBlock,
+		Regex:       regex,
 	}
+}
 
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
-	if err != nil {
-		return fmt.Errorf("创建请求失败: %v", err)
+// NewOffsetWordUnit 构建集数偏移规则，按前后定位词定位集数并偏移
+func NewOffsetWordUnit(groupID int, front, back string, offset int) models.WordUnit {
+	return models.WordUnit{
+		WordGroupID: groupID,
+		Front:       front,
+		Back:        back,
+		Offset:      FormatOffset(offset),
+		Enabled:     true,
+		Type:        models.WordUnitTypeOffset,
+		Regex:       true,
 	}
+}
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", s.authToken)
+// AddWordUnit 添加替换规则
+func (s *WordGroupService) AddWordUnit(groupID int, beReplaced, replace, front, back string, offset int) error {
+	_, err := s.CreateWordUnit(NewWordUnit(groupID, beReplaced, replace, front, back, offset))
+	return err
+}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+// CreateWordUnit 按完整的规则内容添加替换规则，返回带ID的规则
+func (s *WordGroupService) CreateWordUnit(wordUnit models.WordUnit) (*models.WordUnit, error) {
+	created, err := s.CreateWordUnits(wordUnit.WordGroupID, []models.WordUnit{wordUnit})
 	if err != nil {
-		return fmt.Errorf("发送请求失败: %v", err)
+		return nil, err
 	}
-	defer resp.Body.Close()
+	return &created[0], nil
+}
 
-	respBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return fmt.Errorf("读取响应失败: %v", err)
+// CreateWordUnits 按顺序将规则添加到词组，返回带ID的规则；出错时返回已添加的规则和错误
+// 服务器未返回ID时，整批添加完成后获取一次词组规则，按规则内容找回ID；
+// 找回ID失败时已添加的规则仍会通知记录器（ID为0），以便回滚和撤销时报告
+func (s *WordGroupService) CreateWordUnits(groupID int, units []models.WordUnit) ([]models.WordUnit, error) {
+	var created []models.WordUnit
+	var addErr error
+	for _, unit := range units {
+		unit.ID = 0
+		unit.WordGroupID = groupID
+		data, err := s.doRequest("POST", "/wordUnit/add", unit)
+		if err != nil {
+			addErr = fmt.Errorf("新增规则 %s 失败: %v", unit.BeReplaced, err)
+			break
+		}
+
+		// 优先使用响应中返回的规则ID
+		var returned models.WordUnit
+		if err := json.Unmarshal(data, &returned); err == nil {
+			unit.ID = returned.ID
+		}
+		created = append(created, unit)
 	}
 
-	var apiResp models.APIResponse
-	if err := json.Unmarshal(respBody, &apiResp); err != nil {
-		return fmt.Errorf("解析响应失败: %v", err)
+	resolveErr := s.resolveWordUnitIDs(groupID, created)
+	for _, unit := range created {
+		if unit.ID == 0 {
+			fmt.Printf("警告：规则 %s 已添加，但未获取到ID，回滚和撤销时需要手动删除\n", unit.BeReplaced)
+		}
+		for _, recorder := range s.recorders {
+			recorder.UnitCreated(unit)
+		}
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("API请求失败，状态码: %d，错误信息: %s", resp.StatusCode, apiResp.Message)
+	switch {
+	case addErr != nil && resolveErr != nil:
+		return created, fmt.Errorf("%v；%v", addErr, resolveErr)
+	case resolveErr != nil:
+		return created, resolveErr
 	}
+	return created, addErr
+}
 
-	if apiResp.Code != 20000 {
-		return fmt.Errorf("API返回错误: [%d] %s", apiResp.Code, apiResp.Message)
+// resolveWordUnitIDs 为服务器未返回ID的新建规则找回ID：内容相同的规则中取ID最大且未被占用的几条，按添加顺序对应
+// 找不到的规则ID保持为0，返回的错误列出这些规则
+func (s *WordGroupService) resolveWordUnitIDs(groupID int, created []models.WordUnit) error {
+	missing := make(map[string][]int) // 内容到缺少ID的新建规则下标
+	known := make(map[int]bool)
+	for i, unit := range created {
+		if unit.ID == 0 {
+			key := wordUnitContentKey(unit)
+			missing[key] = append(missing[key], i)
+		} else {
+			known[unit.ID] = true
+		}
+	}
+	if len(missing) == 0 {
+		return nil
 	}
 
+	units, err := s.ListWordUnits(groupID)
+	if err != nil {
+		return fmt.Errorf("规则已添加，但获取规则ID失败: %v", err)
+	}
+	candidates := make(map[string][]int)
+	for _, unit := range units {
+		key := wordUnitContentKey(unit)
+		if _, exists := missing[key]; exists && !known[unit.ID] {
+			candidates[key] = append(candidates[key], unit.ID)
+		}
+	}
+
+	var unresolved []string
+	for key, indexes := range missing {
+		ids := candidates[key]
+		sort.Ints(ids)
+		if len(ids) < len(indexes) {
+			unresolved = append(unresolved, created[indexes[0]].BeReplaced)
+			continue
+		}
+		// 新建的规则ID最大，按添加顺序对应
+		ids = ids[len(ids)-len(indexes):]
+		for n, i := range indexes {
+			created[i].ID = ids[n]
+		}
+	}
+	if len(unresolved) > 0 {
+		sort.Strings(unresolved)
+		return fmt.Errorf("规则已添加，但在词组中找不到新建的规则 %s", strings.Join(unresolved, "、"))
+	}
 	return nil
 }
 
+// wordUnitContentKey 返回按内容识别规则的键（类型、被替换词、替换词、前后定位词和偏移量）
+func wordUnitContentKey(unit models.WordUnit) string {
+	return fmt.Sprintf("%d\x00%s\x00%s\x00%s\x00%s\x00%s", unit.Type, unit.BeReplaced, unit.Replace, unit.Front, unit.Back, unit.Offset)
+}
+
+// AddBlockWord 添加屏蔽词规则
+func (s *WordGroupService) AddBlockWord(groupID int, pattern string, regex bool) error {
+	_, err := s.CreateWordUnit(NewBlockWordUnit(groupID, pattern, regex))
+	return err
+}
+
+// AddOffsetWordUnit 添加集数偏移规则
+func (s *WordGroupService) AddOffsetWordUnit(groupID int, front, back string, offset int) error {
+	if front == "" && back == "" {
+		return fmt.Errorf("集数偏移规则的前后定位词不能都为空")
+	}
+	_, err := s.CreateWordUnit(NewOffsetWordUnit(groupID, front, back, offset))
+	return err
+}
+
+// UpdateWordUnit 更新替换规则
+func (s *WordGroupService) UpdateWordUnit(wordUnit models.WordUnit) error {
+	if wordUnit.ID == 0 {
+		return fmt.Errorf("更新替换规则时ID不能为空")
+	}
+	_, err := s.doRequest("POST", "/wordUnit/update", wordUnit)
+	return err
+}
+
+// DeleteWordUnit 删除替换规则
+func (s *WordGroupService) DeleteWordUnit(id int) error {
+	_, err := s.doRequest("POST", "/wordUnit/delete", map[string][]int{"ids": {id}})
+	return err
+}
+
+// WordUnitList 表示替换规则列表响应
+type WordUnitList struct {
+	Total    int               `json:"total"`
+	PageNum  int               `json:"pageNum"`
+	PageSize int               `json:"pageSize"`
+	List     []models.WordUnit `json:"list"`
+}
+
+// wordUnitPageSize 分页获取替换规则时每页的数量
+const wordUnitPageSize = 200
+
+// ListWordUnits 获取词组下的所有替换规则
+func (s *WordGroupService) ListWordUnits(groupID int) ([]models.WordUnit, error) {
+	var units []models.WordUnit
+	for pageNum := 1; ; pageNum++ {
+		path := fmt.Sprintf("/wordUnit/page?wordGroupId=%d&pageNum=%d&pageSize=%d&keyword=",
+			groupID, pageNum, wordUnitPageSize)
+		data, err := s.doRequest("GET", path, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		var page WordUnitList
+		if err := json.Unmarshal(data, &page); err != nil {
+			return nil, fmt.Errorf("解析响应数据失败: %v", err)
+		}
+
+		units = append(units, page.List...)
+		if len(page.List) == 0 || len(units) >= page.Total {
+			break
+		}
+	}
+
+	return units, nil
+}
+
 // WordGroupList 表示词组列表响应
 type WordGroupList struct {
 	Total    int                `json:"total"`
@@ -175,47 +471,71 @@ type WordGroupList struct {
 	List     []models.WordGroup `json:"list"`
 }
 
-// GetWordGroupList 获取词组列表
-func (s *WordGroupService) GetWordGroupList() (*WordGroupList, error) {
-	url := fmt.Sprintf("%s/wordGroup/page?pageNum=1&pageSize=9999&keyword=", s.apiBaseURL)
+// wordGroupPageSize 分页获取词组时每页的数量
+const wordGroupPageSize = 200
 
-	req, err := http.NewRequest("GET", url, nil)
+// GetWordGroupPage 获取一页词组，keyword 为服务器端按标题搜索的关键词，为空表示不限
+func (s *WordGroupService) GetWordGroupPage(pageNum, pageSize int, keyword string) (*WordGroupList, error) {
+	path := fmt.Sprintf("/wordGroup/page?pageNum=%d&pageSize=%d&keyword=%s",
+		pageNum, pageSize, url.QueryEscape(keyword))
+	data, err := s.doRequest("GET", path, nil)
 	if err != nil {
-		return nil, fmt.Errorf("创建请求失败: %v", err)
+		return nil, err
 	}
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", s.authToken)
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("发送请求失败: %v", err)
+	var list WordGroupList
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("解析响应数据失败: %v", err)
 	}
-	defer resp.Body.Close()
 
-	respBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("读取响应失败: %v", err)
-	}
+	return &list, nil
+}
+
+// ListWordGroups 分页获取标题包含关键词的所有词组，keyword 为空时获取全部词组
+func (s *WordGroupService) ListWordGroups(keyword string) ([]models.WordGroup, error) {
+	var groups []models.WordGroup
+	for pageNum := 1; ; pageNum++ {
+		page, err := s.GetWordGroupPage(pageNum, wordGroupPageSize, keyword)
+		if err != nil {
+			return nil, err
+		}
 
-	var apiResp struct {
-		Code    int           `json:"code"`
-		Message string        `json:"message"`
-		Data    WordGroupList `json:"data"`
+		groups = append(groups, page.List...)
+		if len(page.List) == 0 || len(groups) >= page.Total {
+			break
+		}
 	}
 
-	if err := json.Unmarshal(respBody, &apiResp); err != nil {
-		return nil, fmt.Errorf("解析响应失败: %v", err)
+	return groups, nil
+}
+
+// FindWordGroup 查找已存在的词组
+// 标题中包含 tmdbid 时按 tmdbid 和类型搜索，TMDB标题或年份变化后仍能找到原词组；否则按标题精确匹配
+func (s *WordGroupService) FindWordGroup(title string) (*models.WordGroup, error) {
+	tmdbID, mediaType, hasToken := utils.ParseTMDBToken(title)
+	keyword := title
+	if hasToken {
+		keyword = fmt.Sprintf("tmdbid=%s;type=%s", tmdbID, mediaType)
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API请求失败，状态码: %d，错误信息: %s", resp.StatusCode, apiResp.Message)
+	groups, err := s.ListWordGroups(keyword)
+	if err != nil {
+		return nil, err
 	}
 
-	if apiResp.Code != 20000 {
-		return nil, fmt.Errorf("API返回错误: [%d] %s", apiResp.Code, apiResp.Message)
+	var found *models.WordGroup
+	for i, group := range groups {
+		if group.Title == title {
+			return &groups[i], nil
+		}
+		if !hasToken || found != nil {
+			continue
+		}
+		// 服务器的关键词搜索为模糊匹配，需确认 tmdbid 和类型完全一致
+		if groupID, groupType, ok := utils.ParseTMDBToken(group.Title); ok && groupID == tmdbID && groupType == mediaType {
+			found = &groups[i]
+		}
 	}
 
-	return &apiResp.Data, nil
+	return found, nil
 }
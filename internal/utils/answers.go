@@ -0,0 +1,296 @@
+package utils
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// 剧集交互中各问题的标识，预设按标识预填回答
+const (
+	AnswerDateMode        = "date_mode"
+	AnswerDateFormats     = "date_formats"
+	AnswerDateTolerance   = "date_tolerance"
+	AnswerSameDate        = "same_date"
+	AnswerMultiEpisode    = "multi_episode"
+	AnswerMultiRanges     = "multi_ranges"
+	AnswerSpecials        = "specials"
+	AnswerSpecialMapping  = "special_mapping"
+	AnswerEpisodeTitles   = "episode_titles"
+	AnswerTitleLanguages  = "title_languages"
+	AnswerPartEpisodes    = "part_episodes"
+	AnswerPartInfo        = "part_info"
+	AnswerHasSeason       = "has_season"
+	AnswerSeasons         = "seasons"
+	AnswerIncludeSpecials = "include_specials"
+	AnswerOffset          = "offset"
+	AnswerPadZero         = "pad_zero"
+	AnswerContinuous      = "continuous"
+	AnswerSeasonOverlap   = "season_overlap"
+	AnswerBlockWords      = "block_words"
+	AnswerSync            = "sync"
+	AnswerDeleteObsolete  = "delete_obsolete"
+	AnswerRollback        = "rollback"
+)
+
+// AnswerKeys 可以写入预设的问题标识及说明，按提问顺序排列
+var AnswerKeys = []struct {
+	Key   string
+	Usage string
+}{
+	{AnswerDateMode, "是否以日期判断集数（y/n）"},
+	{AnswerDateFormats, "日期格式（如 YYYYMMDD;MM.DD）"},
+	{AnswerDateTolerance, "播出日期容差天数"},
+	{AnswerSameDate, "同日多集的区分方式（1 合并、2 part、3 集数）"},
+	{AnswerMultiEpisode, "是否为多集文件（y/n）"},
+	{AnswerMultiRanges, "多集文件的集数区间（如 1-2;3-4，auto 为每2集自动分组）"},
+	{AnswerSpecials, "是否为特别篇映射模式（y/n）"},
+	{AnswerSpecialMapping, "对特别篇默认映射的修改（如 OVA1=3;12.5=0，空为使用默认映射）"},
+	{AnswerEpisodeTitles, "是否按集名匹配（y/n）"},
+	{AnswerTitleLanguages, "额外匹配的集名语言（如 en-US;ja-JP，none 为只使用中文集名）"},
+	{AnswerPartEpisodes, "是否有part剧集（y/n）"},
+	{AnswerPartInfo, "part剧集信息（如 2:2;5:2）"},
+	{AnswerHasSeason, "是否使用原文件名季数（y/n）"},
+	{AnswerSeasons, "要生成的季数（如 1;2，all 为所有季）"},
+	{AnswerIncludeSpecials, "生成所有季时是否包含第0季（y/n）"},
+	{AnswerOffset, "集数偏移量（如 +1、-1、0）"},
+	{AnswerPadZero, "集数是否补0站位（y/n）"},
+	{AnswerContinuous, "集数是否连续（y/n）"},
+	{AnswerSeasonOverlap, "不同季规则重叠时的处理方式（1 保持不变、2 要求季数标记、3 绝对集数）"},
+	{AnswerBlockWords, "是否同时生成屏蔽词规则（y/n）"},
+	{AnswerSync, "词组已存在时是否同步规则（y/n）"},
+	{AnswerDeleteObsolete, "是否删除过期规则（y/n）"},
+	{AnswerRollback, "上传失败时是否回滚（y/n）"},
+}
+
+// presetAnswers 当前使用的预设，提问时作为默认回答
+var presetAnswers map[string]string
+
+// replaying 是否正在重放配方，重放时不再读取输入，回答取自 replayAnswers
+var replaying bool
+
+// replayAnswers 重放的配方中记录的回答
+var replayAnswers map[string]string
+
+// ReplayQuestion 重放配方时提出的问题
+type ReplayQuestion struct {
+	Key    string
+	Prompt string
+}
+
+// replayTrace 重放时按顺序提出的问题
+var replayTrace []ReplayQuestion
+
+// OnReplayQuestion 重放时每次显示问题和回答后调用，可用于获取提问前的输出（如选项列表）
+var OnReplayQuestion func(key string)
+
+// recordedAnswers 本次运行中各问题的回答，已转换为预设中使用的格式
+var recordedAnswers = make(map[string]string)
+
+// defaultHintRegexp 匹配提示中说明直接回车默认值的部分，使用预设时替换为预设值
+var defaultHintRegexp = regexp.MustCompile(`直接回车[^，,）)]*`)
+
+// ValidateAnswers 检查预设中的问题标识是否有效
+func ValidateAnswers(answers map[string]string) error {
+	for key := range answers {
+		if !isAnswerKey(key) {
+			return fmt.Errorf("未知的问题标识 '%s'", key)
+		}
+	}
+	return nil
+}
+
+// isAnswerKey 判断是否为有效的问题标识，可带范围（如 same_date@S2）
+func isAnswerKey(key string) bool {
+	base, scope := splitAnswerKey(key)
+	if base != key && scope == "" {
+		return false
+	}
+	for _, answerKey := range AnswerKeys {
+		if answerKey.Key == base {
+			return true
+		}
+	}
+	return false
+}
+
+// AnswerUsage 返回问题标识的说明，带范围的标识在说明后注明范围，未知的标识返回标识本身
+func AnswerUsage(key string) string {
+	base, scope := splitAnswerKey(key)
+	for _, answerKey := range AnswerKeys {
+		if answerKey.Key == base {
+			if scope != "" {
+				return fmt.Sprintf("%s（%s）", answerKey.Usage, scope)
+			}
+			return answerKey.Usage
+		}
+	}
+	return key
+}
+
+// ScopedAnswerKey 返回问题在某一范围内的标识，如第2季的同日多集区分方式为 same_date@S2
+// 同一问题在多个范围（如每一季、每个服务器）中提问时按范围分别记录和重放回答
+func ScopedAnswerKey(key, scope string) string {
+	if scope == "" {
+		return key
+	}
+	return key + "@" + scope
+}
+
+// splitAnswerKey 将问题标识拆分为不带范围的标识和范围
+func splitAnswerKey(key string) (string, string) {
+	if i := strings.Index(key, "@"); i >= 0 {
+		return key[:i], key[i+1:]
+	}
+	return key, ""
+}
+
+// lookupAnswer 查找问题的回答，没有该范围的回答时使用不带范围的回答
+func lookupAnswer(answers map[string]string, key string) (string, bool) {
+	if value, exists := answers[key]; exists {
+		return value, true
+	}
+	base, _ := splitAnswerKey(key)
+	value, exists := answers[base]
+	return value, exists
+}
+
+// SetPresetAnswers 设置当前使用的预设
+func SetPresetAnswers(answers map[string]string) {
+	presetAnswers = answers
+}
+
+// SetReplayAnswers 开始重放配方：之后的提问直接使用配方中的回答，配方中没有的问题使用默认值
+func SetReplayAnswers(answers map[string]string) {
+	replaying = true
+	replayAnswers = answers
+	replayTrace = nil
+	recordedAnswers = make(map[string]string)
+}
+
+// StopReplay 结束重放，之后的提问重新读取输入
+func StopReplay() {
+	replaying = false
+	replayAnswers = nil
+}
+
+// ReplayTrace 返回本次重放中按顺序提出的问题
+func ReplayTrace() []ReplayQuestion {
+	return replayTrace
+}
+
+// IsReplaying 判断是否正在重放配方
+func IsReplaying() bool {
+	return replaying
+}
+
+// PresetAnswers 返回当前使用的预设，没有使用预设时返回 nil
+func PresetAnswers() map[string]string {
+	return presetAnswers
+}
+
+// RecordedAnswers 返回本次运行中各问题的回答，可保存为新的预设
+func RecordedAnswers() map[string]string {
+	answers := make(map[string]string, len(recordedAnswers))
+	for key, value := range recordedAnswers {
+		answers[key] = value
+	}
+	return answers
+}
+
+// FormatAnswers 按提问顺序格式化回答，每行一个
+func FormatAnswers(answers map[string]string) string {
+	var lines []string
+	for _, answerKey := range AnswerKeys {
+		if value, exists := answers[answerKey.Key]; exists {
+			lines = append(lines, fmt.Sprintf("  %-17s %s", answerKey.Key, value))
+		}
+
+		// 带范围的回答排在不带范围的回答之后
+		var scoped []string
+		for key := range answers {
+			if base, scope := splitAnswerKey(key); base == answerKey.Key && scope != "" {
+				scoped = append(scoped, key)
+			}
+		}
+		sort.Strings(scoped)
+		for _, key := range scoped {
+			lines = append(lines, fmt.Sprintf("  %-17s %s", key, answers[key]))
+		}
+	}
+
+	// 未知的标识排在最后，便于发现配置文件中的错误
+	var unknown []string
+	for key := range answers {
+		if !isAnswerKey(key) {
+			unknown = append(unknown, key)
+		}
+	}
+	sort.Strings(unknown)
+	for _, key := range unknown {
+		lines = append(lines, fmt.Sprintf("  %-17s %s（未知的问题标识）", key, answers[key]))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// answer 提问并返回去除首尾空白的输入，key 为空时只读取输入；使用预设时提示中显示预设值，直接回车返回预设值
+// 重放配方时显示提示和配方中的回答，不读取输入
+func (p *Prompter) answer(key, prompt string) (string, error) {
+	if key == "" {
+		return p.ReadLine(prompt)
+	}
+
+	if replaying {
+		replayTrace = append(replayTrace, ReplayQuestion{Key: key, Prompt: prompt})
+		value, _ := lookupAnswer(replayAnswers, key)
+		input := strings.TrimSpace(value)
+		p.Printf("%s%s\n", prompt, input)
+		if OnReplayQuestion != nil {
+			OnReplayQuestion(key)
+		}
+		return input, nil
+	}
+
+	preset, hasPreset := lookupAnswer(presetAnswers, key)
+	if hasPreset {
+		hint := fmt.Sprintf("直接回车使用预设值 %s", preset)
+		if defaultHintRegexp.MatchString(prompt) {
+			prompt = defaultHintRegexp.ReplaceAllLiteralString(prompt, hint)
+		} else {
+			prompt = strings.TrimSuffix(prompt, ": ") + "（" + hint + "）: "
+		}
+	}
+
+	input, err := p.ReadLine(prompt)
+	if err != nil {
+		return "", err
+	}
+	if input == "" && hasPreset {
+		input = strings.TrimSpace(preset)
+	}
+	return input, nil
+}
+
+// recordAnswer 记录问题的回答
+// 带范围的问题第一次回答时记为不带范围的回答，之后只记录与之不同的回答，重放时没有该范围的回答则使用不带范围的回答
+func recordAnswer(key, value string) {
+	base, _ := splitAnswerKey(key)
+	if base == key {
+		recordedAnswers[key] = value
+		return
+	}
+	if first, exists := recordedAnswers[base]; !exists {
+		recordedAnswers[base] = value
+	} else if first != value {
+		recordedAnswers[key] = value
+	}
+}
+
+// formatYesNo 将是/否转换为预设中使用的 y/n
+func formatYesNo(value bool) string {
+	if value {
+		return "y"
+	}
+	return "n"
+}
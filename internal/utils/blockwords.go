@@ -0,0 +1,49 @@
+package utils
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// DefaultBlockWordPatterns 默认的屏蔽词正则，用于删除文件名中常见的网址广告标记
+var DefaultBlockWordPatterns = []string{
+	`【[^】]*(?:[Ww]{3}\.|\.com|\.net|\.cn|\.cc|\.tv|\.me|\.org)[^】]*】`,     // 【www.xxx.com】
+	`\[[^\]]*(?:[Ww]{3}\.|\.com|\.net|\.cn|\.cc|\.tv|\.me|\.org)[^\]]*\]`, // [www.xxx.com]
+	`[Ww]{3}\.[A-Za-z0-9-]+\.(?:com|net|cn|cc|tv|me|org)[._ -]?`,          // 文件名开头或中间的 www.xxx.com.
+}
+
+// LoadBlockWordPatterns 从文件读取屏蔽词正则，每行一条，忽略空行和以#开头的注释行
+func LoadBlockWordPatterns(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("打开屏蔽词文件失败: %v", err)
+	}
+	defer file.Close()
+
+	var patterns []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		patterns = append(patterns, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("读取屏蔽词文件失败: %v", err)
+	}
+	if len(patterns) == 0 {
+		return nil, fmt.Errorf("屏蔽词文件 %s 中没有任何屏蔽词", path)
+	}
+	return patterns, nil
+}
+
+// GetBlockWordPatterns 获取屏蔽词正则：设置了 BLOCK_WORDS_FILE 时从该文件读取，否则使用默认列表
+func GetBlockWordPatterns() ([]string, error) {
+	if path := os.Getenv("BLOCK_WORDS_FILE"); path != "" {
+		return LoadBlockWordPatterns(path)
+	}
+	return DefaultBlockWordPatterns, nil
+}
@@ -0,0 +1,209 @@
+package utils
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// airDateLayout TMDB返回的播出日期格式
+const airDateLayout = "2006-01-02"
+
+// DateFormat 表示文件名中的日期格式
+type DateFormat struct {
+	Name   string // 显示名称，如 YYYYMMDD
+	Layout string // Go时间格式
+}
+
+// DateFormats 支持的日期格式列表
+var DateFormats = []DateFormat{
+	{Name: "YYYYMMDD", Layout: "20060102"},
+	{Name: "YYYY.MM.DD", Layout: "2006.01.02"},
+	{Name: "YYYY-MM-DD", Layout: "2006-01-02"},
+	{Name: "YYMMDD", Layout: "060102"},
+	{Name: "MM.DD", Layout: "01.02"},
+}
+
+// ParseDateFormats 解析用户选择的日期格式（序号或名称，多个用;分隔），为空时默认为YYYYMMDD
+func ParseDateFormats(input string) ([]DateFormat, error) {
+	input = strings.TrimSpace(input)
+	if input == "" {
+		return []DateFormat{DateFormats[0]}, nil
+	}
+
+	var formats []DateFormat
+	seen := make(map[string]bool)
+	for _, s := range strings.Split(input, ";") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+
+		var format *DateFormat
+		if index, err := strconv.Atoi(s); err == nil {
+			if index < 1 || index > len(DateFormats) {
+				return nil, fmt.Errorf("无效的日期格式序号: %d", index)
+			}
+			format = &DateFormats[index-1]
+		} else {
+			for i := range DateFormats {
+				if strings.EqualFold(DateFormats[i].Name, s) {
+					format = &DateFormats[i]
+					break
+				}
+			}
+			if format == nil {
+				return nil, fmt.Errorf("无效的日期格式 '%s'", s)
+			}
+		}
+
+		if !seen[format.Name] {
+			seen[format.Name] = true
+			formats = append(formats, *format)
+		}
+	}
+
+	if len(formats) == 0 {
+		return []DateFormat{DateFormats[0]}, nil
+	}
+	return formats, nil
+}
+
+// FormatAirDate 按日期格式转换TMDB返回的播出日期（YYYY-MM-DD），如 2024-01-05 按 MM.DD 转换为 01.05
+func FormatAirDate(airDate string, format DateFormat) (string, error) {
+	date, err := time.Parse(airDateLayout, airDate)
+	if err != nil {
+		return "", fmt.Errorf("无效的播出日期 '%s': %v", airDate, err)
+	}
+	return date.Format(format.Layout), nil
+}
+
+// dateBoundaryPrefix、dateBoundarySuffix 日期前后不能紧接数字，避免一种格式的日期匹配到另一个日期的一部分
+// （如 2020-12-01 的 YYMMDD 形式 201201 出现在 20120120 中）；使用环视，不占用日期前后的字符
+const (
+	dateBoundaryPrefix = "(?<![0-9])"
+	dateBoundarySuffix = "(?![0-9])"
+)
+
+// GenerateDatePattern 生成匹配播出日期的正则表达式，容差范围内的每一天按每种格式各生成一个分支，日期前后不能紧接数字
+func GenerateDatePattern(airDate string, formats []DateFormat, tolerance int) (string, error) {
+	date, err := time.Parse(airDateLayout, airDate)
+	if err != nil {
+		return "", fmt.Errorf("无效的播出日期 '%s': %v", airDate, err)
+	}
+	if tolerance < 0 {
+		tolerance = 0
+	}
+
+	var patterns []string
+	seen := make(map[string]bool)
+	for day := -tolerance; day <= tolerance; day++ {
+		current := date.AddDate(0, 0, day)
+		for _, format := range formats {
+			pattern := regexp.QuoteMeta(current.Format(format.Layout))
+			if !seen[pattern] {
+				seen[pattern] = true
+				patterns = append(patterns, pattern)
+			}
+		}
+	}
+
+	alternatives := patterns[0]
+	if len(patterns) > 1 {
+		alternatives = fmt.Sprintf("(?:%s)", strings.Join(patterns, "|"))
+	}
+	return dateBoundaryPrefix + alternatives + dateBoundarySuffix, nil
+}
+
+// TrimDateBoundary 去掉 GenerateDatePattern 生成的日期前后的边界，返回日期分支；没有边界时返回 false
+func TrimDateBoundary(pattern string) (string, bool) {
+	if !strings.HasPrefix(pattern, dateBoundaryPrefix) || !strings.HasSuffix(pattern, dateBoundarySuffix) {
+		return "", false
+	}
+	return pattern[len(dateBoundaryPrefix) : len(pattern)-len(dateBoundarySuffix)], true
+}
+
+// DateOverlap 表示两集的播出日期在容差范围内重叠
+type DateOverlap struct {
+	FirstEpisode  int
+	FirstAirDate  string
+	SecondEpisode int
+	SecondAirDate string
+}
+
+// FindDateOverlaps 查找容差窗口互相重叠的集数，airDates为集数到播出日期（YYYY-MM-DD）的映射
+// 播出日期完全相同的集数不在此列，由FindSameDateEpisodes单独处理
+func FindDateOverlaps(airDates map[int]string, tolerance int) []DateOverlap {
+	type datedEpisode struct {
+		episode int
+		airDate string
+		date    time.Time
+	}
+
+	var episodes []datedEpisode
+	for episode, airDate := range airDates {
+		date, err := time.Parse(airDateLayout, airDate)
+		if err != nil {
+			continue
+		}
+		episodes = append(episodes, datedEpisode{episode: episode, airDate: airDate, date: date})
+	}
+	sort.Slice(episodes, func(i, j int) bool {
+		if episodes[i].date.Equal(episodes[j].date) {
+			return episodes[i].episode < episodes[j].episode
+		}
+		return episodes[i].date.Before(episodes[j].date)
+	})
+
+	// 两个窗口重叠的条件：日期相差不超过2倍容差
+	window := time.Duration(2*tolerance) * 24 * time.Hour
+	var overlaps []DateOverlap
+	for i := 0; i < len(episodes); i++ {
+		for j := i + 1; j < len(episodes); j++ {
+			diff := episodes[j].date.Sub(episodes[i].date)
+			if diff > window {
+				break
+			}
+			if diff == 0 {
+				continue
+			}
+			overlaps = append(overlaps, DateOverlap{
+				FirstEpisode:  episodes[i].episode,
+				FirstAirDate:  episodes[i].airDate,
+				SecondEpisode: episodes[j].episode,
+				SecondAirDate: episodes[j].airDate,
+			})
+		}
+	}
+	return overlaps
+}
+
+// FindSameDateEpisodes 查找播出日期相同的集数，返回播出日期到集数列表（升序）的映射，只包含多于一集的日期
+func FindSameDateEpisodes(airDates map[int]string) map[string][]int {
+	byDate := make(map[string][]int)
+	for episode, airDate := range airDates {
+		byDate[airDate] = append(byDate[airDate], episode)
+	}
+
+	collisions := make(map[string][]int)
+	for airDate, episodes := range byDate {
+		if len(episodes) > 1 {
+			sort.Ints(episodes)
+			collisions[airDate] = episodes
+		}
+	}
+	return collisions
+}
+
+// IsConsecutive 判断升序的集数是否连续，如 1、2、3
+func IsConsecutive(episodes []int) bool {
+	for i := 1; i < len(episodes); i++ {
+		if episodes[i] != episodes[i-1]+1 {
+			return false
+		}
+	}
+	return true
+}
@@ -5,13 +5,31 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
 // LoadEnv 加载环境变量
 func LoadEnv() error {
+	path, err := loadEnvFile()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("成功加载配置文件: %s\n", path)
+	return nil
+}
+
+// LoadEnvQuiet 加载环境变量，成功时不输出提示（用于输出需要被程序解析的场景）
+func LoadEnvQuiet() error {
+	_, err := loadEnvFile()
+	return err
+}
+
+// loadEnvFile 依次尝试可能的位置加载 .env 文件，返回成功加载的路径
+func loadEnvFile() (string, error) {
 	// 尝试多个可能的位置
 	envPaths := []string{
 		".env",                   // 当前工作目录
@@ -40,13 +58,12 @@ func LoadEnv() error {
 	for _, path := range envPaths {
 		err := godotenv.Load(path)
 		if err == nil {
-			fmt.Printf("成功加载配置文件: %s\n", path)
-			return nil
+			return path, nil
 		}
 		lastErr = err
 	}
 
-	return fmt.Errorf("未能找到或加载 .env 文件，尝试过以下路径：\n%s\n最后一个错误: %v",
+	return "", fmt.Errorf("未能找到或加载 .env 文件，尝试过以下路径：\n%s\n最后一个错误: %v",
 		strings.Join(envPaths, "\n"), lastErr)
 }
 
@@ -59,14 +76,12 @@ func CheckRequiredEnvVars() error {
 
 	// 检查上传相关的环境变量
 	if IsUploadEnabled() {
-		apiBaseURL := os.Getenv("API_BASE_URL")
-		if apiBaseURL == "" {
-			return fmt.Errorf("启用上传功能时，环境变量 API_BASE_URL 必须设置")
+		profiles, err := GetServerProfiles()
+		if err != nil {
+			return err
 		}
-
-		authToken := os.Getenv("AUTH_TOKEN")
-		if authToken == "" {
-			return fmt.Errorf("启用上传功能时，环境变量 AUTH_TOKEN 必须设置")
+		if len(profiles) == 0 {
+			return fmt.Errorf("启用上传功能时，环境变量 API_BASE_URL 或 MS_SERVERS 必须设置")
 		}
 	}
 	return nil
@@ -77,3 +92,104 @@ func IsUploadEnabled() bool {
 	upload := strings.ToLower(os.Getenv("UPLOAD_MS"))
 	return upload == "true"
 }
+
+// IsAutoRollbackEnabled 检查上传失败时是否自动回滚
+func IsAutoRollbackEnabled() bool {
+	rollback := strings.ToLower(os.Getenv("AUTO_ROLLBACK"))
+	return rollback == "true"
+}
+
+// GetConfigDir 获取本工具在用户配置目录下的目录，如 Linux 为 ~/.config/rename-by-tmdb
+func GetConfigDir() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("获取用户配置目录失败: %v", err)
+	}
+	return filepath.Join(configDir, "rename-by-tmdb"), nil
+}
+
+// GetAuditLogPath 获取审计日志路径，未设置 AUDIT_LOG 时使用用户配置目录下的 rename-by-tmdb/audit.jsonl
+func GetAuditLogPath() (string, error) {
+	if path := os.Getenv("AUDIT_LOG"); path != "" {
+		return path, nil
+	}
+	configDir, err := GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "audit.jsonl"), nil
+}
+
+// GetRecipeDir 获取配方目录，未设置 RECIPE_DIR 时使用用户配置目录下的 rename-by-tmdb/recipes
+func GetRecipeDir() (string, error) {
+	if dir := os.Getenv("RECIPE_DIR"); dir != "" {
+		return dir, nil
+	}
+	configDir, err := GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "recipes"), nil
+}
+
+// IsRecipeNoteEnabled 检查是否在规则备注中记录生成规则的配方名称
+func IsRecipeNoteEnabled() bool {
+	return strings.ToLower(os.Getenv("RECIPE_IN_NOTE")) == "true"
+}
+
+// GetTokenCachePath 获取登录令牌的保存路径（用户配置目录下的 rename-by-tmdb/tokens.json）
+func GetTokenCachePath() (string, error) {
+	configDir, err := GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "tokens.json"), nil
+}
+
+// GetTMDBLanguage 获取TMDB请求使用的语言，未设置 TMDB_LANGUAGE 时为 zh-CN
+func GetTMDBLanguage() string {
+	if language := os.Getenv("TMDB_LANGUAGE"); language != "" {
+		return language
+	}
+	return "zh-CN"
+}
+
+// GetPadZeroDefault 获取集数是否补0站位的默认选择，未设置 PAD_ZERO 时为补0
+func GetPadZeroDefault() bool {
+	padZero, err := strconv.ParseBool(os.Getenv("PAD_ZERO"))
+	if err != nil {
+		return true
+	}
+	return padZero
+}
+
+// GetCacheTTL 获取登录令牌缓存的有效期，未设置 CACHE_TTL 或格式无效时返回0（不过期）
+func GetCacheTTL() time.Duration {
+	ttl, err := time.ParseDuration(os.Getenv("CACHE_TTL"))
+	if err != nil || ttl < 0 {
+		return 0
+	}
+	return ttl
+}
+
+// ValidateNamingTemplate 检查名称模板，模板必须包含 {name} 或 {original}
+func ValidateNamingTemplate(template string) error {
+	if !strings.Contains(template, "{name}") && !strings.Contains(template, "{original}") {
+		return fmt.Errorf("名称模板必须包含 {name} 或 {original}")
+	}
+	return nil
+}
+
+// FormatName 按 NAMING_TEMPLATE 生成命名格式中的名称部分，空格替换为点号；未设置模板时使用TMDB名称
+// original 为空时 {original} 使用TMDB名称
+func FormatName(name, original string) string {
+	template := os.Getenv("NAMING_TEMPLATE")
+	if ValidateNamingTemplate(template) != nil {
+		template = "{name}"
+	}
+	if original == "" {
+		original = name
+	}
+	formatted := strings.NewReplacer("{name}", name, "{original}", original).Replace(template)
+	return strings.ReplaceAll(formatted, " ", ".")
+}
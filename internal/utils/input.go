@@ -1,77 +1,85 @@
 package utils
 
 import (
-	"bufio"
 	"fmt"
-	"os"
+	"sort"
 	"strconv"
 	"strings"
 )
 
-// GetUserInput 从用户获取输入
+// GetUserInput 从用户获取输入，输入为空时重新提问
 func GetUserInput(prompt string) (string, error) {
-	fmt.Print(prompt)
-	reader := bufio.NewReader(os.Stdin)
-	input, err := reader.ReadString('\n')
-	if err != nil {
-		return "", fmt.Errorf("读取输入失败: %v", err)
-	}
-	return strings.TrimSpace(input), nil
+	return Ask(prompter, Question[string]{Prompt: prompt, Parse: ParseText, Validate: NonEmpty})
+}
+
+// GetPassword 从用户获取密码，在终端中输入时不回显
+func GetPassword(prompt string) (string, error) {
+	return prompter.ReadPassword(prompt)
+}
+
+// GetConfirmation 从用户获取确认（直接回车默认为否）
+func GetConfirmation(prompt string) (bool, error) {
+	return Ask(prompter, Question[bool]{Prompt: prompt, Default: "n", Parse: ParseYesNo})
+}
+
+// GetDateModeChoice 从用户获取是否以日期判断集数的选择（直接回车默认为n）
+func GetDateModeChoice() (bool, error) {
+	return askYesNo(AnswerDateMode, "是否以日期判断集数？(y/N，直接回车默认为N): ", false)
 }
 
 // GetHasSeasonChoice 从用户获取是否包含季数的选择（直接回车默认为包含）
 func GetHasSeasonChoice() (bool, error) {
-	input, err := GetUserInput("是否使用原文件名季数？(y/n，直接回车默认为y): ")
-	if err != nil {
-		return false, err
-	}
-
-	input = strings.ToLower(strings.TrimSpace(input))
-	if input == "" || input == "y" || input == "yes" {
-		return true, nil
-	}
-	return false, nil
+	return askYesNo(AnswerHasSeason, "是否使用原文件名季数？(y/n，直接回车默认为y): ", true)
 }
 
 // GetEpisodeOffset 从用户获取集数偏移量（直接回车默认为0）
 func GetEpisodeOffset() (int, error) {
-	input, err := GetUserInput("请输入集数偏移量（如：+1、-1，直接回车表示不偏移）: ")
-	if err != nil {
-		return 0, err
-	}
-
-	input = strings.TrimSpace(input)
-	if input == "" {
-		return 0, nil
-	}
-
-	// 尝试解析偏移量，支持 + 和 - 号
-	offset, err := strconv.Atoi(input)
-	if err != nil {
-		return 0, fmt.Errorf("无效的偏移量: %v", err)
-	}
-
-	return offset, nil
+	return Ask(prompter, Question[int]{
+		Key:     AnswerOffset,
+		Prompt:  "请输入集数偏移量（如：+1、-1，直接回车表示不偏移）: ",
+		Default: "0",
+		Parse:   IntParser("偏移量"), // 支持 + 和 - 号
+		Format: func(offset int) string {
+			if offset == 0 {
+				return "0"
+			}
+			return fmt.Sprintf("%+d", offset)
+		},
+	})
 }
 
-// GetSpecificSeasons 从用户获取指定的季数
+// GetSpecificSeasons 从用户获取指定的季数，第二个返回值为 true 时表示生成所有季
 func GetSpecificSeasons() ([]int, bool, error) {
-	input, err := GetUserInput("请输入要生成的季数（多季用;分隔，直接回车生成所有季，0表示特别篇）: ")
+	seasons, err := Ask(prompter, Question[[]int]{
+		Key:     AnswerSeasons,
+		Prompt:  "请输入要生成的季数（多季用;分隔，直接回车生成所有季，0表示特别篇）: ",
+		Default: "all",
+		Parse:   parseSeasons,
+		Format: func(seasons []int) string {
+			if seasons == nil {
+				return "all"
+			}
+			var seasonStrings []string
+			for _, season := range seasons {
+				seasonStrings = append(seasonStrings, strconv.Itoa(season))
+			}
+			return strings.Join(seasonStrings, ";")
+		},
+	})
 	if err != nil {
 		return nil, false, err
 	}
+	return seasons, seasons == nil, nil
+}
 
-	input = strings.TrimSpace(input)
-	if input == "" {
-		return nil, true, nil // 返回 nil, true 表示生成所有季
+// parseSeasons 解析以;分隔的季数，all 或没有有效的季数时返回 nil 表示生成所有季
+func parseSeasons(input string) ([]int, error) {
+	if strings.EqualFold(input, "all") {
+		return nil, nil
 	}
 
-	// 分割输入的季数
-	seasonStrs := strings.Split(input, ";")
 	var seasons []int
-
-	// 解析每个季数
-	for _, s := range seasonStrs {
+	for _, s := range strings.Split(input, ";") {
 		s = strings.TrimSpace(s)
 		if s == "" {
 			continue
@@ -79,91 +87,60 @@ func GetSpecificSeasons() ([]int, bool, error) {
 
 		season, err := strconv.Atoi(s)
 		if err != nil {
-			return nil, false, fmt.Errorf("无效的季数 '%s': %v", s, err)
+			return nil, fmt.Errorf("无效的季数 '%s'，应为整数", s)
 		}
 		if season < 0 {
-			return nil, false, fmt.Errorf("季数不能为负数: %d", season)
+			return nil, fmt.Errorf("季数不能为负数: %d", season)
 		}
 		seasons = append(seasons, season)
 	}
-
-	if len(seasons) == 0 {
-		return nil, true, nil // 如果没有有效的季数，则生成所有季
-	}
-
-	return seasons, false, nil
+	return seasons, nil
 }
 
 // GetIncludeSpecialSeason 从用户获取是否包含第0季（特别篇）的选择（直接回车默认为n）
 func GetIncludeSpecialSeason() (bool, error) {
-	input, err := GetUserInput("是否包含第0季（特别篇）？(y/n，直接回车默认为n): ")
-	if err != nil {
-		return false, err
-	}
-
-	input = strings.ToLower(strings.TrimSpace(input))
-	return input == "y" || input == "yes", nil
+	return askYesNo(AnswerIncludeSpecials, "是否包含第0季（特别篇）？(y/n，直接回车默认为n): ", false)
 }
 
-// GetPadZeroChoice 从用户获取是否需要补0站位的选择（直接回车默认为y）
+// GetPadZeroChoice 从用户获取是否需要补0站位的选择（直接回车使用 PAD_ZERO 配置的默认值，未配置时为y）
 func GetPadZeroChoice() (bool, error) {
-	input, err := GetUserInput("集数是否补0站位？(y/n，直接回车默认为y): ")
-	if err != nil {
-		return false, err
-	}
-
-	input = strings.ToLower(strings.TrimSpace(input))
-	if input == "" || input == "y" || input == "yes" {
-		return true, nil
-	}
-	return false, nil
+	defaultPadZero := GetPadZeroDefault()
+	return askYesNo(AnswerPadZero, fmt.Sprintf("集数是否补0站位？(y/n，直接回车默认为%s): ", formatYesNo(defaultPadZero)), defaultPadZero)
 }
 
 // GetEpisodeContinuousChoice 从用户获取集数是否连续的选择（直接回车默认为y）
 func GetEpisodeContinuousChoice() (bool, error) {
-	input, err := GetUserInput("集数是否连续？(y/n，直接回车默认为y): ")
-	if err != nil {
-		return false, err
-	}
-
-	input = strings.ToLower(strings.TrimSpace(input))
-	if input == "" || input == "y" || input == "yes" {
-		return true, nil
-	}
-	return false, nil
+	return askYesNo(AnswerContinuous, "集数是否连续？(y/n，直接回车默认为y): ", true)
 }
 
 // GetPartEpisodeChoice 从用户获取是否有part剧集的选择（直接回车默认为n）
 func GetPartEpisodeChoice() (bool, error) {
-	input, err := GetUserInput("是否有part剧集（y/n，直接回车默认为n）: ")
-	if err != nil {
-		return false, err
-	}
-
-	input = strings.ToLower(strings.TrimSpace(input))
-	return input == "y" || input == "yes", nil
+	return askYesNo(AnswerPartEpisodes, "是否有part剧集（y/n，直接回车默认为n）: ", false)
 }
 
 // GetPartEpisodeInfo 从用户获取part剧集信息
 func GetPartEpisodeInfo() (map[int][]int, error) {
-	input, err := GetUserInput("请输入有part的集数和part数（格式为：集数:part数，多集之间以;间隔）例如：2:2;5:2，代表第二集和第五集都有part1和part2: ")
-	if err != nil {
-		return nil, err
-	}
-
-	// 清理输入，移除所有不可见字符和BOM
-	input = strings.TrimSpace(input)
-
-	// 添加调试信息，显示输入的原始字符
-	fmt.Printf("调试信息 - 输入长度: %d, 字符码点: ", len(input))
-	for i, r := range input {
-		if i > 0 {
-			fmt.Printf(", ")
-		}
-		fmt.Printf("'%c'(%d)", r, r)
-	}
-	fmt.Println()
+	return Ask(prompter, Question[map[int][]int]{
+		Key:    AnswerPartInfo,
+		Prompt: "请输入有part的集数和part数（格式为：集数:part数，多集之间以;间隔）例如：2:2;5:2，代表第二集和第五集都有part1和part2: ",
+		Parse:  parsePartEpisodeInfo,
+		Format: func(partInfo map[int][]int) string {
+			var episodes []int
+			for episodeNum := range partInfo {
+				episodes = append(episodes, episodeNum)
+			}
+			sort.Ints(episodes)
+			var partStrings []string
+			for _, episodeNum := range episodes {
+				partStrings = append(partStrings, fmt.Sprintf("%d:%d", episodeNum, len(partInfo[episodeNum])))
+			}
+			return strings.Join(partStrings, ";")
+		},
+	})
+}
 
+// parsePartEpisodeInfo 解析 集数:part数 格式的part剧集信息，多集之间以;间隔
+func parsePartEpisodeInfo(input string) (map[int][]int, error) {
 	// 移除可能的BOM和其他不可见字符，只保留数字、冒号、分号和空格
 	input = strings.Map(func(r rune) rune {
 		// 只保留数字、冒号、分号、空格和换行符
@@ -197,25 +174,21 @@ func GetPartEpisodeInfo() (map[int][]int, error) {
 			return nil, fmt.Errorf("无效的格式 '%s'，应为 '集数:part数'", episodeStr)
 		}
 
-		// 添加调试信息
-		fmt.Printf("调试信息 - 分割结果: parts[0]='%s'(长度:%d), parts[1]='%s'(长度:%d)\n",
-			parts[0], len(parts[0]), parts[1], len(parts[1]))
-
-		// 解析集数，添加调试信息
+		// 解析集数
 		episodeStrClean := strings.TrimSpace(parts[0])
 		episodeNum, err := strconv.Atoi(episodeStrClean)
 		if err != nil {
-			return nil, fmt.Errorf("无效的集数 '%s' (长度:%d): %v", episodeStrClean, len(episodeStrClean), err)
+			return nil, fmt.Errorf("无效的集数 '%s' (长度:%d)", episodeStrClean, len(episodeStrClean))
 		}
 		if episodeNum <= 0 {
 			return nil, fmt.Errorf("集数必须大于0: %d", episodeNum)
 		}
 
-		// 解析part数，添加调试信息
+		// 解析part数
 		partStrClean := strings.TrimSpace(parts[1])
 		partCount, err := strconv.Atoi(partStrClean)
 		if err != nil {
-			return nil, fmt.Errorf("无效的part数 '%s' (长度:%d): %v", partStrClean, len(partStrClean), err)
+			return nil, fmt.Errorf("无效的part数 '%s' (长度:%d)", partStrClean, len(partStrClean))
 		}
 		if partCount <= 0 {
 			return nil, fmt.Errorf("part数必须大于0: %d", partCount)
@@ -233,6 +206,256 @@ func GetPartEpisodeInfo() (map[int][]int, error) {
 	if len(partInfo) == 0 {
 		return nil, fmt.Errorf("没有有效的part剧集信息")
 	}
-
 	return partInfo, nil
 }
+
+// GetDateFormats 从用户获取文件名中的日期格式（直接回车默认为YYYYMMDD）
+func GetDateFormats() ([]DateFormat, error) {
+	options := []string{"可选的日期格式："}
+	for i, format := range DateFormats {
+		options = append(options, fmt.Sprintf("%d. %s", i+1, format.Name))
+	}
+	return Ask(prompter, Question[[]DateFormat]{
+		Key:     AnswerDateFormats,
+		Prompt:  "请选择文件名中的日期格式（多个用;分隔，直接回车默认为YYYYMMDD）: ",
+		Options: options,
+		Parse:   ParseDateFormats,
+		Format: func(formats []DateFormat) string {
+			var names []string
+			for _, format := range formats {
+				names = append(names, format.Name)
+			}
+			return strings.Join(names, ";")
+		},
+	})
+}
+
+// GetDateTolerance 从用户获取播出日期的容差天数（直接回车默认为0）
+func GetDateTolerance() (int, error) {
+	return Ask(prompter, Question[int]{
+		Key:      AnswerDateTolerance,
+		Prompt:   "请输入播出日期容差天数（如：1 表示前后各1天，直接回车表示不容差）: ",
+		Default:  "0",
+		Parse:    IntParser("容差天数"),
+		Validate: NonNegative("容差天数"),
+		Format:   strconv.Itoa,
+	})
+}
+
+// SameDateStrategy 表示同一播出日期有多集时的区分方式
+type SameDateStrategy int
+
+const (
+	// SameDateMerge 合并为多集替换（如 S01E01-E02）
+	SameDateMerge SameDateStrategy = iota
+	// SameDateByPart 按文件名中的part标记区分（part1对应第一集，依此类推）
+	SameDateByPart
+	// SameDateByEpisode 按文件名中的集数提示区分
+	SameDateByEpisode
+)
+
+// GetSameDateStrategy 从用户获取某一季同日多集的区分方式（直接回车默认为合并），各季的回答分别记录
+func GetSameDateStrategy(season int) (SameDateStrategy, error) {
+	return Ask(prompter, Question[SameDateStrategy]{
+		Key:    ScopedAnswerKey(AnswerSameDate, fmt.Sprintf("S%d", season)),
+		Prompt: "请输入选项（直接回车默认为1）: ",
+		Options: []string{
+			"同一播出日期有多集，请选择区分方式：",
+			"1. 合并为多集替换（如：S01E01-E02，集数不连续时按集数区分）",
+			"2. 按part标记区分（part1对应第一集，part2对应第二集）",
+			"3. 按文件名中的集数区分（如：E01、第1集）",
+		},
+		Default: "1",
+		Parse: ChoiceParser(map[string]SameDateStrategy{
+			"1": SameDateMerge,
+			"2": SameDateByPart,
+			"3": SameDateByEpisode,
+		}),
+	})
+}
+
+// SeasonOverlapFix 表示不同季的规则集数区间重叠时的处理方式
+type SeasonOverlapFix int
+
+const (
+	// SeasonOverlapKeep 保持不变
+	SeasonOverlapKeep SeasonOverlapFix = iota
+	// SeasonOverlapRequireSeason 被替换词要求文件名包含季数标记（如 S02E05）
+	SeasonOverlapRequireSeason
+	// SeasonOverlapAbsolute 原文件使用绝对集数，第2季接着第1季的最后一集编号
+	SeasonOverlapAbsolute
+)
+
+// GetSeasonOverlapFix 从用户获取不同季规则重叠时的处理方式（直接回车默认为保持不变）
+func GetSeasonOverlapFix() (SeasonOverlapFix, error) {
+	return Ask(prompter, Question[SeasonOverlapFix]{
+		Key:    AnswerSeasonOverlap,
+		Prompt: "请输入选项（直接回车默认为1）: ",
+		Options: []string{
+			"请选择处理方式：",
+			"1. 保持不变",
+			"2. 要求季数标记（文件名必须包含 S01、S02 等季数，如：S02E05）",
+			"3. 改为绝对集数（原文件集数跨季连续编号，如第1季12集时第2季第1集为第13集）",
+		},
+		Default: "1",
+		Parse: ChoiceParser(map[string]SeasonOverlapFix{
+			"1": SeasonOverlapKeep,
+			"2": SeasonOverlapRequireSeason,
+			"3": SeasonOverlapAbsolute,
+		}),
+	})
+}
+
+// EpisodeRange 表示一个多集文件包含的集数区间
+type EpisodeRange struct {
+	Start int
+	End   int
+}
+
+// GetMultiEpisodeChoice 从用户获取是否为多集文件的选择（直接回车默认为n）
+func GetMultiEpisodeChoice() (bool, error) {
+	return askYesNo(AnswerMultiEpisode, "是否为多集文件（如：E01-E02、E01E02、第1-2集）？(y/n，直接回车默认为n): ", false)
+}
+
+// GetMultiEpisodeRanges 从用户获取多集文件的集数区间（原文件集数），直接回车返回nil表示按每2集自动分组
+func GetMultiEpisodeRanges() ([]EpisodeRange, error) {
+	return Ask(prompter, Question[[]EpisodeRange]{
+		Key:     AnswerMultiRanges,
+		Prompt:  "请输入多集文件的集数区间（原文件集数，多个用;分隔，如：1-2;3-4，直接回车按每2集自动分组）: ",
+		Default: "auto",
+		Parse:   parseEpisodeRanges,
+		Format: func(ranges []EpisodeRange) string {
+			if ranges == nil {
+				return "auto"
+			}
+			var rangeStrings []string
+			for _, episodeRange := range ranges {
+				rangeStrings = append(rangeStrings, fmt.Sprintf("%d-%d", episodeRange.Start, episodeRange.End))
+			}
+			return strings.Join(rangeStrings, ";")
+		},
+	})
+}
+
+// parseEpisodeRanges 解析以;分隔的集数区间，auto 时返回nil表示按每2集自动分组
+func parseEpisodeRanges(input string) ([]EpisodeRange, error) {
+	if strings.EqualFold(input, "auto") {
+		return nil, nil
+	}
+
+	var ranges []EpisodeRange
+	for _, rangeStr := range strings.Split(input, ";") {
+		rangeStr = strings.TrimSpace(rangeStr)
+		if rangeStr == "" {
+			continue
+		}
+
+		bounds := strings.Split(rangeStr, "-")
+		if len(bounds) != 2 {
+			return nil, fmt.Errorf("无效的集数区间 '%s'，应为 '起始集数-结束集数'", rangeStr)
+		}
+
+		start, err := strconv.Atoi(strings.TrimSpace(bounds[0]))
+		if err != nil {
+			return nil, fmt.Errorf("无效的起始集数 '%s'", bounds[0])
+		}
+		end, err := strconv.Atoi(strings.TrimSpace(bounds[1]))
+		if err != nil {
+			return nil, fmt.Errorf("无效的结束集数 '%s'", bounds[1])
+		}
+		if start <= 0 || end <= start {
+			return nil, fmt.Errorf("无效的集数区间 '%s'，起始集数必须大于0且小于结束集数", rangeStr)
+		}
+
+		ranges = append(ranges, EpisodeRange{Start: start, End: end})
+	}
+	return ranges, nil
+}
+
+// GetSpecialsChoice 从用户获取是否使用特别篇映射模式的选择（直接回车默认为n）
+func GetSpecialsChoice() (bool, error) {
+	return askYesNo(AnswerSpecials, "是否为特别篇映射模式（文件名使用 SP01、OVA、Special、番外、12.5 等标记）？(y/n，直接回车默认为n): ", false)
+}
+
+// GetSpecialMappingChange 从用户获取对特别篇映射的修改，直接回车返回空字符串表示确认
+func GetSpecialMappingChange() (string, error) {
+	return Ask(prompter, Question[string]{
+		Key:    AnswerSpecialMapping,
+		Prompt: "\n确认使用以上映射？(直接回车确认，或输入修改，格式为 标记=集数，如：OVA1=3;12.5=0，集数为0表示删除): ",
+		Parse:  ParseText,
+	})
+}
+
+// RecordSpecialMappingChanges 记录本次对特别篇映射的全部修改，重放时一次应用
+func RecordSpecialMappingChanges(changes []string) {
+	recordAnswer(AnswerSpecialMapping, strings.Join(changes, ";"))
+}
+
+// GetEpisodeTitleChoice 从用户获取是否使用集名匹配模式的选择（直接回车默认为n）
+func GetEpisodeTitleChoice() (bool, error) {
+	return askYesNo(AnswerEpisodeTitles, "是否按集名匹配（文件名中只有集名，没有集数）？(y/n，直接回车默认为n): ", false)
+}
+
+// GetTitleLanguages 从用户获取需要额外匹配的集名语言（直接回车表示只使用中文集名）
+func GetTitleLanguages() ([]string, error) {
+	return Ask(prompter, Question[[]string]{
+		Key:    AnswerTitleLanguages,
+		Prompt: "请输入需要额外匹配的集名语言（多个用;分隔，如：en-US;ja-JP，直接回车只使用中文集名）: ",
+		Parse: func(input string) ([]string, error) {
+			var languages []string
+			for _, language := range strings.Split(input, ";") {
+				language = strings.TrimSpace(language)
+				if language != "" && language != "zh-CN" && !strings.EqualFold(language, "none") {
+					languages = append(languages, language)
+				}
+			}
+			return languages, nil
+		},
+		Format: func(languages []string) string {
+			if len(languages) == 0 {
+				return "none"
+			}
+			return strings.Join(languages, ";")
+		},
+	})
+}
+
+// GetSyncChoice 从用户获取词组已存在时是否同步规则的选择（直接回车默认为y）
+// 同步会新增缺失的规则、更新变化的规则并清理过期的规则；选择n则直接追加所有规则。各服务器的回答分别记录
+func GetSyncChoice(server string) (bool, error) {
+	return askYesNo(ScopedAnswerKey(AnswerSync, server), "词组已存在，是否同步规则（新增缺失、更新变化、清理过期，选n则直接追加）？(y/n，直接回车默认为y): ", true)
+}
+
+// GetDeleteObsoleteChoice 从用户获取过期规则是否删除的选择（直接回车默认为n，即只禁用），各服务器的回答分别记录
+func GetDeleteObsoleteChoice(server string) (bool, error) {
+	return askYesNo(ScopedAnswerKey(AnswerDeleteObsolete, server), "是否删除过期规则（选n则只禁用）？(y/n，直接回车默认为n): ", false)
+}
+
+// GetRollbackChoice 从用户获取上传失败时是否回滚的选择（直接回车默认为y），各服务器的回答分别记录
+func GetRollbackChoice(server string) (bool, error) {
+	return askYesNo(ScopedAnswerKey(AnswerRollback, server), "是否回滚本次已创建的规则和词组？(y/n，直接回车默认为y): ", true)
+}
+
+// GetImportStrategy 从用户获取导入时词组已存在的处理方式，返回 skip、merge 或 replace（直接回车默认为skip）
+func GetImportStrategy() (string, error) {
+	return Ask(prompter, Question[string]{
+		Prompt: "请输入选项（直接回车默认为1）: ",
+		Options: []string{
+			"导入的词组在服务器上已存在时，请选择处理方式：",
+			"1. 跳过（保留服务器上的词组不变）",
+			"2. 合并（新增缺失的规则、更新变化的规则，保留其他规则）",
+			"3. 替换（使词组的规则与导出文件完全一致，删除多余的规则）",
+		},
+		Default: "1",
+		Parse: ChoiceParser(map[string]string{
+			"1": "skip",
+			"2": "merge",
+			"3": "replace",
+		}),
+	})
+}
+
+// GetBlockWordsChoice 从用户获取是否同时生成屏蔽词规则的选择（直接回车默认为n）
+func GetBlockWordsChoice() (bool, error) {
+	return askYesNo(AnswerBlockWords, "是否同时生成屏蔽词规则，删除文件名中的网址广告标记？(y/n，直接回车默认为n): ", false)
+}
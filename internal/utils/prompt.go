@@ -0,0 +1,189 @@
+package utils
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// Prompter 向 writer 输出提示并从 reader 读取输入，可替换为其他输入输出（如字符串和缓冲区）以便测试提问
+type Prompter struct {
+	input  io.Reader
+	reader *bufio.Reader
+	writer io.Writer
+}
+
+// NewPrompter 创建从 reader 读取输入、向 writer 输出提示的 Prompter
+func NewPrompter(reader io.Reader, writer io.Writer) *Prompter {
+	return &Prompter{input: reader, reader: bufio.NewReader(reader), writer: writer}
+}
+
+// stdio 读写时才取 os.Stdin 和 os.Stdout，使用替换后的标准输入输出
+type stdio struct{}
+
+func (stdio) Read(p []byte) (int, error)  { return os.Stdin.Read(p) }
+func (stdio) Write(p []byte) (int, error) { return os.Stdout.Write(p) }
+
+// prompter 各 Get* 函数使用的 Prompter，默认使用标准输入输出
+var prompter = NewPrompter(stdio{}, stdio{})
+
+// SetPrompter 替换各 Get* 函数使用的 Prompter，返回原来的 Prompter 以便恢复
+func SetPrompter(p *Prompter) *Prompter {
+	previous := prompter
+	prompter = p
+	return previous
+}
+
+// Printf 向 Prompter 的输出写入格式化文本
+func (p *Prompter) Printf(format string, args ...any) {
+	fmt.Fprintf(p.writer, format, args...)
+}
+
+// ReadLine 输出提示并读取一行，返回去除首尾空白的输入；最后一行没有换行符时也会返回
+func (p *Prompter) ReadLine(prompt string) (string, error) {
+	fmt.Fprint(p.writer, prompt)
+	input, err := p.reader.ReadString('\n')
+	if err != nil && (err != io.EOF || input == "") {
+		return "", fmt.Errorf("读取输入失败: %v", err)
+	}
+	return strings.TrimSpace(input), nil
+}
+
+// ReadPassword 输出提示并读取密码，使用标准输入且为终端时不回显
+func (p *Prompter) ReadPassword(prompt string) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if _, ok := p.input.(stdio); !ok || !term.IsTerminal(fd) {
+		return p.ReadLine(prompt)
+	}
+
+	fmt.Fprint(p.writer, prompt)
+	password, err := term.ReadPassword(fd)
+	fmt.Fprintln(p.writer)
+	if err != nil {
+		return "", fmt.Errorf("读取输入失败: %v", err)
+	}
+	return strings.TrimSpace(string(password)), nil
+}
+
+// Question 描述一个问题：如何解析和检查输入、直接回车时使用的输入，以及如何记录回答
+type Question[T any] struct {
+	Key      string                        // 问题标识，用于预设、配方重放和记录回答，为空时不使用
+	Prompt   string                        // 提示
+	Options  []string                      // 提问前显示的说明和选项，每项一行
+	Default  string                        // 直接回车且没有预设时使用的输入
+	Parse    func(input string) (T, error) // 将输入转换为值
+	Validate func(value T) error           // 可选，检查转换后的值
+	Format   func(value T) string          // 可选，将值转换为记录的回答，为空时记录输入
+}
+
+// Ask 提问直到输入有效，输入无效时显示原因并重新提问；读取输入失败时返回错误
+// 重放配方时回答取自配方，无效时直接返回错误
+func Ask[T any](p *Prompter, q Question[T]) (T, error) {
+	var zero T
+	for _, option := range q.Options {
+		p.Printf("%s\n", option)
+	}
+
+	for {
+		input, err := p.answer(q.Key, q.Prompt)
+		if err != nil {
+			return zero, err
+		}
+		if input == "" {
+			input = q.Default
+		}
+
+		value, err := q.Parse(input)
+		if err == nil && q.Validate != nil {
+			err = q.Validate(value)
+		}
+		if err == nil {
+			if q.Key != "" {
+				answer := input
+				if q.Format != nil {
+					answer = q.Format(value)
+				}
+				recordAnswer(q.Key, answer)
+			}
+			return value, nil
+		}
+
+		if replaying && q.Key != "" {
+			return zero, err
+		}
+		p.Printf("%v，请重新输入\n", err)
+	}
+}
+
+// ParseText 原样返回输入
+func ParseText(input string) (string, error) {
+	return input, nil
+}
+
+// ParseYesNo 解析 y/yes/n/no（不区分大小写）
+func ParseYesNo(input string) (bool, error) {
+	switch strings.ToLower(input) {
+	case "y", "yes":
+		return true, nil
+	case "n", "no":
+		return false, nil
+	}
+	return false, fmt.Errorf("无效的输入 '%s'，请输入 y 或 n", input)
+}
+
+// IntParser 返回解析整数的函数，name 为错误提示中数值的名称
+func IntParser(name string) func(input string) (int, error) {
+	return func(input string) (int, error) {
+		value, err := strconv.Atoi(input)
+		if err != nil {
+			return 0, fmt.Errorf("无效的%s '%s'，应为整数", name, input)
+		}
+		return value, nil
+	}
+}
+
+// NonNegative 返回检查整数不小于0的函数，name 为错误提示中数值的名称
+func NonNegative(name string) func(value int) error {
+	return func(value int) error {
+		if value < 0 {
+			return fmt.Errorf("%s不能为负数: %d", name, value)
+		}
+		return nil
+	}
+}
+
+// NonEmpty 检查输入不为空
+func NonEmpty(value string) error {
+	if value == "" {
+		return fmt.Errorf("输入不能为空")
+	}
+	return nil
+}
+
+// ChoiceParser 返回按选项解析输入的函数，输入必须是 choices 中的键
+func ChoiceParser[T any](choices map[string]T) func(input string) (T, error) {
+	return func(input string) (T, error) {
+		value, exists := choices[input]
+		if !exists {
+			var zero T
+			return zero, fmt.Errorf("无效的选项: %s", input)
+		}
+		return value, nil
+	}
+}
+
+// askYesNo 提问是/否，直接回车且没有预设时返回 defaultValue，回答按 y/n 记录
+func askYesNo(key, prompt string, defaultValue bool) (bool, error) {
+	return Ask(prompter, Question[bool]{
+		Key:     key,
+		Prompt:  prompt,
+		Default: formatYesNo(defaultValue),
+		Parse:   ParseYesNo,
+		Format:  formatYesNo,
+	})
+}
@@ -0,0 +1,329 @@
+package utils
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// usePrompter 让各 Get* 函数从 input 读取输入并把输出写入返回的缓冲区，测试结束后恢复 Prompter 和回答状态
+func usePrompter(t *testing.T, input string) *bytes.Buffer {
+	t.Helper()
+	var output bytes.Buffer
+	previous := SetPrompter(NewPrompter(strings.NewReader(input), &output))
+	t.Cleanup(func() {
+		SetPrompter(previous)
+		SetPresetAnswers(nil)
+		StopReplay()
+		recordedAnswers = make(map[string]string)
+	})
+	return &output
+}
+
+func TestAsk(t *testing.T) {
+	question := Question[int]{
+		Key:      AnswerOffset,
+		Prompt:   "数量: ",
+		Default:  "5",
+		Parse:    IntParser("数量"),
+		Validate: NonNegative("数量"),
+	}
+
+	tests := []struct {
+		name     string
+		input    string
+		want     int
+		wantErr  bool
+		reprompt []string // 输出中应包含的重新输入提示
+		recorded string
+	}{
+		{name: "有效输入", input: "3\n", want: 3, recorded: "3"},
+		{name: "直接回车使用默认值", input: "\n", want: 5, recorded: "5"},
+		{name: "最后一行没有换行符", input: "7", want: 7, recorded: "7"},
+		{
+			name:     "无效输入后重新提问",
+			input:    "x\n-1\n3\n",
+			want:     3,
+			reprompt: []string{"无效的数量 'x'，应为整数，请重新输入", "数量不能为负数: -1，请重新输入"},
+			recorded: "3",
+		},
+		{name: "没有输入", input: "", wantErr: true},
+		{name: "无效输入后没有输入", input: "x\n", wantErr: true, reprompt: []string{"无效的数量 'x'，应为整数，请重新输入"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output := usePrompter(t, tt.input)
+			got, err := Ask(prompter, question)
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "读取输入失败") {
+					t.Fatalf("Ask() error = %v, 期望读取输入失败", err)
+				}
+			} else if err != nil || got != tt.want {
+				t.Fatalf("Ask() = %d, %v, 期望 %d", got, err, tt.want)
+			}
+			for _, message := range tt.reprompt {
+				if !strings.Contains(output.String(), message) {
+					t.Errorf("输出中没有 %q:\n%s", message, output.String())
+				}
+			}
+			if recorded := RecordedAnswers()[AnswerOffset]; recorded != tt.recorded {
+				t.Errorf("记录的回答 = %q, 期望 %q", recorded, tt.recorded)
+			}
+		})
+	}
+}
+
+func TestAskPrintsOptionsOnce(t *testing.T) {
+	output := usePrompter(t, "9\n1\n")
+	got, err := Ask(prompter, Question[string]{
+		Prompt:  "选项: ",
+		Options: []string{"请选择：", "1. 一"},
+		Parse:   ChoiceParser(map[string]string{"1": "一"}),
+	})
+	if err != nil || got != "一" {
+		t.Fatalf("Ask() = %q, %v", got, err)
+	}
+	if count := strings.Count(output.String(), "1. 一"); count != 1 {
+		t.Errorf("选项显示了 %d 次，期望 1 次:\n%s", count, output.String())
+	}
+	if !strings.Contains(output.String(), "无效的选项: 9，请重新输入") {
+		t.Errorf("输出中没有重新输入提示:\n%s", output.String())
+	}
+	if len(RecordedAnswers()) != 0 {
+		t.Errorf("没有标识的问题不应记录回答: %v", RecordedAnswers())
+	}
+}
+
+func TestGetUserInput(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		want     string
+		wantErr  bool
+		reprompt bool
+	}{
+		{name: "有效输入", input: "admin\n", want: "admin"},
+		{name: "去除首尾空白", input: "  admin \n", want: "admin"},
+		{name: "空输入后重新提问", input: "\n \nadmin\n", want: "admin", reprompt: true},
+		{name: "没有输入", input: "", wantErr: true},
+		{name: "只有空输入", input: "\n", wantErr: true, reprompt: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output := usePrompter(t, tt.input)
+			got, err := GetUserInput("用户名: ")
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("GetUserInput() = %q, 期望错误", got)
+				}
+			} else if err != nil || got != tt.want {
+				t.Fatalf("GetUserInput() = %q, %v, 期望 %q", got, err, tt.want)
+			}
+			if reprompted := strings.Contains(output.String(), "输入不能为空，请重新输入"); reprompted != tt.reprompt {
+				t.Errorf("重新提问 = %v, 期望 %v:\n%s", reprompted, tt.reprompt, output.String())
+			}
+		})
+	}
+}
+
+func TestGetEpisodeOffset(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		want     int
+		recorded string
+	}{
+		{name: "正偏移", input: "+2\n", want: 2, recorded: "+2"},
+		{name: "负偏移", input: "-1\n", want: -1, recorded: "-1"},
+		{name: "直接回车不偏移", input: "\n", want: 0, recorded: "0"},
+		{name: "无效输入后重新提问", input: "x\n-1\n3\n", want: -1, recorded: "-1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			usePrompter(t, tt.input)
+			got, err := GetEpisodeOffset()
+			if err != nil || got != tt.want {
+				t.Fatalf("GetEpisodeOffset() = %d, %v, 期望 %d", got, err, tt.want)
+			}
+			if recorded := RecordedAnswers()[AnswerOffset]; recorded != tt.recorded {
+				t.Errorf("记录的回答 = %q, 期望 %q", recorded, tt.recorded)
+			}
+		})
+	}
+}
+
+func TestGetSpecificSeasons(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		want     []int
+		wantAll  bool
+		reprompt string
+		recorded string
+	}{
+		{name: "指定多季", input: "1;2\n", want: []int{1, 2}, recorded: "1;2"},
+		{name: "包含特别篇", input: "0; 3\n", want: []int{0, 3}, recorded: "0;3"},
+		{name: "直接回车生成所有季", input: "\n", wantAll: true, recorded: "all"},
+		{name: "all 不区分大小写", input: "ALL\n", wantAll: true, recorded: "all"},
+		{name: "无效的季数", input: "x\n3\n", want: []int{3}, reprompt: "无效的季数 'x'，应为整数，请重新输入", recorded: "3"},
+		{name: "负数季数", input: "-1\n3\n", want: []int{3}, reprompt: "季数不能为负数: -1，请重新输入", recorded: "3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output := usePrompter(t, tt.input)
+			got, all, err := GetSpecificSeasons()
+			if err != nil || all != tt.wantAll || !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("GetSpecificSeasons() = %v, %v, %v, 期望 %v, %v", got, all, err, tt.want, tt.wantAll)
+			}
+			if tt.reprompt != "" && !strings.Contains(output.String(), tt.reprompt) {
+				t.Errorf("输出中没有 %q:\n%s", tt.reprompt, output.String())
+			}
+			if recorded := RecordedAnswers()[AnswerSeasons]; recorded != tt.recorded {
+				t.Errorf("记录的回答 = %q, 期望 %q", recorded, tt.recorded)
+			}
+		})
+	}
+}
+
+func TestGetPartEpisodeInfo(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		want     map[int][]int
+		wantErr  bool
+		reprompt string
+		recorded string
+	}{
+		{name: "多集", input: "5:3;2:2\n", want: map[int][]int{2: {1, 2}, 5: {1, 2, 3}}, recorded: "2:2;5:3"},
+		{name: "去除不可见字符", input: "\ufeff2:2\n", want: map[int][]int{2: {1, 2}}, recorded: "2:2"},
+		{name: "格式错误后重新提问", input: "2\n2:2\n", want: map[int][]int{2: {1, 2}}, reprompt: "无效的格式 '2'，应为 '集数:part数'，请重新输入", recorded: "2:2"},
+		{name: "part数为0", input: "2:0\n2:2\n", want: map[int][]int{2: {1, 2}}, reprompt: "part数必须大于0: 0，请重新输入", recorded: "2:2"},
+		{name: "没有默认值", input: "\n", wantErr: true, reprompt: "输入不能为空，请重新输入"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output := usePrompter(t, tt.input)
+			got, err := GetPartEpisodeInfo()
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("GetPartEpisodeInfo() = %v, 期望错误", got)
+				}
+			} else if err != nil || !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("GetPartEpisodeInfo() = %v, %v, 期望 %v", got, err, tt.want)
+			}
+			if tt.reprompt != "" && !strings.Contains(output.String(), tt.reprompt) {
+				t.Errorf("输出中没有 %q:\n%s", tt.reprompt, output.String())
+			}
+			if strings.Contains(output.String(), "调试信息") {
+				t.Errorf("不应输出调试信息:\n%s", output.String())
+			}
+			if recorded := RecordedAnswers()[AnswerPartInfo]; recorded != tt.recorded {
+				t.Errorf("记录的回答 = %q, 期望 %q", recorded, tt.recorded)
+			}
+		})
+	}
+}
+
+func TestParseYesNo(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    bool
+		wantErr bool
+	}{
+		{input: "y", want: true},
+		{input: "YES", want: true},
+		{input: "n", want: false},
+		{input: "No", want: false},
+		{input: "", wantErr: true},
+		{input: "ye", wantErr: true},
+		{input: "是", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseYesNo(tt.input)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseYesNo(%q) = %v, %v, 期望 %v, 错误 %v", tt.input, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestPresetAnswers(t *testing.T) {
+	tests := []struct {
+		name   string
+		preset map[string]string
+		input  string
+		want   int
+		hint   string
+	}{
+		{name: "直接回车使用预设值", preset: map[string]string{AnswerOffset: "+1"}, input: "\n", want: 1, hint: "直接回车使用预设值 +1"},
+		{name: "输入覆盖预设值", preset: map[string]string{AnswerOffset: "+1"}, input: "-2\n", want: -2, hint: "直接回车使用预设值 +1"},
+		{name: "没有预设时使用默认值", preset: map[string]string{AnswerSeasons: "1"}, input: "\n", want: 0, hint: "直接回车表示不偏移"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output := usePrompter(t, tt.input)
+			SetPresetAnswers(tt.preset)
+			got, err := GetEpisodeOffset()
+			if err != nil || got != tt.want {
+				t.Fatalf("GetEpisodeOffset() = %d, %v, 期望 %d", got, err, tt.want)
+			}
+			if !strings.Contains(output.String(), tt.hint) {
+				t.Errorf("提示中没有 %q:\n%s", tt.hint, output.String())
+			}
+		})
+	}
+}
+
+func TestScopedPresetFallback(t *testing.T) {
+	usePrompter(t, "\n\n")
+	SetPresetAnswers(map[string]string{AnswerSameDate: "2", ScopedAnswerKey(AnswerSameDate, "S2"): "3"})
+
+	for season, want := range map[int]SameDateStrategy{1: SameDateByPart, 2: SameDateByEpisode} {
+		got, err := GetSameDateStrategy(season)
+		if err != nil || got != want {
+			t.Errorf("第%d季 GetSameDateStrategy() = %v, %v, 期望 %v", season, got, err, want)
+		}
+	}
+}
+
+func TestReplay(t *testing.T) {
+	tests := []struct {
+		name    string
+		answers map[string]string
+		want    int
+		wantErr string
+	}{
+		{name: "使用配方中的回答", answers: map[string]string{AnswerOffset: "+1"}, want: 1},
+		{name: "配方中没有时使用默认值", answers: map[string]string{}, want: 0},
+		{name: "无效的回答直接返回错误", answers: map[string]string{AnswerOffset: "x"}, wantErr: "无效的偏移量 'x'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 重放时不读取输入，输入中的内容不应被使用
+			output := usePrompter(t, "5\n")
+			SetReplayAnswers(tt.answers)
+			got, err := GetEpisodeOffset()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("GetEpisodeOffset() error = %v, 期望 %q", err, tt.wantErr)
+				}
+				if strings.Contains(output.String(), "请重新输入") {
+					t.Errorf("重放时不应重新提问:\n%s", output.String())
+				}
+			} else if err != nil || got != tt.want {
+				t.Fatalf("GetEpisodeOffset() = %d, %v, 期望 %d", got, err, tt.want)
+			}
+			if trace := ReplayTrace(); len(trace) != 1 || trace[0].Key != AnswerOffset {
+				t.Errorf("ReplayTrace() = %v", trace)
+			}
+		})
+	}
+}
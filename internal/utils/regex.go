@@ -2,7 +2,11 @@ package utils
 
 import (
 	"fmt"
+	"regexp"
 	"strings"
+	"time"
+
+	"github.com/dlclark/regexp2"
 )
 
 // GenerateRangePattern 生成指定范围的正则表达式模式
@@ -33,3 +37,74 @@ func GenerateRangePattern(start, end int, digits int) string {
 	}
 	return fmt.Sprintf("(%s)", strings.Join(patterns, "|"))
 }
+
+// GenerateMultiEpisodePattern 生成匹配多集区间的正则表达式模式，兼容 E01-E02、E01E02、第1-2集 等写法
+// 集数允许前导0，因此补0与不补0的文件名都能匹配
+func GenerateMultiEpisodePattern(start, end int) string {
+	return fmt.Sprintf("(?:^|[^0-9])(?:E|Ep|EP|[Ee]pisode|[Ee]p|第)?0*%d(?:[-~&+]|[-~&+]?(?:E|Ep|EP|[Ee]p))0*%d(?:集|话|話)?(?:[^0-9]|$)",
+		start, end)
+}
+
+// titleSeparatorRegexp 匹配标题中的非字母、非数字字符
+var titleSeparatorRegexp = regexp.MustCompile(`[^\p{L}\p{N}]+`)
+
+// genericEpisodeNameRegexp 匹配TMDB在缺少集名时使用的占位名称，如"第 5 集"、"Episode 5"
+var genericEpisodeNameRegexp = regexp.MustCompile(`(?i)^(?:第\s*\d+\s*[集话話]|episode\s*\d+|ep\s*\d+|\d+)$`)
+
+// NormalizeTitle 规范化标题：转为小写，并将标点、空格等分隔符统一为单个空格
+func NormalizeTitle(title string) string {
+	return strings.TrimSpace(titleSeparatorRegexp.ReplaceAllString(strings.ToLower(title), " "))
+}
+
+// IsGenericEpisodeName 判断集名是否为TMDB的占位名称（无法用于匹配）
+func IsGenericEpisodeName(name string) bool {
+	return genericEpisodeNameRegexp.MatchString(strings.TrimSpace(name))
+}
+
+// GenerateTitlePattern 生成匹配标题的正则表达式模式，忽略大小写，单词之间允许任意分隔符
+func GenerateTitlePattern(titles []string) string {
+	var patterns []string
+	seen := make(map[string]bool)
+	for _, title := range titles {
+		normalized := NormalizeTitle(title)
+		if normalized == "" || seen[normalized] {
+			continue
+		}
+		seen[normalized] = true
+
+		var words []string
+		for _, word := range strings.Fields(normalized) {
+			words = append(words, regexp.QuoteMeta(word))
+		}
+		patterns = append(patterns, strings.Join(words, `[^\p{L}\p{N}]*`))
+	}
+	return fmt.Sprintf("(?i:%s)", strings.Join(patterns, "|"))
+}
+
+// tmdbTokenRegexp 匹配命名格式中的 {[tmdbid=ID;type=类型]}
+var tmdbTokenRegexp = regexp.MustCompile(`tmdbid=(\d+);type=(\w+)`)
+
+// ParseTMDBToken 从词组标题或替换词中解析TMDB ID和类型
+func ParseTMDBToken(text string) (tmdbID, mediaType string, ok bool) {
+	matches := tmdbTokenRegexp.FindStringSubmatch(text)
+	if matches == nil {
+		return "", "", false
+	}
+	return matches[1], matches[2], true
+}
+
+// serverRegexTimeout 单次匹配的超时时间，避免回溯过多的正则卡住
+const serverRegexTimeout = time.Second
+
+// CompileServerRegex 按服务器的正则语法编译被替换词，支持前瞻等Go正则不支持的语法；regex为false时按原文匹配
+func CompileServerRegex(pattern string, regex bool) (*regexp2.Regexp, error) {
+	if !regex {
+		pattern = regexp2.Escape(pattern)
+	}
+	compiled, err := regexp2.Compile(pattern, regexp2.None)
+	if err != nil {
+		return nil, err
+	}
+	compiled.MatchTimeout = serverRegexTimeout
+	return compiled, nil
+}
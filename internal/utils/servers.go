@@ -0,0 +1,222 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// DefaultServerName 使用 API_BASE_URL/AUTH_TOKEN 配置的服务器名称
+const DefaultServerName = "default"
+
+// configServers 配置文件中的服务器，由 SetConfigServers 设置
+var configServers []ServerProfile
+
+// SetConfigServers 设置配置文件中的服务器，GetServerProfiles 用其补充环境变量中未设置的值
+func SetConfigServers(profiles []ServerProfile) {
+	configServers = profiles
+}
+
+// ValidateServerNames 检查服务器名称不为空且不重复（不区分大小写）
+func ValidateServerNames(profiles []ServerProfile) error {
+	for i, profile := range profiles {
+		if strings.TrimSpace(profile.Name) == "" {
+			return fmt.Errorf("服务器名称不能为空")
+		}
+		for _, other := range profiles[:i] {
+			if strings.EqualFold(other.Name, profile.Name) {
+				return fmt.Errorf("服务器 %s 和 %s 重复", other.Name, profile.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// configServer 按名称（不区分大小写）查找配置文件中的服务器
+func configServer(name string) (ServerProfile, bool) {
+	for _, profile := range configServers {
+		if strings.EqualFold(profile.Name, name) {
+			return profile, true
+		}
+	}
+	return ServerProfile{}, false
+}
+
+// withConfigServer 用配置文件中同名服务器的值补充环境变量中未设置的字段
+func withConfigServer(profile ServerProfile) ServerProfile {
+	configured, exists := configServer(profile.Name)
+	if !exists {
+		return profile
+	}
+	if profile.APIBaseURL == "" {
+		profile.APIBaseURL = configured.APIBaseURL
+	}
+	if profile.AuthToken == "" {
+		profile.AuthToken = configured.AuthToken
+	}
+	if profile.Username == "" {
+		profile.Username = configured.Username
+	}
+	if profile.Password == "" {
+		profile.Password = configured.Password
+	}
+	return profile
+}
+
+// ServerProfile 表示一个MS服务器的连接配置，AuthToken 为固定令牌，设置了用户名和密码时可自动登录获取令牌
+type ServerProfile struct {
+	Name       string
+	APIBaseURL string
+	AuthToken  string
+	Username   string
+	Password   string
+}
+
+// HasCredentials 判断是否配置了登录用的用户名和密码
+func (p ServerProfile) HasCredentials() bool {
+	return p.Username != "" && p.Password != ""
+}
+
+// Validate 检查服务器配置是否完整，未设置地址的服务器不可用
+func (p ServerProfile) Validate() error {
+	if p.APIBaseURL != "" {
+		return nil
+	}
+	if p.Name == DefaultServerName {
+		return fmt.Errorf("服务器 %s 的 API_BASE_URL 未设置", p.Name)
+	}
+	return fmt.Errorf("服务器 %s 的 %sAPI_BASE_URL（或配置文件中的 servers.%s.api_base_url）未设置", p.Name, serverEnvPrefix(p.Name), p.Name)
+}
+
+// DefaultServerProfile 获取 API_BASE_URL/AUTH_TOKEN 和 MS_USERNAME/MS_PASSWORD 配置的默认服务器，
+// 未设置的值使用配置文件中的 default 服务器
+func DefaultServerProfile() ServerProfile {
+	return withConfigServer(ServerProfile{
+		Name:       DefaultServerName,
+		APIBaseURL: os.Getenv("API_BASE_URL"),
+		AuthToken:  os.Getenv("AUTH_TOKEN"),
+		Username:   os.Getenv("MS_USERNAME"),
+		Password:   os.Getenv("MS_PASSWORD"),
+	})
+}
+
+// serverEnvPrefix 返回服务器配置的环境变量前缀，如 home → MS_HOME_
+func serverEnvPrefix(name string) string {
+	key := strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, strings.ToUpper(name))
+	return "MS_" + key + "_"
+}
+
+// GetServerProfiles 获取所有配置的服务器
+// API_BASE_URL/AUTH_TOKEN 为 default 服务器；MS_SERVERS 列出其他服务器名称（逗号分隔），
+// 每个服务器通过 MS_<名称>_API_BASE_URL、MS_<名称>_AUTH_TOKEN、MS_<名称>_USERNAME 和 MS_<名称>_PASSWORD 配置
+// 配置文件 servers 中的服务器排在 MS_SERVERS 之后，同名服务器的环境变量优先，未设置的值使用配置文件中的值
+// 单个服务器的配置不完整时仍会返回，由使用方调用 Validate 检查，不影响其他服务器
+func GetServerProfiles() ([]ServerProfile, error) {
+	var profiles []ServerProfile
+	if profile := DefaultServerProfile(); profile.APIBaseURL != "" {
+		profiles = append(profiles, profile)
+	}
+
+	for _, name := range strings.Split(os.Getenv("MS_SERVERS"), ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if strings.EqualFold(name, DefaultServerName) {
+			return nil, fmt.Errorf("MS_SERVERS 中的服务器不能命名为 %s", DefaultServerName)
+		}
+		if containsServer(profiles, name) {
+			return nil, fmt.Errorf("MS_SERVERS 中的服务器 %s 重复", name)
+		}
+
+		profiles = append(profiles, envServerProfile(name))
+	}
+
+	for _, configured := range configServers {
+		if strings.EqualFold(configured.Name, DefaultServerName) || containsServer(profiles, configured.Name) {
+			continue
+		}
+		profiles = append(profiles, envServerProfile(configured.Name))
+	}
+
+	return profiles, nil
+}
+
+// envServerProfile 获取 MS_<名称>_* 环境变量配置的服务器，未设置的值使用配置文件中的同名服务器
+func envServerProfile(name string) ServerProfile {
+	prefix := serverEnvPrefix(name)
+	return withConfigServer(ServerProfile{
+		Name:       name,
+		APIBaseURL: os.Getenv(prefix + "API_BASE_URL"),
+		AuthToken:  os.Getenv(prefix + "AUTH_TOKEN"),
+		Username:   os.Getenv(prefix + "USERNAME"),
+		Password:   os.Getenv(prefix + "PASSWORD"),
+	})
+}
+
+// containsServer 判断是否已有同名服务器（不区分大小写）
+func containsServer(profiles []ServerProfile, name string) bool {
+	for _, profile := range profiles {
+		if strings.EqualFold(profile.Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// SelectServerProfiles 按名称选择服务器，多个用逗号分隔，all 表示所有服务器
+// 未指定名称时使用 default 服务器，没有 default 且只配置了一个服务器时使用该服务器
+func SelectServerProfiles(names string) ([]ServerProfile, error) {
+	profiles, err := GetServerProfiles()
+	if err != nil {
+		return nil, err
+	}
+	if len(profiles) == 0 {
+		return nil, fmt.Errorf("未配置任何服务器，请设置 API_BASE_URL 或 MS_SERVERS，或在配置文件的 servers 中添加服务器")
+	}
+
+	names = strings.TrimSpace(names)
+	if strings.EqualFold(names, "all") {
+		return profiles, nil
+	}
+	if names == "" {
+		if profiles[0].Name == DefaultServerName || len(profiles) == 1 {
+			return profiles[:1], nil
+		}
+		return nil, fmt.Errorf("配置了多个服务器，请使用 --server 指定：%s", strings.Join(serverNames(profiles), ", "))
+	}
+
+	var selected []ServerProfile
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		found := false
+		for _, profile := range profiles {
+			if strings.EqualFold(profile.Name, name) {
+				selected = append(selected, profile)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("未配置服务器 %s，可用的服务器：%s", name, strings.Join(serverNames(profiles), ", "))
+		}
+	}
+	return selected, nil
+}
+
+// serverNames 返回服务器名称列表
+func serverNames(profiles []ServerProfile) []string {
+	var names []string
+	for _, profile := range profiles {
+		names = append(names, profile.Name)
+	}
+	return names
+}
@@ -0,0 +1,57 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGetServerProfilesFromConfig(t *testing.T) {
+	for _, env := range []string{"API_BASE_URL", "AUTH_TOKEN", "MS_USERNAME", "MS_PASSWORD", "MS_SERVERS"} {
+		t.Setenv(env, "")
+	}
+	t.Setenv("MS_SERVERS", "work")
+	t.Setenv("MS_WORK_API_BASE_URL", "http://work.env")
+	t.Setenv("MS_HOME_API_BASE_URL", "http://home.env")
+	SetConfigServers([]ServerProfile{
+		{Name: "default", APIBaseURL: "http://default.file", AuthToken: "file-token"},
+		{Name: "home", APIBaseURL: "http://home.file", Username: "alice", Password: "secret"},
+		{Name: "Work", AuthToken: "work-token"},
+	})
+	t.Cleanup(func() { SetConfigServers(nil) })
+
+	got, err := GetServerProfiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ServerProfile{
+		{Name: "default", APIBaseURL: "http://default.file", AuthToken: "file-token"},
+		// MS_SERVERS 中的服务器在前，与配置文件中的同名服务器（不区分大小写）合并
+		{Name: "work", APIBaseURL: "http://work.env", AuthToken: "work-token"},
+		// 环境变量优先于配置文件
+		{Name: "home", APIBaseURL: "http://home.env", Username: "alice", Password: "secret"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetServerProfiles() = %+v\n期望 %+v", got, want)
+	}
+}
+
+func TestValidateServerNames(t *testing.T) {
+	tests := []struct {
+		names   []string
+		wantErr bool
+	}{
+		{names: []string{"default", "home"}},
+		{names: []string{"home", "Home"}, wantErr: true},
+		{names: []string{" "}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		var profiles []ServerProfile
+		for _, name := range tt.names {
+			profiles = append(profiles, ServerProfile{Name: name})
+		}
+		if err := ValidateServerNames(profiles); (err != nil) != tt.wantErr {
+			t.Errorf("ValidateServerNames(%v) error = %v, 期望错误 %v", tt.names, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/term"
+)
+
+// KeyType 表示按键的类型
+type KeyType int
+
+const (
+	KeyText      KeyType = iota // 输入的文本
+	KeyEnter                    // 回车
+	KeyBackspace                // 退格
+	KeyEscape                   // Esc
+	KeyUp                       // ↑
+	KeyDown                     // ↓
+	KeyLeft                     // ←
+	KeyRight                    // →
+	KeyBackTab                  // Shift+Tab
+	KeyClearLine                // Ctrl+U，清空输入
+	KeyInterrupt                // Ctrl+C
+	KeyUnknown                  // 其他控制键
+)
+
+// Key 表示终端中的一次按键
+type Key struct {
+	Type KeyType
+	Text string // KeyText 时输入的文本，粘贴时可能包含多个字符
+}
+
+// 绘制行使用的样式（ANSI SGR 参数）
+const (
+	StyleBold    = "1"
+	StyleDim     = "2"
+	StyleReverse = "7"
+	StyleError   = "31"
+	StyleAccent  = "36"
+)
+
+// ScreenLine 表示屏幕上的一行，Style 为空时不使用样式
+type ScreenLine struct {
+	Text  string
+	Style string
+}
+
+// Screen 全屏终端界面：原始模式下逐键读取输入，在备用屏幕缓冲区中绘制，关闭后恢复原来的终端内容
+type Screen struct {
+	in    *os.File
+	out   *os.File
+	state *term.State
+	buf   [256]byte
+}
+
+// OpenScreen 将终端切换到原始模式和备用屏幕，输入和输出都必须是终端
+func OpenScreen(in, out *os.File) (*Screen, error) {
+	if !term.IsTerminal(int(in.Fd())) || !term.IsTerminal(int(out.Fd())) {
+		return nil, fmt.Errorf("需要在终端中运行")
+	}
+	state, err := term.MakeRaw(int(in.Fd()))
+	if err != nil {
+		return nil, fmt.Errorf("切换终端模式失败: %v", err)
+	}
+	fmt.Fprint(out, "\x1b[?1049h\x1b[H\x1b[2J")
+	return &Screen{in: in, out: out, state: state}, nil
+}
+
+// Close 离开备用屏幕并恢复终端模式
+func (s *Screen) Close() {
+	fmt.Fprint(s.out, "\x1b[0m\x1b[?25h\x1b[?1049l")
+	term.Restore(int(s.in.Fd()), s.state)
+}
+
+// Size 返回终端的宽度和高度，获取失败时按 80x24 处理
+func (s *Screen) Size() (int, int) {
+	width, height, err := term.GetSize(int(s.out.Fd()))
+	if err != nil || width <= 0 || height <= 0 {
+		return 80, 24
+	}
+	return width, height
+}
+
+// Draw 重绘整个屏幕：超出宽度的行被截断，超出高度的行被丢弃；cursorRow 小于0时隐藏光标，否则将光标移到指定位置（从0开始）
+func (s *Screen) Draw(lines []ScreenLine, cursorRow, cursorCol int) {
+	width, height := s.Size()
+	if len(lines) > height {
+		lines = lines[:height]
+	}
+
+	var b strings.Builder
+	b.WriteString("\x1b[?25l\x1b[H")
+	for i, line := range lines {
+		text := TruncateWidth(line.Text, width)
+		if line.Style != "" {
+			b.WriteString("\x1b[" + line.Style + "m" + text + "\x1b[0m")
+		} else {
+			b.WriteString(text)
+		}
+		b.WriteString("\x1b[K")
+		if i < len(lines)-1 {
+			b.WriteString("\r\n")
+		}
+	}
+	b.WriteString("\x1b[J")
+	if cursorRow >= 0 && cursorRow < len(lines) {
+		fmt.Fprintf(&b, "\x1b[%d;%dH\x1b[?25h", cursorRow+1, min(cursorCol, width-1)+1)
+	}
+	fmt.Fprint(s.out, b.String())
+}
+
+// ReadKey 读取一次按键
+func (s *Screen) ReadKey() (Key, error) {
+	n, err := s.in.Read(s.buf[:])
+	if err != nil {
+		return Key{}, fmt.Errorf("读取输入失败: %v", err)
+	}
+	return parseKey(s.buf[:n]), nil
+}
+
+// parseKey 解析一次读取到的按键序列
+func parseKey(data []byte) Key {
+	switch string(data) {
+	case "\r", "\n", "\r\n":
+		return Key{Type: KeyEnter}
+	case "\x7f", "\x08":
+		return Key{Type: KeyBackspace}
+	case "\x1b":
+		return Key{Type: KeyEscape}
+	case "\x1b[A", "\x1bOA":
+		return Key{Type: KeyUp}
+	case "\x1b[B", "\x1bOB":
+		return Key{Type: KeyDown}
+	case "\x1b[C", "\x1bOC":
+		return Key{Type: KeyRight}
+	case "\x1b[D", "\x1bOD":
+		return Key{Type: KeyLeft}
+	case "\x1b[Z":
+		return Key{Type: KeyBackTab}
+	case "\x15":
+		return Key{Type: KeyClearLine}
+	case "\x03":
+		return Key{Type: KeyInterrupt}
+	}
+	if data[0] == '\x1b' {
+		return Key{Type: KeyUnknown}
+	}
+
+	// 文本输入（包括粘贴），去掉其中的控制字符
+	var text strings.Builder
+	for _, r := range string(data) {
+		if r != utf8.RuneError && !unicode.IsControl(r) {
+			text.WriteRune(r)
+		}
+	}
+	if text.Len() == 0 {
+		return Key{Type: KeyUnknown}
+	}
+	return Key{Type: KeyText, Text: text.String()}
+}
+
+// runeWidth 返回字符在终端中占用的列数，中日韩文字和全角符号占两列
+func runeWidth(r rune) int {
+	switch {
+	case r == 0 || unicode.IsControl(r) || unicode.Is(unicode.Mn, r):
+		return 0
+	case r >= 0x1100 && r <= 0x115F,
+		r >= 0x2E80 && r <= 0xA4CF,
+		r >= 0xAC00 && r <= 0xD7A3,
+		r >= 0xF900 && r <= 0xFAFF,
+		r >= 0xFE30 && r <= 0xFE4F,
+		r >= 0xFF00 && r <= 0xFF60,
+		r >= 0xFFE0 && r <= 0xFFE6,
+		r >= 0x1F300 && r <= 0x1F64F,
+		r >= 0x20000 && r <= 0x3FFFD:
+		return 2
+	}
+	return 1
+}
+
+// DisplayWidth 返回字符串在终端中占用的列数
+func DisplayWidth(s string) int {
+	width := 0
+	for _, r := range s {
+		width += runeWidth(r)
+	}
+	return width
+}
+
+// TruncateWidth 将字符串截断到指定列数以内，截断时以 … 结尾
+func TruncateWidth(s string, width int) string {
+	if DisplayWidth(s) <= width {
+		return s
+	}
+	if width <= 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	used := 0
+	for _, r := range s {
+		w := runeWidth(r)
+		if used+w > width-1 {
+			break
+		}
+		b.WriteRune(r)
+		used += w
+	}
+	b.WriteString("…")
+	return b.String()
+}
@@ -0,0 +1,391 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"regexp/syntax"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dlclark/regexp2"
+	"github.com/harry/rename-by-tmdb/internal/models"
+	"github.com/harry/rename-by-tmdb/internal/utils"
+)
+
+// lintUnit 表示参与检查的一条规则
+type lintUnit struct {
+	Group   models.WordGroup
+	Unit    models.WordUnit
+	Pattern *regexp2.Regexp
+	Prefix  string   // 被替换词开头的字面量，样例中不包含时该规则不可能匹配
+	Samples []string // 按被替换词生成的文件名样例
+}
+
+// label 返回规则的说明：规则ID、被替换词和所属词组
+func (u *lintUnit) label() string {
+	return fmt.Sprintf("规则 %d（%s，词组 %s）", u.Unit.ID, u.Unit.BeReplaced, u.Group.Title)
+}
+
+// lintOverlap 表示一条规则的样例同时被另一条规则匹配
+type lintOverlap struct {
+	Rule     *lintUnit
+	Other    *lintUnit
+	Sample   string
+	Shadowed bool // Rule 的所有样例都被 Other 匹配
+}
+
+// lintIssue 表示无法检查的规则
+type lintIssue struct {
+	Rule   *lintUnit
+	Reason string
+}
+
+// literalPrefix 返回正则开头的字面量部分；顶层有分支时返回空字符串
+func literalPrefix(pattern string) string {
+	depth, inClass := 0, false
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; {
+		case c == '\\':
+			i++
+		case inClass:
+			inClass = c != ']'
+		case c == '[':
+			inClass = true
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == '|' && depth == 0:
+			return ""
+		}
+	}
+
+	var prefix strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		if c == '\\' && i+1 < len(pattern) && !isAlphanumeric(pattern[i+1]) {
+			prefix.WriteByte(pattern[i+1])
+			i++
+			continue
+		}
+		if strings.IndexByte(`\.+*?()|[]{}^$`, c) >= 0 {
+			// 量词允许前一个字符出现0次，前一个字符不属于前缀
+			if (c == '?' || c == '*' || c == '{') && prefix.Len() > 0 {
+				text := prefix.String()
+				return text[:len(text)-1]
+			}
+			break
+		}
+		prefix.WriteByte(c)
+	}
+	return prefix.String()
+}
+
+// isAlphanumeric 判断字符是否为ASCII字母或数字
+func isAlphanumeric(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+// syntaxSample 按正则语法树生成一个能被匹配的字符串，last为true时分支取最后一个，否则取第一个
+func syntaxSample(re *syntax.Regexp, last bool, sample *strings.Builder) {
+	switch re.Op {
+	case syntax.OpLiteral:
+		sample.WriteString(string(re.Rune))
+	case syntax.OpCharClass:
+		sample.WriteRune(classSample(re.Rune))
+	case syntax.OpAnyChar, syntax.OpAnyCharNotNL:
+		sample.WriteByte('.')
+	case syntax.OpCapture, syntax.OpPlus:
+		syntaxSample(re.Sub[0], last, sample)
+	case syntax.OpStar:
+		// .* 用分隔符代替，使样例更接近真实文件名
+		if op := re.Sub[0].Op; op == syntax.OpAnyChar || op == syntax.OpAnyCharNotNL {
+			sample.WriteByte('.')
+		}
+	case syntax.OpRepeat:
+		for i := 0; i < re.Min; i++ {
+			syntaxSample(re.Sub[0], last, sample)
+		}
+	case syntax.OpConcat:
+		for _, sub := range re.Sub {
+			syntaxSample(sub, last, sample)
+		}
+	case syntax.OpAlternate:
+		if last {
+			syntaxSample(re.Sub[len(re.Sub)-1], last, sample)
+		} else {
+			syntaxSample(re.Sub[0], last, sample)
+		}
+	}
+}
+
+// classSample 从字符类中选一个字符，优先选择文件名中常见的字符
+func classSample(ranges []rune) rune {
+	for _, candidate := range []rune{'.', ' ', 'a', 'A', '0', '1'} {
+		for i := 0; i+1 < len(ranges); i += 2 {
+			if candidate >= ranges[i] && candidate <= ranges[i+1] {
+				return candidate
+			}
+		}
+	}
+	if len(ranges) == 0 {
+		return '.'
+	}
+	return ranges[0]
+}
+
+// noteSamples 按生成参数构造典型的文件名样例
+func noteSamples(note *models.UnitNote) []string {
+	title := note.FileTitle
+	var samples []string
+	switch note.Mode {
+	case models.NoteModeMovie:
+		samples = append(samples, title+".1080p.mkv")
+	case models.NoteModeRange, models.NoteModePartRange:
+		for _, episode := range []int{note.Start, note.End} {
+			if note.HasSeason {
+				samples = append(samples, fmt.Sprintf("%s.S%02dE%0*d.1080p.mkv", title, note.Season, note.Digits, episode))
+			} else {
+				samples = append(samples, fmt.Sprintf("%s - %0*d [1080p].mkv", title, note.Digits, episode))
+			}
+		}
+	case models.NoteModeMulti:
+		if note.HasSeason {
+			samples = append(samples, fmt.Sprintf("%s.S%02dE%02d-E%02d.mkv", title, note.Season, note.Start, note.End))
+		} else {
+			samples = append(samples, fmt.Sprintf("%s.E%02d-E%02d.mkv", title, note.Start, note.End))
+		}
+	case models.NoteModePart:
+		if note.HasSeason {
+			samples = append(samples, fmt.Sprintf("%s.S%02dE%0*d.Part%d.mkv", title, note.Season, note.Digits, note.Episode, note.Part))
+		} else {
+			samples = append(samples, fmt.Sprintf("%s.E%0*d.Part%d.mkv", title, note.Digits, note.Episode, note.Part))
+		}
+	case models.NoteModeDate:
+		date, err := time.Parse("2006-01-02", note.AirDate)
+		formats, formatErr := utils.ParseDateFormats(strings.Join(note.DateFormats, ";"))
+		if err != nil || formatErr != nil {
+			break
+		}
+		sample := fmt.Sprintf("%s.%s", title, date.Format(formats[0].Layout))
+		switch note.SameDate {
+		case "part":
+			sample += fmt.Sprintf(".Part%d", note.Part)
+		case "episode":
+			sample += fmt.Sprintf(".E%02d", note.Episode)
+		}
+		samples = append(samples, sample+".mkv")
+	case models.NoteModeSpecial:
+		samples = append(samples, fmt.Sprintf("%s.%s.mkv", title, note.Marker))
+	case models.NoteModeTitle:
+		if len(note.Titles) > 0 {
+			samples = append(samples, fmt.Sprintf("%s.%s.mkv", title, strings.ReplaceAll(note.Titles[0], " ", ".")))
+		}
+	}
+	return samples
+}
+
+// buildLintUnit 编译规则并生成样例，只保留能被规则自身匹配的样例
+func buildLintUnit(group models.WordGroup, unit models.WordUnit) (*lintUnit, error) {
+	pattern, err := utils.CompileServerRegex(unit.BeReplaced, unit.Regex)
+	if err != nil {
+		return nil, err
+	}
+	rule := &lintUnit{Group: group, Unit: unit, Pattern: pattern}
+
+	var candidates []string
+	if unit.Regex {
+		rule.Prefix = literalPrefix(unit.BeReplaced)
+		if parsed, err := syntax.Parse(unit.BeReplaced, syntax.Perl); err == nil {
+			for _, last := range []bool{false, true} {
+				var sample strings.Builder
+				syntaxSample(parsed, last, &sample)
+				candidates = append(candidates, sample.String())
+			}
+		}
+	} else {
+		rule.Prefix = unit.BeReplaced
+		candidates = append(candidates, unit.BeReplaced)
+	}
+
+	note, err := models.ParseUnitNote(unit.Note)
+	if err != nil || note == nil {
+		note, _ = interpretUnit(unit)
+	}
+	if note != nil {
+		candidates = append(candidates, noteSamples(note)...)
+	}
+
+	seen := make(map[string]bool)
+	for _, sample := range candidates {
+		if sample == "" || seen[sample] {
+			continue
+		}
+		seen[sample] = true
+		if matched, err := pattern.MatchString(sample); err == nil && matched {
+			rule.Samples = append(rule.Samples, sample)
+		}
+	}
+	return rule, nil
+}
+
+// findOverlaps 用每条规则的样例检查其他规则，sameGroup为false时只检查不同词组之间的规则
+func findOverlaps(rules []*lintUnit, sameGroup bool) []lintOverlap {
+	var overlaps []lintOverlap
+	for _, rule := range rules {
+		for _, other := range rules {
+			if other == rule || (!sameGroup && other.Group.ID == rule.Group.ID) {
+				continue
+			}
+
+			matched := 0
+			firstSample := ""
+			for _, sample := range rule.Samples {
+				if other.Prefix != "" && !strings.Contains(sample, other.Prefix) {
+					continue
+				}
+				if ok, err := other.Pattern.MatchString(sample); err == nil && ok {
+					if matched == 0 {
+						firstSample = sample
+					}
+					matched++
+				}
+			}
+			if matched > 0 {
+				overlaps = append(overlaps, lintOverlap{
+					Rule:     rule,
+					Other:    other,
+					Sample:   firstSample,
+					Shadowed: matched == len(rule.Samples),
+				})
+			}
+		}
+	}
+	return overlaps
+}
+
+// findDisabledDuplicates 查找与其他规则被替换词相同的禁用规则
+func findDisabledDuplicates(rules []*lintUnit) []lintIssue {
+	byPattern := make(map[string][]*lintUnit)
+	var patterns []string
+	for _, rule := range rules {
+		key := fmt.Sprintf("%t\x00%s", rule.Unit.Regex, rule.Unit.BeReplaced)
+		if _, exists := byPattern[key]; !exists {
+			patterns = append(patterns, key)
+		}
+		byPattern[key] = append(byPattern[key], rule)
+	}
+
+	var issues []lintIssue
+	for _, key := range patterns {
+		duplicates := byPattern[key]
+		if len(duplicates) < 2 {
+			continue
+		}
+		for _, rule := range duplicates {
+			if rule.Unit.Enabled {
+				continue
+			}
+			for _, other := range duplicates {
+				if other != rule {
+					issues = append(issues, lintIssue{Rule: rule, Reason: fmt.Sprintf("与%s相同", other.label())})
+					break
+				}
+			}
+		}
+	}
+	return issues
+}
+
+// runLint lint 命令：加载服务器上所有词组和规则，检查无效正则、不同词组之间重叠或被遮蔽的规则，以及禁用的重复规则
+func runLint(args []string) error {
+	flags := flag.NewFlagSet("lint", flag.ExitOnError)
+	sameGroup := flags.Bool("same", false, "同时检查同一词组内的规则")
+	flags.Parse(args)
+
+	wordGroupService, err := newWordGroupService()
+	if err != nil {
+		return fmt.Errorf("创建词组服务失败: %v", err)
+	}
+
+	groups, err := wordGroupService.ListWordGroups("")
+	if err != nil {
+		return fmt.Errorf("获取词组列表失败: %v", err)
+	}
+
+	var all, enabled []*lintUnit
+	var invalid []lintIssue
+	total := 0
+	for _, group := range groups {
+		units, err := wordGroupService.ListWordUnits(group.ID)
+		if err != nil {
+			return fmt.Errorf("获取词组 %s 的规则失败: %v", group.Title, err)
+		}
+		for _, unit := range units {
+			// 屏蔽词和集数偏移规则不决定文件名的替换结果，不参与检查
+			if unit.Type == models.WordUnitTypeBlock || unit.Type == models.WordUnitTypeOffset || unit.BeReplaced == "" {
+				continue
+			}
+			total++
+			rule, err := buildLintUnit(group, unit)
+			if err != nil {
+				invalid = append(invalid, lintIssue{Rule: &lintUnit{Group: group, Unit: unit}, Reason: err.Error()})
+				continue
+			}
+			all = append(all, rule)
+			if unit.Enabled {
+				enabled = append(enabled, rule)
+			}
+		}
+	}
+	fmt.Printf("已加载 %d 个词组、%d 条替换规则\n", len(groups), total)
+
+	if len(invalid) > 0 {
+		fmt.Printf("\n=== 无效的正则（%d 条）===\n", len(invalid))
+		for _, issue := range invalid {
+			fmt.Printf("%s\n  %s\n", issue.Rule.label(), issue.Reason)
+		}
+	}
+
+	overlaps := findOverlaps(enabled, *sameGroup)
+	sort.SliceStable(overlaps, func(i, j int) bool {
+		return overlaps[i].Shadowed && !overlaps[j].Shadowed
+	})
+	if len(overlaps) > 0 {
+		fmt.Printf("\n=== 重叠的规则（%d 处）===\n", len(overlaps))
+		for _, overlap := range overlaps {
+			if overlap.Shadowed {
+				fmt.Printf("[遮蔽] %s\n  所有样例都被 %s 匹配\n", overlap.Rule.label(), overlap.Other.label())
+			} else {
+				fmt.Printf("[重叠] %s\n  部分样例也被 %s 匹配\n", overlap.Rule.label(), overlap.Other.label())
+			}
+			fmt.Printf("  样例：%s\n", overlap.Sample)
+		}
+	}
+
+	duplicates := findDisabledDuplicates(all)
+	if len(duplicates) > 0 {
+		fmt.Printf("\n=== 禁用的重复规则（%d 条）===\n", len(duplicates))
+		for _, issue := range duplicates {
+			fmt.Printf("%s\n  %s\n", issue.Rule.label(), issue.Reason)
+		}
+	}
+
+	noSamples := 0
+	for _, rule := range enabled {
+		if len(rule.Samples) == 0 {
+			noSamples++
+		}
+	}
+	if noSamples > 0 {
+		fmt.Printf("\n注意：%d 条规则无法生成文件名样例，未参与重叠检查\n", noSamples)
+	}
+
+	if len(invalid) == 0 && len(overlaps) == 0 && len(duplicates) == 0 {
+		fmt.Println("\n没有发现问题")
+		return nil
+	}
+	fmt.Printf("\n共发现：无效正则 %d 条，重叠 %d 处，禁用的重复规则 %d 条\n", len(invalid), len(overlaps), len(duplicates))
+	return nil
+}
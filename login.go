@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/harry/rename-by-tmdb/internal/services"
+	"github.com/harry/rename-by-tmdb/internal/utils"
+)
+
+// runLogin login 命令：使用用户名和密码登录选择的服务器，并将令牌保存到本地
+// 未在配置中设置用户名或密码时提示输入
+func runLogin() error {
+	profiles, err := utils.SelectServerProfiles(serverFlag)
+	if err != nil {
+		return err
+	}
+
+	for _, profile := range profiles {
+		fmt.Printf("\n登录服务器：%s（%s）\n", profile.Name, profile.APIBaseURL)
+		if !profile.HasCredentials() {
+			if profile.Username == "" {
+				profile.Username, err = utils.GetUserInput("请输入用户名: ")
+				if err != nil {
+					return fmt.Errorf("错误: %v", err)
+				}
+			}
+			if profile.Password == "" {
+				profile.Password, err = utils.GetPassword("请输入密码: ")
+				if err != nil {
+					return fmt.Errorf("错误: %v", err)
+				}
+			}
+		}
+
+		wordGroupService, err := services.NewWordGroupServiceForProfile(profile)
+		if err != nil {
+			return fmt.Errorf("创建词组服务失败: %v", err)
+		}
+		if err := wordGroupService.Login(); err != nil {
+			return err
+		}
+		fmt.Println("登录成功，令牌已保存")
+	}
+	return nil
+}
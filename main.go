@@ -3,16 +3,21 @@ package main
 import (
 	"fmt"
 	"io"
+	"os"
 	"regexp"
 	"sort"
 	"strconv"
 	"strings"
 
+	"github.com/harry/rename-by-tmdb/internal/config"
 	"github.com/harry/rename-by-tmdb/internal/models"
 	"github.com/harry/rename-by-tmdb/internal/services"
 	"github.com/harry/rename-by-tmdb/internal/utils"
 )
 
+// version 工具版本，写入配方；发布时由 scripts/build.sh 通过 -ldflags 设置
+var version = "v1.0.5"
+
 // romanToArabic 将罗马数字转换为阿拉伯数字
 func romanToArabic(roman string) int {
 	romanMap := map[byte]int{
@@ -73,34 +78,31 @@ func extractPartInfo(fileTitle string) string {
 	return ""
 }
 
-// findExistingWordGroup 查找已存在的词组
+// findExistingWordGroup 查找已存在的词组，找到的词组标题与命名格式不同时给出提示
 func findExistingWordGroup(wordGroupService *services.WordGroupService, namingFormat string) (*models.WordGroup, error) {
-	list, err := wordGroupService.GetWordGroupList()
+	group, err := wordGroupService.FindWordGroup(namingFormat)
 	if err != nil {
 		return nil, fmt.Errorf("获取词组列表失败: %v", err)
 	}
 
-	for _, group := range list.List {
-		if group.Title == namingFormat {
-			return &group, nil
-		}
+	if group != nil && group.Title != namingFormat {
+		fmt.Printf("注意：找到相同 tmdbid 的词组，但标题与当前命名格式不同：%s\n", group.Title)
 	}
-
-	return nil, nil
+	return group, nil
 }
 
 // 处理电影重命名
-func handleMovie(tmdbService *services.TMDBService) error {
+func handleMovie(tmdbService *services.TMDBService, out io.Writer) (*ruleSet, error) {
 	// 获取电影ID
-	movieID, err := utils.GetUserInput("请输入电影ID: ")
+	movieID, err := askRecipeInput(out, "请输入电影ID: ", func(r *recipe) string { return r.TMDBID })
 	if err != nil {
-		return fmt.Errorf("错误: %v", err)
+		return nil, fmt.Errorf("错误: %v", err)
 	}
 
 	// 获取电影信息
 	movie, err := tmdbService.FetchMovieInfo(movieID)
 	if err != nil {
-		return fmt.Errorf("获取电影信息失败: %v", err)
+		return nil, fmt.Errorf("获取电影信息失败: %v", err)
 	}
 
 	// 从发布日期中提取年份
@@ -109,91 +111,64 @@ func handleMovie(tmdbService *services.TMDBService) error {
 		year = movie.ReleaseDate[:4]
 	}
 
-	// 将空格替换为点号
-	movieName := strings.ReplaceAll(movie.Title, " ", ".")
+	// 按名称模板生成名称，空格替换为点号
+	naming := mediaNaming{
+		Name:      utils.FormatName(movie.Title, movie.OriginalTitle),
+		Year:      year,
+		TMDBID:    movieID,
+		MediaType: "movie",
+	}
 
 	// 创建命名格式
-	namingFormat := fmt.Sprintf("%s.%s.{[tmdbid=%s;type=movie]}",
-		movieName, year, movieID)
-	fmt.Printf("命名格式：\n%s\n", namingFormat)
-
-	var wordGroup *models.WordGroup
-	if utils.IsUploadEnabled() {
-		// 创建词组服务
-		wordGroupService, err := services.NewWordGroupService()
-		if err != nil {
-			return fmt.Errorf("创建词组服务失败: %v", err)
-		}
-
-		// 查找是否存在相同的命名格式
-		existingGroup, err := findExistingWordGroup(wordGroupService, namingFormat)
-		if err != nil {
-			return err
-		}
-
-		if existingGroup != nil {
-			// 使用已存在的词组
-			wordGroup = existingGroup
-			fmt.Printf("使用已存在的词组，ID: %d\n", wordGroup.ID)
-		} else {
-			// 创建新词组
-			wordGroup, err = wordGroupService.CreateWordGroup(namingFormat)
-			if err != nil {
-				return fmt.Errorf("创建词组失败: %v", err)
-			}
-			fmt.Printf("词组创建成功，ID: %d\n", wordGroup.ID)
-		}
+	namingFormat := naming.title()
+	fmt.Fprintf(out, "命名格式：\n%s\n", namingFormat)
 
-		// 获取用户当前文件名中的标题部分
-		fileTitle, err := utils.GetUserInput("请输入当前文件名中的标题部分（例如：The.Matrix）: ")
-		if err != nil {
-			return fmt.Errorf("错误: %v", err)
-		}
-
-		// 检测并提取part信息
-		partInfo := extractPartInfo(fileTitle)
-
-		// 构建电影的替换规则
-		beReplaced := fmt.Sprintf("%s.*", regexp.QuoteMeta(fileTitle))
-		var replace string
-		if partInfo != "" {
-			replace = fmt.Sprintf("%s.%s.%s.{[tmdbid=%s;type=movie]}", movieName, year, partInfo, movieID)
-		} else {
-			replace = fmt.Sprintf("%s.%s.{[tmdbid=%s;type=movie]}", movieName, year, movieID)
-		}
-
-		fmt.Printf("\n被替换词：\n%s\n", beReplaced)
-		fmt.Printf("替换词：\n%s\n", replace)
-
-		// 上传替换规则
-		err = wordGroupService.AddWordUnit(wordGroup.ID, beReplaced, replace, "", "", 0)
-		if err != nil {
-			return fmt.Errorf("上传电影替换规则失败: %v", err)
-		}
-		fmt.Printf("电影替换规则上传成功\n")
+	// 获取用户当前文件名中的标题部分
+	fileTitle, err := askRecipeInput(out, "请输入当前文件名中的标题部分（例如：The.Matrix）: ", func(r *recipe) string { return r.FileTitle })
+	if err != nil {
+		return nil, fmt.Errorf("错误: %v", err)
+	}
 
-		fmt.Println("\n注意：")
-		fmt.Println("1. 正则表达式中的点号（.）已经被转义")
-		fmt.Println("2. 替换词中的'\\1'表示保留原始集数")
-		fmt.Println("3. [^.]* 匹配除点号外的任意字符，用于处理标题和集数之间可能存在的额外字符")
-		fmt.Println("4. 替换后的文件名使用TMDB中的官方电影名称")
+	// 构建电影的替换规则，检测到part信息时加入替换词
+	rule, err := buildRule(naming, models.UnitNote{
+		Mode:      models.NoteModeMovie,
+		FileTitle: fileTitle,
+		PartInfo:  extractPartInfo(fileTitle),
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	return nil
+	fmt.Fprintf(out, "\n被替换词：\n%s\n", rule.Unit.BeReplaced)
+	fmt.Fprintf(out, "替换词：\n%s\n", rule.Unit.Replace)
+
+	fmt.Fprintln(out, "\n注意：")
+	fmt.Fprintln(out, "1. 正则表达式中的点号（.）已经被转义")
+	fmt.Fprintln(out, "2. 替换词中的'\\1'表示保留原始集数")
+	fmt.Fprintln(out, "3. [^.]* 匹配除点号外的任意字符，用于处理标题和集数之间可能存在的额外字符")
+	fmt.Fprintln(out, "4. 替换后的文件名使用TMDB中的官方电影名称")
+
+	return &ruleSet{
+		Title:     namingFormat,
+		TMDBID:    movieID,
+		MediaType: "movie",
+		FileTitle: fileTitle,
+		Rules:     []generatedRule{rule},
+	}, nil
 }
 
 // 处理剧集重命名
-func handleTVShow(tmdbService *services.TMDBService) error {
+func handleTVShow(tmdbService *services.TMDBService, out io.Writer) (*ruleSet, error) {
 	// 获取剧集ID
-	seriesID, err := utils.GetUserInput("请输入剧集ID: ")
+	seriesID, err := askRecipeInput(out, "请输入剧集ID: ", func(r *recipe) string { return r.TMDBID })
 	if err != nil {
-		return fmt.Errorf("错误: %v", err)
+		return nil, fmt.Errorf("错误: %v", err)
 	}
 
 	// 获取剧集信息
 	show, err := tmdbService.FetchShowInfo(seriesID)
 	if err != nil {
-		return fmt.Errorf("获取剧集信息失败: %v", err)
+		return nil, fmt.Errorf("获取剧集信息失败: %v", err)
 	}
 
 	// 从首播日期中提取年份
@@ -203,13 +178,10 @@ func handleTVShow(tmdbService *services.TMDBService) error {
 	}
 
 	// 询问是否以日期判断集数
-	fmt.Print("是否以日期判断集数？(y/N，直接回车默认为N): ")
-	useDateForEpisode, err := utils.GetUserInput("")
+	isDateMode, err := utils.GetDateModeChoice()
 	if err != nil {
-		return fmt.Errorf("错误: %v", err)
+		return nil, fmt.Errorf("错误: %v", err)
 	}
-	useDateForEpisode = strings.ToLower(strings.TrimSpace(useDateForEpisode))
-	isDateMode := useDateForEpisode == "y" || useDateForEpisode == "yes"
 
 	// 显示剧集命名格式
 	showType := "tv"
@@ -217,8 +189,9 @@ func handleTVShow(tmdbService *services.TMDBService) error {
 		showType = "movie"
 	}
 
-	// 将空格替换为点号
-	showName := strings.ReplaceAll(show.Name, " ", ".")
+	// 按名称模板生成名称，空格替换为点号
+	showName := utils.FormatName(show.Name, show.OriginalName)
+	naming := mediaNaming{Name: showName, Year: year, TMDBID: seriesID, MediaType: showType}
 
 	// 获取最后一季的最大集数
 	var maxEpisodeNumber int
@@ -240,44 +213,16 @@ func handleTVShow(tmdbService *services.TMDBService) error {
 	}
 
 	// 创建命名格式
-	namingFormat := fmt.Sprintf("%s.%s.{[tmdbid=%s;type=%s]}",
-		showName, year, seriesID, showType)
-	fmt.Printf("命名格式：\n%s\n", namingFormat)
-
-	var wordGroup *models.WordGroup
-	var wordGroupService *services.WordGroupService
-	if utils.IsUploadEnabled() {
-		// 创建词组服务
-		var err error
-		wordGroupService, err = services.NewWordGroupService()
-		if err != nil {
-			return fmt.Errorf("创建词组服务失败: %v", err)
-		}
+	namingFormat := naming.title()
+	fmt.Fprintf(out, "命名格式：\n%s\n", namingFormat)
 
-		// 查找是否存在相同的命名格式
-		existingGroup, err := findExistingWordGroup(wordGroupService, namingFormat)
-		if err != nil {
-			return err
-		}
-
-		if existingGroup != nil {
-			// 使用已存在的词组
-			wordGroup = existingGroup
-			fmt.Printf("使用已存在的词组，ID: %d\n", wordGroup.ID)
-		} else {
-			// 创建新词组
-			wordGroup, err = wordGroupService.CreateWordGroup(namingFormat)
-			if err != nil {
-				return fmt.Errorf("创建词组失败: %v", err)
-			}
-			fmt.Printf("词组创建成功，ID: %d\n", wordGroup.ID)
-		}
-	}
+	// 生成的替换规则，全部生成后统一上传
+	var rules []generatedRule
 
 	// 获取用户当前文件名中的标题部分
-	fileTitle, err := utils.GetUserInput("请输入当前文件名中的标题部分（例如：One.Piece）: ")
+	fileTitle, err := askRecipeInput(out, "请输入当前文件名中的标题部分（例如：One.Piece）: ", func(r *recipe) string { return r.FileTitle })
 	if err != nil {
-		return fmt.Errorf("错误: %v", err)
+		return nil, fmt.Errorf("错误: %v", err)
 	}
 
 	// 获取是否有part剧集
@@ -287,42 +232,102 @@ func handleTVShow(tmdbService *services.TMDBService) error {
 	var generateAllSeasons bool
 	var includeSpecialSeason bool
 
+	// 日期模式下获取日期格式和容差天数
+	var dateFormats []utils.DateFormat
+	var dateTolerance int
+	if isDateMode {
+		dateFormats, err = utils.GetDateFormats()
+		if err != nil {
+			return nil, fmt.Errorf("错误: %v", err)
+		}
+
+		dateTolerance, err = utils.GetDateTolerance()
+		if err != nil {
+			return nil, fmt.Errorf("错误: %v", err)
+		}
+	}
+	var dateFormatNames, dateExamples []string
+	for _, format := range dateFormats {
+		dateFormatNames = append(dateFormatNames, format.Name)
+		// 以首播日期为例显示每种格式在文件名中的写法
+		if example, err := utils.FormatAirDate(show.FirstAirDate, format); err == nil {
+			dateExamples = append(dateExamples, example)
+		}
+	}
+
+	// 获取是否为多集文件（多集模式与part模式互斥）
+	var isMultiEpisode bool
 	if !isDateMode {
+		isMultiEpisode, err = utils.GetMultiEpisodeChoice()
+		if err != nil {
+			return nil, fmt.Errorf("错误: %v", err)
+		}
+	}
+
+	// 获取是否为特别篇映射模式，是则只生成第0季的映射规则
+	if !isDateMode && !isMultiEpisode {
+		isSpecialsMode, err := utils.GetSpecialsChoice()
+		if err != nil {
+			return nil, fmt.Errorf("错误: %v", err)
+		}
+		if isSpecialsMode {
+			rules, err = handleSpecials(tmdbService, out, show, naming, fileTitle)
+			if err != nil {
+				return nil, err
+			}
+			return &ruleSet{Title: namingFormat, TMDBID: seriesID, MediaType: showType, FileTitle: fileTitle, Rules: rules}, nil
+		}
+
+		// 获取是否为集名匹配模式，是则按集名生成规则
+		isTitleMode, err := utils.GetEpisodeTitleChoice()
+		if err != nil {
+			return nil, fmt.Errorf("错误: %v", err)
+		}
+		if isTitleMode {
+			rules, err = handleEpisodeTitles(tmdbService, out, show, naming, fileTitle)
+			if err != nil {
+				return nil, err
+			}
+			return &ruleSet{Title: namingFormat, TMDBID: seriesID, MediaType: showType, FileTitle: fileTitle, Rules: rules}, nil
+		}
+	}
+
+	if !isDateMode && !isMultiEpisode {
 		hasPartEpisodes, err = utils.GetPartEpisodeChoice()
 		if err != nil {
-			return fmt.Errorf("错误: %v", err)
+			return nil, fmt.Errorf("错误: %v", err)
 		}
 
 		if hasPartEpisodes {
 			// 先询问要生成的季数
-			fmt.Println("\n由于选择了part模式，需要先确定要生成的季数")
+			fmt.Fprintln(out, "\n由于选择了part模式，需要先确定要生成的季数")
 			specificSeasons, generateAllSeasons, err = utils.GetSpecificSeasons()
 			if err != nil {
-				return fmt.Errorf("错误: %v", err)
+				return nil, fmt.Errorf("错误: %v", err)
 			}
 
 			// 如果选择生成所有季，询问是否包含第0季
 			if generateAllSeasons {
 				includeSpecialSeason, err = utils.GetIncludeSpecialSeason()
 				if err != nil {
-					return fmt.Errorf("错误: %v", err)
+					return nil, fmt.Errorf("错误: %v", err)
 				}
 			}
 
 			// 然后询问part剧集信息
 			partEpisodeInfo, err = utils.GetPartEpisodeInfo()
 			if err != nil {
-				return fmt.Errorf("错误: %v", err)
+				return nil, fmt.Errorf("错误: %v", err)
 			}
 
 			// 显示用户输入的part剧集信息
-			fmt.Printf("\n=== Part剧集信息 ===\n")
+			fmt.Fprintf(out, "\n=== Part剧集信息 ===\n")
 			for episodeNum, parts := range partEpisodeInfo {
-				fmt.Printf("第%d集: part%d", episodeNum, parts[0])
+				fmt.Fprintf(out, "第%d集: part%d", episodeNum, parts[0])
 				for i := 1; i < len(parts); i++ {
-					fmt.Printf(", part%d", parts[i])
+					fmt.Fprintf(out, ", part%d", parts[i])
 				}
-				fmt.Println()
+				fmt.Fprintln(out)
 			}
 		}
 	}
@@ -332,11 +337,11 @@ func handleTVShow(tmdbService *services.TMDBService) error {
 	if hasPartEpisodes {
 		// 如果有part剧集，自动设置为不使用原文件名季数
 		hasSeason = false
-		fmt.Println("\n注意：由于选择了part剧集，自动设置为不使用原文件名季数")
+		fmt.Fprintln(out, "\n注意：由于选择了part剧集，自动设置为不使用原文件名季数")
 	} else {
 		hasSeason, err = utils.GetHasSeasonChoice()
 		if err != nil {
-			return fmt.Errorf("错误: %v", err)
+			return nil, fmt.Errorf("错误: %v", err)
 		}
 	}
 
@@ -345,14 +350,14 @@ func handleTVShow(tmdbService *services.TMDBService) error {
 		// 如果不使用原文件名季数，则询问用户要生成哪些季
 		specificSeasons, generateAllSeasons, err = utils.GetSpecificSeasons()
 		if err != nil {
-			return fmt.Errorf("错误: %v", err)
+			return nil, fmt.Errorf("错误: %v", err)
 		}
 
 		// 如果选择生成所有季，询问是否包含第0季
 		if generateAllSeasons {
 			includeSpecialSeason, err = utils.GetIncludeSpecialSeason()
 			if err != nil {
-				return fmt.Errorf("错误: %v", err)
+				return nil, fmt.Errorf("错误: %v", err)
 			}
 		}
 	}
@@ -362,31 +367,37 @@ func handleTVShow(tmdbService *services.TMDBService) error {
 	if !isDateMode && !hasPartEpisodes {
 		episodeOffset, err = utils.GetEpisodeOffset()
 		if err != nil {
-			return fmt.Errorf("错误: %v", err)
+			return nil, fmt.Errorf("错误: %v", err)
+		}
+	}
+
+	// 多集模式下获取多集文件的集数区间
+	var multiEpisodeRanges []utils.EpisodeRange
+	if isMultiEpisode {
+		multiEpisodeRanges, err = utils.GetMultiEpisodeRanges()
+		if err != nil {
+			return nil, fmt.Errorf("错误: %v", err)
 		}
 	}
 
-	// 获取是否需要补0站位
+	// 获取是否需要补0站位（多集模式的被替换词兼容补0与不补0，无需询问）
 	var padZero, episodeContinuous bool
-	if !isDateMode {
+	if !isDateMode && !isMultiEpisode {
 		padZero, err = utils.GetPadZeroChoice()
 		if err != nil {
-			return fmt.Errorf("错误: %v", err)
+			return nil, fmt.Errorf("错误: %v", err)
 		}
 
 		// 如果需要补0，询问集数是否连续
 		if padZero {
 			episodeContinuous, err = utils.GetEpisodeContinuousChoice()
 			if err != nil {
-				return fmt.Errorf("错误: %v", err)
+				return nil, fmt.Errorf("错误: %v", err)
 			}
 		}
 	}
 
-	// 设置后定位词为".年份."
-	backPositionWord := fmt.Sprintf(".%s.", year)
-
-	fmt.Printf("\n=== %s 各季重命名正则表达式 ===\n", show.Name)
+	fmt.Fprintf(out, "\n=== %s 各季重命名正则表达式 ===\n", show.Name)
 
 	// 为每一季生成替换规则
 	for _, season := range show.Seasons {
@@ -411,20 +422,20 @@ func handleTVShow(tmdbService *services.TMDBService) error {
 
 		// 显示季数信息（为第0季添加特别说明）
 		if season.SeasonNumber == 0 {
-			fmt.Printf("\n--- 特别篇 ---\n")
+			fmt.Fprintf(out, "\n--- 特别篇 ---\n")
 		} else {
-			fmt.Printf("\n--- 第 %d 季 ---\n", season.SeasonNumber)
+			fmt.Fprintf(out, "\n--- 第 %d 季 ---\n", season.SeasonNumber)
 		}
 
 		// 获取该季的详细信息
 		seasonDetails, err := tmdbService.FetchSeasonDetails(seriesID, season.SeasonNumber)
 		if err != nil {
-			fmt.Printf("获取第 %d 季信息失败: %v\n", season.SeasonNumber, err)
+			fmt.Fprintf(out, "获取第 %d 季信息失败: %v\n", season.SeasonNumber, err)
 			continue
 		}
 
 		if len(seasonDetails.Episodes) == 0 {
-			fmt.Printf("第 %d 季没有找到任何剧集\n", season.SeasonNumber)
+			fmt.Fprintf(out, "第 %d 季没有找到任何剧集\n", season.SeasonNumber)
 			continue
 		}
 
@@ -434,44 +445,176 @@ func handleTVShow(tmdbService *services.TMDBService) error {
 
 		if isDateMode {
 			// 日期模式：为每一集生成替换规则
-			fmt.Printf("\n=== 第 %d 季 - 日期模式 ===\n", season.SeasonNumber)
+			fmt.Fprintf(out, "\n=== 第 %d 季 - 日期模式 ===\n", season.SeasonNumber)
+
+			// 检查容差窗口是否导致不同集数的日期重叠
+			airDates := make(map[int]string)
+			for _, episode := range seasonDetails.Episodes {
+				if episode.AirDate != "" {
+					airDates[episode.EpisodeNumber] = episode.AirDate
+				}
+			}
+			for _, overlap := range utils.FindDateOverlaps(airDates, dateTolerance) {
+				fmt.Fprintf(out, "警告：第%d集（%s）与第%d集（%s）的播出日期在±%d天容差内重叠，可能匹配到错误的集数\n",
+					overlap.FirstEpisode, overlap.FirstAirDate, overlap.SecondEpisode, overlap.SecondAirDate, dateTolerance)
+			}
+
+			// 检查同一播出日期是否有多集（如双集首播），有则询问区分方式
+			sameDateEpisodes := utils.FindSameDateEpisodes(airDates)
+			var sameDateStrategy utils.SameDateStrategy
+			if len(sameDateEpisodes) > 0 {
+				var collisionDates []string
+				for airDate := range sameDateEpisodes {
+					collisionDates = append(collisionDates, airDate)
+				}
+				sort.Strings(collisionDates)
+				fmt.Fprintln(out)
+				for _, airDate := range collisionDates {
+					fmt.Fprintf(out, "注意：播出日期 %s 有多集：%v\n", airDate, sameDateEpisodes[airDate])
+				}
+
+				sameDateStrategy, err = utils.GetSameDateStrategy(season.SeasonNumber)
+				if err != nil {
+					return nil, fmt.Errorf("错误: %v", err)
+				}
+
+				// 集数不连续时合并会包含中间的其他集，这些日期改为按集数区分
+				if sameDateStrategy == utils.SameDateMerge {
+					for _, airDate := range collisionDates {
+						if episodes := sameDateEpisodes[airDate]; !utils.IsConsecutive(episodes) {
+							fmt.Fprintf(out, "注意：播出日期 %s 的集数 %v 不连续，不能合并为多集替换，改为按文件名中的集数区分\n", airDate, episodes)
+						}
+					}
+				}
+			}
 
 			for _, episode := range seasonDetails.Episodes {
 				// 只处理有播出日期的集数
 				if episode.AirDate == "" {
-					fmt.Printf("第%d集：未获取到播出日期，跳过\n", episode.EpisodeNumber)
+					fmt.Fprintf(out, "第%d集：未获取到播出日期，跳过\n", episode.EpisodeNumber)
+					continue
+				}
+
+				// 被替换词：标题+播出日期+后面所有字符；替换词：剧集名称.S季数.E集数.年份.{[tmdbid=ID;type=tv]}
+				note := models.UnitNote{
+					Mode:        models.NoteModeDate,
+					FileTitle:   fileTitle,
+					Season:      season.SeasonNumber,
+					Episode:     episode.EpisodeNumber,
+					AirDate:     episode.AirDate,
+					DateFormats: dateFormatNames,
+					Tolerance:   dateTolerance,
+				}
+
+				// 同日多集按所选方式区分
+				if episodes, exists := sameDateEpisodes[episode.AirDate]; exists {
+					strategy := sameDateStrategy
+					if strategy == utils.SameDateMerge && !utils.IsConsecutive(episodes) {
+						strategy = utils.SameDateByEpisode
+					}
+					switch strategy {
+					case utils.SameDateMerge:
+						// 只在同日的第一集生成合并规则，其余集数跳过
+						if episode.EpisodeNumber != episodes[0] {
+							continue
+						}
+						note.SameDate = "merge"
+						note.End = episodes[len(episodes)-1]
+						fmt.Fprintf(out, "\n播出日期 %s 的第%d-%d集合并为多集替换\n",
+							episode.AirDate, episodes[0], episodes[len(episodes)-1])
+					case utils.SameDateByPart:
+						note.SameDate = "part"
+						note.Part = sort.SearchInts(episodes, episode.EpisodeNumber) + 1
+						fmt.Fprintf(out, "\n第%d集与同日其他集数按part%d区分\n", episode.EpisodeNumber, note.Part)
+					case utils.SameDateByEpisode:
+						note.SameDate = "episode"
+						fmt.Fprintf(out, "\n第%d集与同日其他集数按集数提示区分\n", episode.EpisodeNumber)
+					}
+				}
+
+				// 按所选格式和容差生成播出日期的匹配模式
+				rule, err := buildRule(naming, note)
+				if err != nil {
+					fmt.Fprintf(out, "第%d集：%v，跳过\n", episode.EpisodeNumber, err)
 					continue
 				}
 
-				// 格式化播出日期为YYYYMMDD
-				airDate := strings.ReplaceAll(episode.AirDate, "-", "")
+				fmt.Fprintf(out, "\n第%d集 (播出日期: %s):\n", episode.EpisodeNumber, episode.AirDate)
+				fmt.Fprintf(out, "被替换词：\n%s\n", rule.Unit.BeReplaced)
+				fmt.Fprintf(out, "替换词：\n%s\n", rule.Unit.Replace)
 
-				// 构建被替换词：标题+播出日期+后面所有字符
-				beReplaced := fmt.Sprintf("%s.*%s.*", regexp.QuoteMeta(fileTitle), airDate)
+				// 收集替换规则，生成完成后统一上传
+				rules = append(rules, rule)
+			}
+			continue // 跳过原有的集数范围处理逻辑
+		}
 
-				// 构建替换词：剧集名称.S季数.E集数.年份.{[tmdbid=ID;type=tv]}
-				replace := fmt.Sprintf("%s.S%02dE%02d.%s.{[tmdbid=%s;type=%s]}",
-					showName, season.SeasonNumber, episode.EpisodeNumber, year, seriesID, showType)
+		// 多集模式处理
+		if isMultiEpisode {
+			fmt.Fprintf(out, "\n=== 第 %d 季 - 多集模式 ===\n", season.SeasonNumber)
 
-				fmt.Printf("\n第%d集 (播出日期: %s):\n", episode.EpisodeNumber, episode.AirDate)
-				fmt.Printf("被替换词：\n%s\n", beReplaced)
-				fmt.Printf("替换词：\n%s\n", replace)
+			// 记录该季在TMDB中实际存在的集数，用于校验区间
+			existingEpisodes := make(map[int]bool)
+			for _, episode := range seasonDetails.Episodes {
+				existingEpisodes[episode.EpisodeNumber] = true
+			}
 
-				// 上传替换规则
-				if utils.IsUploadEnabled() {
-					err = wordGroupService.AddWordUnit(wordGroup.ID, beReplaced, replace, "", "", 0)
-					if err != nil {
-						return fmt.Errorf("上传第 %d 季第 %d 集替换规则失败: %v", season.SeasonNumber, episode.EpisodeNumber, err)
+			// 未指定区间时，按TMDB集数列表每2集自动分组
+			ranges := multiEpisodeRanges
+			if len(ranges) == 0 {
+				for i := 0; i+1 < len(seasonDetails.Episodes); i += 2 {
+					ranges = append(ranges, utils.EpisodeRange{
+						Start: seasonDetails.Episodes[i].EpisodeNumber - episodeOffset,
+						End:   seasonDetails.Episodes[i+1].EpisodeNumber - episodeOffset,
+					})
+				}
+			}
+
+			for _, episodeRange := range ranges {
+				// 偏移量同时作用于区间两端：原文件集数 + 偏移量 = TMDB集数
+				actualStart := episodeRange.Start + episodeOffset
+				actualEnd := episodeRange.End + episodeOffset
+
+				// 校验区间内每一集都存在于TMDB集数列表中
+				var missingEpisodes []int
+				for episodeNum := actualStart; episodeNum <= actualEnd; episodeNum++ {
+					if !existingEpisodes[episodeNum] {
+						missingEpisodes = append(missingEpisodes, episodeNum)
 					}
-					fmt.Printf("第 %d 季第 %d 集替换规则上传成功\n", season.SeasonNumber, episode.EpisodeNumber)
 				}
+				if len(missingEpisodes) > 0 {
+					fmt.Fprintf(out, "\n区间 %d-%d（实际集数：%d-%d）中的第%v集不在第 %d 季中，跳过\n",
+						episodeRange.Start, episodeRange.End, actualStart, actualEnd, missingEpisodes, season.SeasonNumber)
+					continue
+				}
+
+				// 被替换词：标题+季数+集数区间；替换词：剧集名称.S季数E起始集数-E结束集数.年份.{[tmdbid=ID;type=tv]}
+				rule, err := buildRule(naming, models.UnitNote{
+					Mode:      models.NoteModeMulti,
+					FileTitle: fileTitle,
+					Season:    season.SeasonNumber,
+					HasSeason: hasSeason,
+					Start:     episodeRange.Start,
+					End:       episodeRange.End,
+					Offset:    episodeOffset,
+				})
+				if err != nil {
+					return nil, err
+				}
+
+				fmt.Fprintf(out, "\n区间 %d-%d（实际集数：%d-%d）:\n", episodeRange.Start, episodeRange.End, actualStart, actualEnd)
+				fmt.Fprintf(out, "被替换词：\n%s\n", rule.Unit.BeReplaced)
+				fmt.Fprintf(out, "替换词：\n%s\n", rule.Unit.Replace)
+
+				// 收集替换规则，生成完成后统一上传
+				rules = append(rules, rule)
 			}
 			continue // 跳过原有的集数范围处理逻辑
 		}
 
 		// Part模式处理
 		if hasPartEpisodes {
-			fmt.Printf("\n=== 第 %d 季 - Part模式 ===\n", season.SeasonNumber)
+			fmt.Fprintf(out, "\n=== 第 %d 季 - Part模式 ===\n", season.SeasonNumber)
 
 			// 计算需要的位数
 			var digits int
@@ -525,7 +668,7 @@ func handleTVShow(tmdbService *services.TMDBService) error {
 					}
 
 					// 调试信息
-					fmt.Printf("调试 - 第%d集part%d: 前面part总数=%d, 最终偏移量=%d\n",
+					fmt.Fprintf(out, "调试 - 第%d集part%d: 前面part总数=%d, 最终偏移量=%d\n",
 						episodeNum, partNum, offset, offset)
 
 					// 计算实际集数（原集数 + 偏移量）
@@ -538,35 +681,27 @@ func handleTVShow(tmdbService *services.TMDBService) error {
 						digits = 2 // 确保至少使用2位数
 					}
 
-					beReplaced := fmt.Sprintf("%s.*?(?:S%02d)?(?:E|Ep|EP|[Ee]pisode|[Ee]p)?%0*d.*?(?:[Pp]art|PART|Part)%d.*",
-						regexp.QuoteMeta(fileTitle), season.SeasonNumber, digits, episodeNum, partNum)
-
-					// 构建替换词：使用原集数，而不是偏移后的集数，集数补0
-					replace := fmt.Sprintf("%s.S%02dE%0*d.%s.{[tmdbid=%s;type=%s]}",
-						showName, season.SeasonNumber, digits, episodeNum, year, seriesID, showType)
+					// 替换词使用原集数，而不是偏移后的集数，集数补0；有偏移量时设置前后定位词
+					rule, err := buildRule(naming, models.UnitNote{
+						Mode:      models.NoteModePart,
+						FileTitle: fileTitle,
+						Season:    season.SeasonNumber,
+						Episode:   episodeNum,
+						Part:      partNum,
+						Offset:    offset,
+						Digits:    digits,
+					})
+					if err != nil {
+						return nil, err
+					}
 
-					fmt.Printf("\n第%d集 part%d (偏移量:+%d, 实际集数:%d):\n",
+					fmt.Fprintf(out, "\n第%d集 part%d (偏移量:+%d, 实际集数:%d):\n",
 						episodeNum, partNum, offset, actualEpisodeNum)
-					fmt.Printf("被替换词：\n%s\n", beReplaced)
-					fmt.Printf("替换词：\n%s\n", replace)
-
-					// 构建前定位词和后定位词（如果有偏移量）
-					var prefix, suffix string
-					if offset > 0 {
-						prefix = fmt.Sprintf("%s.S%02dE", showName, season.SeasonNumber)
-						suffix = backPositionWord
-					}
+					fmt.Fprintf(out, "被替换词：\n%s\n", rule.Unit.BeReplaced)
+					fmt.Fprintf(out, "替换词：\n%s\n", rule.Unit.Replace)
 
-					// 上传替换规则
-					if utils.IsUploadEnabled() {
-						err = wordGroupService.AddWordUnit(wordGroup.ID, beReplaced, replace, prefix, suffix, offset)
-						if err != nil {
-							return fmt.Errorf("上传第 %d 季第 %d 集part%d替换规则失败: %v",
-								season.SeasonNumber, episodeNum, partNum, err)
-						}
-						fmt.Printf("第 %d 季第 %d 集part%d替换规则上传成功\n",
-							season.SeasonNumber, episodeNum, partNum)
-					}
+					// 收集替换规则，生成完成后统一上传
+					rules = append(rules, rule)
 				}
 			}
 
@@ -649,37 +784,27 @@ func handleTVShow(tmdbService *services.TMDBService) error {
 
 				// 如果区间有效，生成替换规则
 				if startEp <= endEp && endEp <= seasonDetails.Episodes[len(seasonDetails.Episodes)-1].EpisodeNumber {
-					// 构建被替换词：匹配区间内的集数
-					beReplaced := fmt.Sprintf("%s.*?(?:S%02d)?(?:E|Ep|EP|[Ee]pisode|[Ee]p)?(%s)(?!.*(?:[Pp]art|PART|Part))",
-						regexp.QuoteMeta(fileTitle), season.SeasonNumber,
-						utils.GenerateRangePattern(startEp, endEp, digits))
-
-					// 构建替换词：使用捕获组和偏移量
-					replace := fmt.Sprintf("%s.S%02dE\\1.%s.{[tmdbid=%s;type=%s]}",
-						showName, season.SeasonNumber, year, seriesID, showType)
-
-					fmt.Printf("\n区间 %d-%d 非part集数规则 (偏移量:+%d):\n", startEp, endEp, offset)
-					fmt.Printf("被替换词：\n%s\n", beReplaced)
-					fmt.Printf("替换词：\n%s\n", replace)
-					fmt.Printf("说明：区间内集数的实际集数 = 原集数 + %d\n", offset)
-
-					// 构建前定位词和后定位词（如果有偏移量）
-					var prefix, suffix string
-					if offset > 0 {
-						prefix = fmt.Sprintf("%s.S%02dE", showName, season.SeasonNumber)
-						suffix = backPositionWord
+					// 被替换词匹配区间内的集数，替换词使用捕获组和偏移量；有偏移量时设置前后定位词
+					rule, err := buildRule(naming, models.UnitNote{
+						Mode:      models.NoteModePartRange,
+						FileTitle: fileTitle,
+						Season:    season.SeasonNumber,
+						Start:     startEp,
+						End:       endEp,
+						Offset:    offset,
+						Digits:    digits,
+					})
+					if err != nil {
+						return nil, err
 					}
 
-					// 上传替换规则
-					if utils.IsUploadEnabled() {
-						err = wordGroupService.AddWordUnit(wordGroup.ID, beReplaced, replace, prefix, suffix, offset)
-						if err != nil {
-							return fmt.Errorf("上传第 %d 季区间 %d-%d 非part集数替换规则失败: %v",
-								season.SeasonNumber, startEp, endEp, err)
-						}
-						fmt.Printf("第 %d 季区间 %d-%d 非part集数替换规则上传成功\n",
-							season.SeasonNumber, startEp, endEp)
-					}
+					fmt.Fprintf(out, "\n区间 %d-%d 非part集数规则 (偏移量:+%d):\n", startEp, endEp, offset)
+					fmt.Fprintf(out, "被替换词：\n%s\n", rule.Unit.BeReplaced)
+					fmt.Fprintf(out, "替换词：\n%s\n", rule.Unit.Replace)
+					fmt.Fprintf(out, "说明：区间内集数的实际集数 = 原集数 + %d\n", offset)
+
+					// 收集替换规则，生成完成后统一上传
+					rules = append(rules, rule)
 				}
 			}
 
@@ -721,96 +846,256 @@ func handleTVShow(tmdbService *services.TMDBService) error {
 		// 显示集数范围和对应关系
 		if padZero {
 			if episodeContinuous {
-				fmt.Printf("集数范围：%d-%d（连续，使用%d位数）\n", sourceStartEp, sourceEndEp, digits)
+				fmt.Fprintf(out, "集数范围：%d-%d（连续，使用%d位数）\n", sourceStartEp, sourceEndEp, digits)
 			} else {
-				fmt.Printf("集数范围：%d-%d（不连续，使用%d位数）\n", sourceStartEp, sourceEndEp, digits)
+				fmt.Fprintf(out, "集数范围：%d-%d（不连续，使用%d位数）\n", sourceStartEp, sourceEndEp, digits)
 			}
 		} else {
-			fmt.Printf("集数范围：%d-%d（不补0）\n", sourceStartEp, sourceEndEp)
+			fmt.Fprintf(out, "集数范围：%d-%d（不补0）\n", sourceStartEp, sourceEndEp)
 		}
 		if episodeOffset != 0 {
-			fmt.Printf("集数偏移量：%+d\n", episodeOffset)
-			fmt.Printf("原始集数示例：%d → 实际集数：%d\n",
+			fmt.Fprintf(out, "集数偏移量：%+d\n", episodeOffset)
+			fmt.Fprintf(out, "原始集数示例：%d → 实际集数：%d\n",
 				sourceStartEp, startEp)
 		}
 
-		// 构建匹配范围的正则表达式
-		var beReplaced string
-		if hasSeason {
-			beReplaced = fmt.Sprintf("%s.*S%02d(?:E|Ep|EP|[Ee]pisode|[Ee]p)?(%s)",
-				regexp.QuoteMeta(fileTitle),
-				season.SeasonNumber,
-				utils.GenerateRangePattern(sourceStartEp, sourceEndEp, digits))
-		} else {
-			beReplaced = fmt.Sprintf("%s.*?(?:S\\d{2})?(?:E|Ep|EP|[Ee]pisode|[Ee]p)?(%s)",
-				regexp.QuoteMeta(fileTitle),
-				utils.GenerateRangePattern(sourceStartEp, sourceEndEp, digits))
-		}
-
-		// 生成替换词和前后定位词
-		replace := fmt.Sprintf("%s.S%02dE\\1.%s.{[tmdbid=%s;type=%s]}", showName, season.SeasonNumber, year, seriesID, showType)
-
-		// 只在有偏移量时设置前后定位词
-		var prefix, suffix string
-		if episodeOffset != 0 {
-			prefix = fmt.Sprintf("%s.S%02dE", showName, season.SeasonNumber)
-			suffix = backPositionWord
+		// 构建匹配范围的正则表达式、替换词和前后定位词
+		rule, err := buildRule(naming, models.UnitNote{
+			Mode:      models.NoteModeRange,
+			FileTitle: fileTitle,
+			Season:    season.SeasonNumber,
+			HasSeason: hasSeason,
+			Start:     sourceStartEp,
+			End:       sourceEndEp,
+			Offset:    episodeOffset,
+			Digits:    digits,
+		})
+		if err != nil {
+			return nil, err
 		}
 
-		fmt.Printf("\n被替换词：\n%s\n", beReplaced)
-		fmt.Printf("替换词：\n%s\n", replace)
+		fmt.Fprintf(out, "\n被替换词：\n%s\n", rule.Unit.BeReplaced)
+		fmt.Fprintf(out, "替换词：\n%s\n", rule.Unit.Replace)
 
 		// 只在有偏移量时显示前后定位词
 		if episodeOffset != 0 {
-			fmt.Printf("\n前定位词：\n%s\n", prefix)
-			fmt.Printf("后定位词：\n%s\n", suffix)
+			fmt.Fprintf(out, "\n前定位词：\n%s\n", rule.Unit.Front)
+			fmt.Fprintf(out, "后定位词：\n%s\n", rule.Unit.Back)
 		}
 
-		// 只在启用上传时上传替换规则
-		if utils.IsUploadEnabled() {
-			err = wordGroupService.AddWordUnit(wordGroup.ID, beReplaced, replace, prefix, suffix, episodeOffset)
-			if err != nil {
-				return fmt.Errorf("上传第 %d 季替换规则失败: %v", season.SeasonNumber, err)
-			}
-			fmt.Printf("第 %d 季替换规则上传成功\n", season.SeasonNumber)
-		}
+		// 收集替换规则，生成完成后统一上传
+		rules = append(rules, rule)
 	}
 
-	fmt.Println("\n注意：")
-	fmt.Println("1. 正则表达式中的点号（.）已经被转义")
-	fmt.Println("2. 替换词中的'\\1'表示保留原始集数")
-	fmt.Println("3. [^.]* 匹配除点号外的任意字符，用于处理标题和集数之间可能存在的额外字符")
-	fmt.Println("4. 替换后的文件名使用TMDB中的官方剧集名称")
+	fmt.Fprintln(out, "\n注意：")
+	fmt.Fprintln(out, "1. 正则表达式中的点号（.）已经被转义")
+	fmt.Fprintln(out, "2. 替换词中的'\\1'表示保留原始集数")
+	fmt.Fprintln(out, "3. [^.]* 匹配除点号外的任意字符，用于处理标题和集数之间可能存在的额外字符")
+	fmt.Fprintln(out, "4. 替换后的文件名使用TMDB中的官方剧集名称")
 	if isDateMode {
-		fmt.Println("5. 日期模式：使用播出日期匹配文件名，每集生成独立的替换规则")
-		fmt.Printf("6. 播出日期格式：YYYYMMDD（如：%s）\n", strings.ReplaceAll(show.FirstAirDate, "-", ""))
-		fmt.Println("7. 只处理有播出日期的集数，未获取到播出日期的集数将被跳过")
+		fmt.Fprintln(out, "5. 日期模式：使用播出日期匹配文件名，每集生成独立的替换规则")
+		if len(dateExamples) > 0 {
+			fmt.Fprintf(out, "6. 播出日期格式：%s（如：%s）\n", strings.Join(dateFormatNames, "、"), strings.Join(dateExamples, "、"))
+		} else {
+			fmt.Fprintf(out, "6. 播出日期格式：%s\n", strings.Join(dateFormatNames, "、"))
+		}
+		fmt.Fprintln(out, "7. 只处理有播出日期的集数，未获取到播出日期的集数将被跳过")
+		if dateTolerance > 0 {
+			fmt.Fprintf(out, "   播出日期容差：±%d天，容差范围内的日期均可匹配\n", dateTolerance)
+		}
+	} else if isMultiEpisode {
+		fmt.Fprintln(out, "5. 多集模式：每个集数区间生成独立的替换规则，兼容 E01-E02、E01E02、第1-2集 等写法")
+		fmt.Fprintln(out, "6. 偏移量同时作用于区间两端，超出TMDB集数列表的区间将被跳过")
 	} else {
-		fmt.Printf("5. 所有集数都使用相同的位数（由最大集数决定），不足位数补0\n")
-		fmt.Printf("   例如：如果最大集数是500（3位），则第1集应该写作001\n")
+		fmt.Fprintf(out, "5. 所有集数都使用相同的位数（由最大集数决定），不足位数补0\n")
+		fmt.Fprintf(out, "   例如：如果最大集数是500（3位），则第1集应该写作001\n")
 	}
 	if !hasSeason {
-		fmt.Printf("8. 原文件名不包含季数，仅匹配集数部分\n")
+		fmt.Fprintf(out, "8. 原文件名不包含季数，仅匹配集数部分\n")
 	}
 	if episodeOffset != 0 {
-		fmt.Printf("9. 被替换词中的集数范围已经过调整，可以直接匹配原文件名中的集数\n")
+		fmt.Fprintf(out, "9. 被替换词中的集数范围已经过调整，可以直接匹配原文件名中的集数\n")
+	}
+
+	// 不同季的规则不要求季数标记时，相同的集数会被多条规则匹配
+	rules, err = resolveSeasonOverlaps(tmdbService, out, seriesID, show, naming, rules)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ruleSet{Title: namingFormat, TMDBID: seriesID, MediaType: showType, FileTitle: fileTitle, Rules: rules}, nil
+}
+
+// savePresetFlag 全局参数 --save-preset 指定的预设名称，生成规则后将本次回答保存为该预设
+var savePresetFlag string
+
+// generateRuleSet 交互获取媒体类型并生成对应的替换规则，按全局参数保存预设，并创建本次的配方
+func generateRuleSet(tmdbService *services.TMDBService) (*ruleSet, error) {
+	set, mediaType, err := buildRuleSet(tmdbService, os.Stdout)
+	if err != nil {
+		return nil, err
+	}
+
+	if savePresetFlag != "" {
+		if err := savePreset(savePresetFlag); err != nil {
+			fmt.Printf("保存预设失败: %v\n", err)
+		}
 	}
 
-	return nil
+	// 配方名称由本次的输入决定，在上传前写入规则备注
+	set.Recipe = newRecipe(mediaType, set.FileTitle, set.TMDBID, set.Title)
+	if utils.IsRecipeNoteEnabled() {
+		if err := set.Recipe.annotate(set.Rules); err != nil {
+			return nil, err
+		}
+	}
+	return set, nil
+}
+
+// buildRuleSet 交互获取媒体类型并生成替换规则和屏蔽词规则，返回规则集和媒体类型选项
+func buildRuleSet(tmdbService *services.TMDBService, out io.Writer) (*ruleSet, string, error) {
+	// 获取媒体类型选择
+	fmt.Fprintln(out, "请选择媒体类型：")
+	fmt.Fprintln(out, "1. 电影")
+	fmt.Fprintln(out, "2. 剧集")
+	mediaType, err := askRecipeInput(out, "请输入选项（1或2）: ", (*recipe).mediaTypeOption)
+	if err != nil {
+		return nil, "", fmt.Errorf("错误: %v", err)
+	}
+
+	var set *ruleSet
+	switch mediaType {
+	case "1":
+		set, err = handleMovie(tmdbService, out)
+	case "2":
+		set, err = handleTVShow(tmdbService, out)
+	default:
+		return nil, "", fmt.Errorf("无效的选项，请输入1或2")
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	// 屏蔽词规则与替换规则放在同一词组，一次清理发布名中的广告标记
+	useBlockWords, err := utils.GetBlockWordsChoice()
+	if err != nil {
+		return nil, "", fmt.Errorf("错误: %v", err)
+	}
+	if useBlockWords {
+		// 屏蔽词规则与名称和年份无关，只在备注中记录TMDB标记
+		blockRules, err := handleBlockWords(out, mediaNaming{TMDBID: set.TMDBID, MediaType: set.MediaType})
+		if err != nil {
+			return nil, "", err
+		}
+		set.Rules = append(set.Rules, blockRules...)
+	}
+	return set, mediaType, nil
+}
+
+// runInteractive 交互模式：生成替换规则并在启用上传时上传
+func runInteractive(tmdbService *services.TMDBService) error {
+	set, err := generateRuleSet(tmdbService)
+	if err != nil {
+		return err
+	}
+	// 上传失败时也保存配方，便于修复问题后重放
+	uploadErr := uploadRules(set)
+	saveRecipe(set.Recipe)
+	return uploadErr
+}
+
+// printUsage 显示命令用法
+func printUsage() {
+	fmt.Println("用法：rename-by-tmdb [--server 名称] [命令]")
+	fmt.Println("  rename-by-tmdb                     交互生成替换规则（UPLOAD_MS=true 时上传）")
+	fmt.Println("  rename-by-tmdb plan [-o 文件]      交互生成替换规则，与服务器当前状态比较后写入计划文件")
+	fmt.Println("  rename-by-tmdb wizard              全屏向导生成替换规则：逐项校验、可返回修改、逐步预览，确认后生成和上传")
+	fmt.Println("  rename-by-tmdb apply [-f 文件]     执行计划文件，服务器状态与规划时不一致时拒绝执行")
+	fmt.Println("  rename-by-tmdb undo [运行ID]       删除指定运行创建的规则和词组，不指定时列出最近的运行")
+	fmt.Println("  rename-by-tmdb drift [-n] [-y]     检查词组标题与TMDB当前名称、年份是否一致，并修改标题和替换词")
+	fmt.Println("  rename-by-tmdb regenerate [-n] [-y] 按规则备注中的生成参数，用当前生成器重新生成规则（-group 指定词组ID）")
+	fmt.Println("  rename-by-tmdb lint [-same]        检查服务器上不同词组之间重叠或被遮蔽的规则、无效正则和禁用的重复规则")
+	fmt.Println("  rename-by-tmdb export [-o 文件]    导出词组及其替换规则（-group 指定词组ID，逗号分隔）")
+	fmt.Println("  rename-by-tmdb import [-f 文件]    导入词组（-strategy skip|merge|replace）")
+	fmt.Println("  rename-by-tmdb login               使用用户名和密码登录服务器，令牌保存到本地")
+	fmt.Println("  rename-by-tmdb config show         显示各配置项的值和来源（密钥已隐藏）")
+	fmt.Println("  rename-by-tmdb config set 键 值    修改配置文件中的配置项，值为空时删除")
+	fmt.Println("  rename-by-tmdb config presets      列出剧集交互的预设及其回答")
+	fmt.Println("")
+	fmt.Println("  --server 名称                      选择服务器（MS_SERVERS 中的名称或 default），多个用逗号分隔，all 表示所有服务器")
+	fmt.Println("                                     只有上传支持同时选择多个服务器")
+	fmt.Println("  --config 文件                      使用指定的配置文件，默认为用户配置目录下的 rename-by-tmdb/config.yaml")
+	fmt.Println("  --set 键=值                        覆盖配置项（如 --set tmdb_api.language=en-US），可出现多次")
+	fmt.Println("  --preset 名称                      使用预设预填剧集交互的回答（内置 anime、variety、western），直接回车使用预设值")
+	fmt.Println("  --save-preset 名称                 生成规则后将本次的回答保存为配置文件中的预设")
+	fmt.Println("  --recipe 配方                      重放配方（文件路径或配方目录下的名称），不再提问；每次生成都会保存配方")
 }
 
 func main() {
-	// 加载环境变量
-	if err := utils.LoadEnv(); err != nil {
+	// 取出全局参数 --server、--config、--set、--preset、--save-preset 和 --recipe，再按子命令分发，未指定子命令时进入交互模式
+	args, server, err := extractServerFlag(os.Args[1:])
+	if err != nil {
+		fmt.Printf("错误: %v\n", err)
+		return
+	}
+	serverFlag = server
+	args, flags, err := extractConfigFlags(args)
+	if err != nil {
+		fmt.Printf("错误: %v\n", err)
+		return
+	}
+
+	// 加载环境变量和配置文件，配置文件存在时可以不使用 .env 文件
+	// config 命令用于写入必需的配置项，.env 和配置文件都不存在时也可以运行
+	isConfigCommand := len(args) > 0 && args[0] == "config"
+	loadEnv := utils.LoadEnv
+	if isConfigCommand {
+		loadEnv = func() error {
+			utils.LoadEnvQuiet()
+			return nil
+		}
+	}
+	configPath, configSettings, err = config.Init(flags.Path, flags.Overrides, loadEnv)
+	if err != nil {
 		fmt.Printf("错误: %v\n", err)
-		fmt.Println("\n请确保 .env 文件存在并包含必要的环境变量")
 		return
 	}
 
+	if isConfigCommand {
+		if err := runConfig(args[1:]); err != nil {
+			fmt.Printf("%v\n", err)
+		}
+		return
+	}
+
+	if flags.Recipe != "" && flags.Preset != "" {
+		fmt.Println("错误: --recipe 和 --preset 不能同时使用")
+		return
+	}
+	// wizard 命令从配方开始逐步修改，不重放
+	isWizardCommand := len(args) > 0 && args[0] == "wizard"
+	if flags.Recipe != "" && len(args) > 0 && args[0] != "plan" && !isWizardCommand {
+		fmt.Println("错误: --recipe 只能用于交互生成规则、plan 或 wizard 命令")
+		return
+	}
+	if flags.Recipe != "" && !isWizardCommand {
+		if err := startReplay(flags.Recipe); err != nil {
+			fmt.Printf("错误: %v\n", err)
+			return
+		}
+	}
+	if flags.Preset != "" {
+		if err := loadPreset(flags.Preset); err != nil {
+			fmt.Printf("错误: %v\n", err)
+			return
+		}
+	}
+	savePresetFlag = flags.SavePreset
+
 	// 检查环境变量
 	if err := utils.CheckRequiredEnvVars(); err != nil {
 		fmt.Printf("错误: %v\n", err)
-		fmt.Println("\n请在 .env 文件中设置以下环境变量：")
+		fmt.Println("\n请在 .env 文件中设置以下环境变量，或使用 config set 写入配置文件：")
 		fmt.Println("TMDB_API_KEY='your_tmdb_api_key'")
 		return
 	}
@@ -822,29 +1107,46 @@ func main() {
 		return
 	}
 
-	// 获取媒体类型选择
-	fmt.Println("请选择媒体类型：")
-	fmt.Println("1. 电影")
-	fmt.Println("2. 剧集")
-	mediaType, err := utils.GetUserInput("请输入选项（1或2）: ")
-	if err != nil {
-		fmt.Printf("错误: %v\n", err)
-		return
+	command := ""
+	if len(args) > 0 {
+		command = args[0]
+		args = args[1:]
 	}
 
-	var handleErr error
-	switch mediaType {
-	case "1":
-		handleErr = handleMovie(tmdbService)
-	case "2":
-		handleErr = handleTVShow(tmdbService)
+	var runErr error
+	switch command {
+	case "":
+		runErr = runInteractive(tmdbService)
+	case "plan":
+		runErr = runPlan(tmdbService, args)
+	case "wizard":
+		runErr = runWizard(tmdbService, args, flags.Recipe)
+	case "apply":
+		runErr = runApply(args)
+	case "undo":
+		runErr = runUndo(args)
+	case "drift":
+		runErr = runDrift(tmdbService, args)
+	case "regenerate":
+		runErr = runRegenerate(args)
+	case "lint":
+		runErr = runLint(args)
+	case "export":
+		runErr = runExport(args)
+	case "import":
+		runErr = runImport(args)
+	case "login":
+		runErr = runLogin()
 	default:
-		fmt.Println("无效的选项，请输入1或2")
+		printUsage()
 		return
 	}
 
-	if handleErr != nil {
-		fmt.Printf("%v\n", handleErr)
+	if runErr != nil {
+		fmt.Printf("%v\n", runErr)
+		return
+	}
+	if command != "" || activeRecipe != nil {
 		return
 	}
 
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/harry/rename-by-tmdb/internal/models"
+	"github.com/harry/rename-by-tmdb/internal/services"
+	"github.com/harry/rename-by-tmdb/internal/utils"
+)
+
+// seasonOverlap 表示同一词组中不同季的两条规则能匹配相同的原文件集数
+type seasonOverlap struct {
+	First  generatedRule
+	Second generatedRule
+	Start  int // 两条规则都能匹配的原文件集数区间
+	End    int
+}
+
+// episodeCoverage 返回规则匹配的原文件集数区间，不按集数匹配的规则返回 false
+func episodeCoverage(note *models.UnitNote) (int, int, bool) {
+	switch note.Mode {
+	case models.NoteModeRange, models.NoteModePartRange, models.NoteModeMulti:
+		return note.Start, note.End, true
+	case models.NoteModePart:
+		return note.Episode, note.Episode, true
+	}
+	return 0, 0, false
+}
+
+// coverageComparable 判断两条规则是否可能匹配同一个文件：只比较不同季的规则，part规则只与相同part的规则比较，
+// 两条规则都要求季数标记时不会重叠
+func coverageComparable(a, b *models.UnitNote) bool {
+	if a.FileTitle != b.FileTitle || a.Season == b.Season {
+		return false
+	}
+	if (a.Mode == models.NoteModePart) != (b.Mode == models.NoteModePart) {
+		return false
+	}
+	if a.Mode == models.NoteModePart && a.Part != b.Part {
+		return false
+	}
+	return !a.HasSeason || !b.HasSeason
+}
+
+// findSeasonOverlaps 按规则备注中的生成参数查找不同季之间原文件集数区间重叠的规则
+func findSeasonOverlaps(rules []generatedRule) []seasonOverlap {
+	notes := make([]*models.UnitNote, len(rules))
+	for i, rule := range rules {
+		notes[i], _ = models.ParseUnitNote(rule.Unit.Note)
+	}
+
+	var overlaps []seasonOverlap
+	for i := range rules {
+		if notes[i] == nil {
+			continue
+		}
+		firstStart, firstEnd, ok := episodeCoverage(notes[i])
+		if !ok {
+			continue
+		}
+		for j := i + 1; j < len(rules); j++ {
+			if notes[j] == nil || !coverageComparable(notes[i], notes[j]) {
+				continue
+			}
+			secondStart, secondEnd, ok := episodeCoverage(notes[j])
+			if !ok {
+				continue
+			}
+			start, end := max(firstStart, secondStart), min(firstEnd, secondEnd)
+			if start > end {
+				continue
+			}
+			overlaps = append(overlaps, seasonOverlap{First: rules[i], Second: rules[j], Start: start, End: end})
+		}
+	}
+	return overlaps
+}
+
+// printSeasonOverlaps 显示不同季之间重叠的规则
+func printSeasonOverlaps(out io.Writer, overlaps []seasonOverlap) {
+	for _, overlap := range overlaps {
+		if overlap.Start == overlap.End {
+			fmt.Fprintf(out, "%s 与 %s：原文件第%d集两条规则都能匹配\n", overlap.First.Label, overlap.Second.Label, overlap.Start)
+		} else {
+			fmt.Fprintf(out, "%s 与 %s：原文件第%d-%d集两条规则都能匹配\n", overlap.First.Label, overlap.Second.Label, overlap.Start, overlap.End)
+		}
+	}
+}
+
+// rebuildRules 按修改后的生成参数重新生成规则，update 返回 false 的规则保持不变
+func rebuildRules(naming mediaNaming, rules []generatedRule, update func(note *models.UnitNote) bool) ([]generatedRule, error) {
+	rebuilt := make([]generatedRule, 0, len(rules))
+	for _, rule := range rules {
+		note, err := models.ParseUnitNote(rule.Unit.Note)
+		if err != nil {
+			return nil, err
+		}
+		if note == nil || !update(note) {
+			rebuilt = append(rebuilt, rule)
+			continue
+		}
+		rule, err = buildRule(naming, *note)
+		if err != nil {
+			return nil, err
+		}
+		rebuilt = append(rebuilt, rule)
+	}
+	return rebuilt, nil
+}
+
+// requireSeasonToken 所有按集数匹配的规则改为要求文件名包含季数标记
+func requireSeasonToken(naming mediaNaming, rules []generatedRule) ([]generatedRule, error) {
+	return rebuildRules(naming, rules, func(note *models.UnitNote) bool {
+		if _, _, ok := episodeCoverage(note); !ok || note.HasSeason {
+			return false
+		}
+		note.HasSeason = true
+		return true
+	})
+}
+
+// seasonEpisodeCounts 获取各季（不含第0季）的集数，用于计算绝对集数
+func seasonEpisodeCounts(tmdbService *services.TMDBService, seriesID string, show *models.TMDBShow, lastSeason int) (map[int]int, error) {
+	counts := make(map[int]int)
+	for _, season := range show.Seasons {
+		if season.SeasonNumber <= 0 || season.SeasonNumber >= lastSeason {
+			continue
+		}
+		details, err := tmdbService.FetchSeasonDetails(seriesID, season.SeasonNumber)
+		if err != nil {
+			return nil, fmt.Errorf("获取第 %d 季信息失败: %v", season.SeasonNumber, err)
+		}
+		counts[season.SeasonNumber] = len(details.Episodes)
+	}
+	return counts, nil
+}
+
+// absoluteNumbering 区间和多集规则改为按绝对集数匹配：原文件集数加上之前各季的总集数，偏移量减去同样的集数，
+// 替换后仍得到该季的集数；part模式的规则保持不变
+func absoluteNumbering(tmdbService *services.TMDBService, out io.Writer, seriesID string, show *models.TMDBShow, naming mediaNaming, rules []generatedRule) ([]generatedRule, error) {
+	lastSeason := 0
+	for _, rule := range rules {
+		if note, _ := models.ParseUnitNote(rule.Unit.Note); note != nil && note.Season > lastSeason {
+			lastSeason = note.Season
+		}
+	}
+	counts, err := seasonEpisodeCounts(tmdbService, seriesID, show, lastSeason)
+	if err != nil {
+		return nil, err
+	}
+
+	skipped := 0
+	rebuilt, err := rebuildRules(naming, rules, func(note *models.UnitNote) bool {
+		if note.Mode == models.NoteModePart || note.Mode == models.NoteModePartRange {
+			skipped++
+			return false
+		}
+		if (note.Mode != models.NoteModeRange && note.Mode != models.NoteModeMulti) || note.Season <= 1 {
+			return false
+		}
+
+		previous := 0
+		for season, count := range counts {
+			if season < note.Season {
+				previous += count
+			}
+		}
+		note.Start += previous
+		note.End += previous
+		note.Offset -= previous
+		// 补0时按绝对集数的最大值确定位数
+		if note.Digits > 1 && len(strconv.Itoa(note.End)) > note.Digits {
+			note.Digits = len(strconv.Itoa(note.End))
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	if skipped > 0 {
+		fmt.Fprintf(out, "注意：%d 条part模式的规则不支持绝对集数，保持不变\n", skipped)
+	}
+	return rebuilt, nil
+}
+
+// resolveSeasonOverlaps 检查不同季之间集数区间重叠的规则，有重叠时由用户选择处理方式并重新生成规则
+func resolveSeasonOverlaps(tmdbService *services.TMDBService, out io.Writer, seriesID string, show *models.TMDBShow, naming mediaNaming, rules []generatedRule) ([]generatedRule, error) {
+	overlaps := findSeasonOverlaps(rules)
+	if len(overlaps) == 0 {
+		return rules, nil
+	}
+
+	fmt.Fprintf(out, "\n=== 不同季的规则集数重叠（%d 处）===\n", len(overlaps))
+	printSeasonOverlaps(out, overlaps)
+	fmt.Fprintln(out, "文件名不包含季数时，这些文件只会按其中一条规则重命名，其他季的文件会得到错误的季数")
+
+	fix, err := utils.GetSeasonOverlapFix()
+	if err != nil {
+		return nil, fmt.Errorf("错误: %v", err)
+	}
+
+	var fixed []generatedRule
+	switch fix {
+	case utils.SeasonOverlapKeep:
+		return rules, nil
+	case utils.SeasonOverlapRequireSeason:
+		fixed, err = requireSeasonToken(naming, rules)
+	case utils.SeasonOverlapAbsolute:
+		fixed, err = absoluteNumbering(tmdbService, out, seriesID, show, naming, rules)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("重新生成规则失败: %v", err)
+	}
+
+	fmt.Fprintf(out, "\n=== 调整后的替换规则 ===\n")
+	for i, rule := range fixed {
+		if rule.Unit == rules[i].Unit {
+			continue
+		}
+		fmt.Fprintf(out, "\n%s:\n", rule.Label)
+		fmt.Fprintf(out, "被替换词：\n%s\n", rule.Unit.BeReplaced)
+		fmt.Fprintf(out, "替换词：\n%s\n", rule.Unit.Replace)
+		if rule.Unit.Offset != "" {
+			fmt.Fprintf(out, "偏移量：%s\n", rule.Unit.Offset)
+		}
+	}
+
+	remaining := findSeasonOverlaps(fixed)
+	if len(remaining) == 0 {
+		fmt.Fprintln(out, "\n不同季的规则已不再重叠")
+		return fixed, nil
+	}
+	fmt.Fprintf(out, "\n仍有 %d 处重叠：\n", len(remaining))
+	printSeasonOverlaps(out, remaining)
+	return fixed, nil
+}
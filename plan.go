@@ -0,0 +1,267 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/harry/rename-by-tmdb/internal/models"
+	"github.com/harry/rename-by-tmdb/internal/services"
+	"github.com/harry/rename-by-tmdb/internal/utils"
+)
+
+// buildGroupPlan 比较生成的规则与服务器当前状态，生成词组的变更计划
+func buildGroupPlan(wordGroupService *services.WordGroupService, set *ruleSet) (*models.GroupPlan, error) {
+	wordGroup, err := findExistingWordGroup(wordGroupService, set.Title)
+	if err != nil {
+		return nil, err
+	}
+
+	// 词组不存在：新建词组并添加所有规则
+	if wordGroup == nil {
+		return &models.GroupPlan{
+			Title:  set.Title,
+			Action: models.GroupActionCreate,
+			Create: set.units(0),
+		}, nil
+	}
+
+	existing, err := wordGroupService.ListWordUnits(wordGroup.ID)
+	if err != nil {
+		return nil, fmt.Errorf("获取词组现有规则失败: %v", err)
+	}
+
+	adoption := newUnitAdoption()
+	syncPlan := services.DiffWordUnitsWithKey(existing, set.units(wordGroup.ID), adoption.key)
+	groupPlan := &models.GroupPlan{
+		Title:        set.Title,
+		Action:       models.GroupActionUpdate,
+		GroupID:      wordGroup.ID,
+		Fingerprint:  services.FingerprintWordUnits(existing),
+		Create:       syncPlan.Create,
+		Update:       syncPlan.Update,
+		Unrecognized: adoption.unrecognized,
+	}
+
+	if len(syncPlan.Obsolete) > 0 {
+		fmt.Printf("\n词组中有 %d 条本次未生成的过期规则\n", len(syncPlan.Obsolete))
+		if len(adoption.unrecognized) > 0 {
+			fmt.Printf("其中 %d 条没有生成参数且无法识别（如手动编写或修改过的规则）：\n", len(adoption.unrecognized))
+			printUnrecognized(adoption.unrecognized, "  ")
+		}
+		deleteObsolete, err := utils.GetDeleteObsoleteChoice(wordGroupService.Name())
+		if err != nil {
+			return nil, fmt.Errorf("错误: %v", err)
+		}
+		for _, unit := range syncPlan.Obsolete {
+			if deleteObsolete {
+				groupPlan.Delete = append(groupPlan.Delete, unit)
+			} else if unit.Enabled {
+				unit.Enabled = false
+				groupPlan.Disable = append(groupPlan.Disable, unit)
+			}
+		}
+	}
+
+	return groupPlan, nil
+}
+
+// printPlan 显示计划内容
+func printPlan(plan *models.Plan) {
+	fmt.Printf("\n=== 变更计划（服务器：%s）===\n", plan.Server)
+	for _, group := range plan.Groups {
+		if group.Action == models.GroupActionCreate {
+			fmt.Printf("\n新建词组：%s\n", group.Title)
+		} else {
+			fmt.Printf("\n修改词组：%s（ID: %d）\n", group.Title, group.GroupID)
+		}
+		for _, unit := range group.Create {
+			fmt.Printf("  + 新增：%s → %s\n", unit.BeReplaced, unit.Replace)
+		}
+		for _, unit := range group.Update {
+			fmt.Printf("  ~ 更新：%s → %s\n", unit.BeReplaced, unit.Replace)
+		}
+		for _, unit := range group.Disable {
+			fmt.Printf("  ! 禁用：%s → %s\n", unit.BeReplaced, unit.Replace)
+		}
+		for _, unit := range group.Delete {
+			fmt.Printf("  - 删除：%s → %s\n", unit.BeReplaced, unit.Replace)
+		}
+		printUnrecognized(group.Unrecognized, "  ")
+		if group.IsEmpty() {
+			fmt.Println("  无变化")
+		}
+		fmt.Printf("  新增 %d 条，更新 %d 条，禁用 %d 条，删除 %d 条，无法识别 %d 条\n",
+			len(group.Create), len(group.Update), len(group.Disable), len(group.Delete), len(group.Unrecognized))
+	}
+}
+
+// runPlan plan 命令：交互生成规则，获取服务器当前状态，并将变更计划写入文件
+func runPlan(tmdbService *services.TMDBService, args []string) error {
+	flags := flag.NewFlagSet("plan", flag.ExitOnError)
+	output := flags.String("o", "plan.json", "计划文件路径")
+	flags.Parse(args)
+
+	set, err := generateRuleSet(tmdbService)
+	if err != nil {
+		return err
+	}
+
+	wordGroupService, err := newWordGroupService()
+	if err != nil {
+		return fmt.Errorf("创建词组服务失败: %v", err)
+	}
+
+	groupPlan, err := buildGroupPlan(wordGroupService, set)
+	if err != nil {
+		return err
+	}
+
+	plan := &models.Plan{
+		Version:   models.PlanVersion,
+		CreatedAt: time.Now(),
+		Server:    wordGroupService.BaseURL(),
+		Groups:    []models.GroupPlan{*groupPlan},
+	}
+	printPlan(plan)
+
+	data, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		return fmt.Errorf("JSON编码失败: %v", err)
+	}
+	if err := os.WriteFile(*output, data, 0644); err != nil {
+		return fmt.Errorf("写入计划文件失败: %v", err)
+	}
+
+	saveRecipe(set.Recipe)
+	fmt.Printf("\n计划已写入：%s\n", *output)
+	fmt.Printf("确认无误后执行：rename-by-tmdb apply -f %s\n", *output)
+	return nil
+}
+
+// checkGroupDrift 检查服务器上词组的状态是否与规划时一致
+func checkGroupDrift(wordGroupService *services.WordGroupService, group *models.GroupPlan) error {
+	wordGroup, err := findExistingWordGroup(wordGroupService, group.Title)
+	if err != nil {
+		return err
+	}
+
+	if group.Action == models.GroupActionCreate {
+		if wordGroup != nil {
+			return fmt.Errorf("词组 %s 在规划后已被创建（ID: %d）", group.Title, wordGroup.ID)
+		}
+		return nil
+	}
+
+	if wordGroup == nil || wordGroup.ID != group.GroupID {
+		return fmt.Errorf("词组 %s（ID: %d）在规划后已被删除或替换", group.Title, group.GroupID)
+	}
+	existing, err := wordGroupService.ListWordUnits(group.GroupID)
+	if err != nil {
+		return fmt.Errorf("获取词组现有规则失败: %v", err)
+	}
+	if services.FingerprintWordUnits(existing) != group.Fingerprint {
+		return fmt.Errorf("词组 %s（ID: %d）的规则在规划后已被修改", group.Title, group.GroupID)
+	}
+	return nil
+}
+
+// applyGroupPlan 执行一个词组的变更
+func applyGroupPlan(wordGroupService *services.WordGroupService, group *models.GroupPlan) error {
+	groupID := group.GroupID
+	if group.Action == models.GroupActionCreate {
+		wordGroup, err := wordGroupService.CreateWordGroup(group.Title)
+		if err != nil {
+			return fmt.Errorf("创建词组失败: %v", err)
+		}
+		groupID = wordGroup.ID
+		fmt.Printf("词组创建成功，ID: %d\n", groupID)
+	}
+
+	created, err := wordGroupService.CreateWordUnits(groupID, group.Create)
+	for _, unit := range created {
+		fmt.Printf("新增：%s\n", unit.BeReplaced)
+	}
+	if err != nil {
+		return err
+	}
+	for _, unit := range group.Update {
+		if err := wordGroupService.UpdateWordUnit(unit); err != nil {
+			return fmt.Errorf("更新规则 %s 失败: %v", unit.BeReplaced, err)
+		}
+		fmt.Printf("更新：%s\n", unit.BeReplaced)
+	}
+	for _, unit := range group.Disable {
+		if err := wordGroupService.UpdateWordUnit(unit); err != nil {
+			return fmt.Errorf("禁用规则 %s 失败: %v", unit.BeReplaced, err)
+		}
+		fmt.Printf("禁用：%s\n", unit.BeReplaced)
+	}
+	for _, unit := range group.Delete {
+		if err := wordGroupService.DeleteWordUnit(unit.ID); err != nil {
+			return fmt.Errorf("删除规则 %s 失败: %v", unit.BeReplaced, err)
+		}
+		fmt.Printf("删除：%s\n", unit.BeReplaced)
+	}
+	return nil
+}
+
+// runApply apply 命令：执行计划文件，服务器状态与规划时不一致时拒绝执行
+func runApply(args []string) error {
+	flags := flag.NewFlagSet("apply", flag.ExitOnError)
+	input := flags.String("f", "plan.json", "计划文件路径")
+	assumeYes := flags.Bool("y", false, "不再确认直接执行")
+	flags.Parse(args)
+
+	data, err := os.ReadFile(*input)
+	if err != nil {
+		return fmt.Errorf("读取计划文件失败: %v", err)
+	}
+	var plan models.Plan
+	if err := json.Unmarshal(data, &plan); err != nil {
+		return fmt.Errorf("解析计划文件失败: %v", err)
+	}
+	if plan.Version != models.PlanVersion {
+		return fmt.Errorf("不支持的计划文件版本: %d", plan.Version)
+	}
+
+	wordGroupService, err := newWordGroupServiceForURL(plan.Server)
+	if err != nil {
+		return fmt.Errorf("创建词组服务失败: %v", err)
+	}
+	if wordGroupService.BaseURL() != plan.Server {
+		return fmt.Errorf("计划文件的服务器（%s）与当前配置的服务器（%s）不一致", plan.Server, wordGroupService.BaseURL())
+	}
+
+	// 执行前检查所有词组，任一词组状态变化都拒绝执行
+	for i := range plan.Groups {
+		if err := checkGroupDrift(wordGroupService, &plan.Groups[i]); err != nil {
+			return fmt.Errorf("服务器状态已变化，拒绝执行计划，请重新运行 plan: %v", err)
+		}
+	}
+
+	printPlan(&plan)
+	if !*assumeYes {
+		confirmed, err := utils.GetConfirmation("\n确认执行以上计划？(y/N): ")
+		if err != nil {
+			return fmt.Errorf("错误: %v", err)
+		}
+		if !confirmed {
+			fmt.Println("已取消")
+			return nil
+		}
+	}
+
+	tracker, audit := newRunRecorders(wordGroupService)
+	for i := range plan.Groups {
+		if err := applyGroupPlan(wordGroupService, &plan.Groups[i]); err != nil {
+			return tracker.handleFailure(wordGroupService, err)
+		}
+	}
+
+	fmt.Println("\n计划执行完成")
+	printRunID(tracker, audit)
+	return nil
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/harry/rename-by-tmdb/internal/models"
+	"github.com/harry/rename-by-tmdb/internal/utils"
+	"gopkg.in/yaml.v3"
+)
+
+// recipe 记录一次交互生成规则的全部输入，用全局参数 --recipe 重放时不再提问
+type recipe struct {
+	ToolVersion string            `yaml:"tool_version"`    // 生成配方的工具版本
+	CreatedAt   time.Time         `yaml:"created_at"`      // 生成时间
+	Title       string            `yaml:"title,omitempty"` // 生成的词组标题，仅供查看
+	MediaType   string            `yaml:"media_type"`      // movie 或 tv，对应媒体类型选项
+	TMDBID      string            `yaml:"tmdb_id"`
+	FileTitle   string            `yaml:"file_title"`        // 原文件名中的标题部分
+	Answers     map[string]string `yaml:"answers,omitempty"` // 各问题的回答，键为问题标识
+
+	name string // 配方名称，由生成规则的输入决定
+}
+
+// activeRecipe 全局参数 --recipe 指定的配方，重放时所有输入取自配方
+var activeRecipe *recipe
+
+// newRecipe 按媒体类型选项、文件标题和本次的回答创建配方
+func newRecipe(option, fileTitle, tmdbID, title string) *recipe {
+	mediaType := "tv"
+	if option == "1" {
+		mediaType = "movie"
+	}
+	r := &recipe{
+		ToolVersion: version,
+		Title:       title,
+		MediaType:   mediaType,
+		TMDBID:      tmdbID,
+		FileTitle:   fileTitle,
+		Answers:     utils.RecordedAnswers(),
+	}
+	r.name = r.hashName()
+	return r
+}
+
+// hashName 按媒体类型、TMDB ID、文件标题和回答生成配方名称，输入相同时名称相同，重放不会改变规则备注
+func (r *recipe) hashName() string {
+	data, _ := json.Marshal(struct {
+		MediaType string
+		TMDBID    string
+		FileTitle string
+		Answers   map[string]string
+	}{r.MediaType, r.TMDBID, r.FileTitle, r.Answers})
+	sum := sha256.Sum256(data)
+	return fmt.Sprintf("%s-%s-%x", r.MediaType, r.TMDBID, sum[:4])
+}
+
+// mediaTypeOption 返回配方对应的媒体类型选项（1 电影、2 剧集）
+func (r *recipe) mediaTypeOption() string {
+	if r.MediaType == "movie" {
+		return "1"
+	}
+	return "2"
+}
+
+// annotate 在规则备注中记录配方名称，并在第一条规则的备注中记录配方内容，
+// 团队成员没有该配方文件时也可以按名称从服务器读取配方重放
+func (r *recipe) annotate(rules []generatedRule) error {
+	carried := false
+	for i := range rules {
+		note, err := models.ParseUnitNote(rules[i].Unit.Note)
+		if err != nil {
+			return err
+		}
+		if note == nil {
+			continue
+		}
+		note.Recipe = r.name
+		if !carried {
+			note.RecipeData = &models.RecipeNote{
+				ToolVersion: r.ToolVersion,
+				MediaType:   r.MediaType,
+				TMDBID:      r.TMDBID,
+				FileTitle:   r.FileTitle,
+				Answers:     r.Answers,
+			}
+			carried = true
+		}
+		rules[i].Unit.Note = note.Encode()
+	}
+	return nil
+}
+
+// askRecipeInput 重放配方时显示提示和配方中的值，否则提示用户输入
+func askRecipeInput(out io.Writer, prompt string, field func(r *recipe) string) (string, error) {
+	if activeRecipe == nil {
+		return utils.GetUserInput(prompt)
+	}
+	value := field(activeRecipe)
+	fmt.Fprintf(out, "%s%s\n", prompt, value)
+	return value, nil
+}
+
+// startReplay 读取配方并开始重放，之后的提问不再读取输入
+func startReplay(nameOrPath string) error {
+	r, path, err := loadRecipe(nameOrPath)
+	if err != nil {
+		return err
+	}
+	activeRecipe = r
+	utils.SetReplayAnswers(r.Answers)
+
+	fmt.Printf("重放配方：%s\n", path)
+	if r.CreatedAt.IsZero() {
+		// 从规则备注中读取的配方没有生成时间
+		fmt.Printf("词组：%s（TMDB ID %s，%s 生成）\n\n", r.Title, r.TMDBID, r.ToolVersion)
+	} else {
+		fmt.Printf("词组：%s（TMDB ID %s，%s 生成于 %s）\n\n", r.Title, r.TMDBID, r.ToolVersion, r.CreatedAt.Local().Format("2006-01-02 15:04"))
+	}
+	if r.ToolVersion != version {
+		fmt.Printf("注意：配方由 %s 生成，当前版本为 %s，生成的规则可能不同\n\n", r.ToolVersion, version)
+	}
+	return nil
+}
+
+// loadRecipe 读取配方，参数不是已存在的文件时按名称在配方目录下查找，配方目录中也没有时从服务器上规则的备注中读取，
+// 返回配方和实际读取的位置
+func loadRecipe(nameOrPath string) (*recipe, string, error) {
+	path := nameOrPath
+	if _, err := os.Stat(path); err != nil {
+		dir, dirErr := utils.GetRecipeDir()
+		if dirErr != nil {
+			return nil, "", dirErr
+		}
+		path = filepath.Join(dir, strings.TrimSuffix(nameOrPath, ".yaml")+".yaml")
+	}
+
+	var r recipe
+	data, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		if err := yaml.Unmarshal(data, &r); err != nil {
+			return nil, "", fmt.Errorf("解析配方 %s 失败: %v", path, err)
+		}
+	case os.IsNotExist(err) && recipeNameRegexp.MatchString(nameOrPath):
+		fetched, source, fetchErr := fetchGroupRecipe(nameOrPath)
+		if fetchErr != nil {
+			return nil, "", fmt.Errorf("配方目录中没有配方 %s，%v", nameOrPath, fetchErr)
+		}
+		r, path = *fetched, source
+	default:
+		return nil, "", fmt.Errorf("读取配方失败: %v", err)
+	}
+	if r.MediaType != "movie" && r.MediaType != "tv" {
+		return nil, "", fmt.Errorf("配方 %s 中的媒体类型无效: '%s'，应为 movie 或 tv", path, r.MediaType)
+	}
+	if r.TMDBID == "" || r.FileTitle == "" {
+		return nil, "", fmt.Errorf("配方 %s 缺少 tmdb_id 或 file_title", path)
+	}
+	if err := utils.ValidateAnswers(r.Answers); err != nil {
+		return nil, "", fmt.Errorf("配方 %s: %v", path, err)
+	}
+	return &r, path, nil
+}
+
+// recipeNameRegexp 匹配配方名称，如 tv-37854-1a2b3c4d
+var recipeNameRegexp = regexp.MustCompile(`^(movie|tv)-(\d+)-[0-9a-f]+$`)
+
+// fetchGroupRecipe 在服务器上该TMDB条目的词组中查找备注记录了该配方内容的规则，返回配方和所在的词组
+func fetchGroupRecipe(name string) (*recipe, string, error) {
+	matches := recipeNameRegexp.FindStringSubmatch(name)
+	mediaType, tmdbID := matches[1], matches[2]
+
+	wordGroupService, err := newWordGroupService()
+	if err != nil {
+		return nil, "", fmt.Errorf("无法从服务器读取: %v", err)
+	}
+	groups, err := wordGroupService.ListWordGroups("tmdbid=" + tmdbID)
+	if err != nil {
+		return nil, "", fmt.Errorf("获取词组列表失败: %v", err)
+	}
+	for _, group := range groups {
+		if id, groupType, ok := utils.ParseTMDBToken(group.Title); !ok || id != tmdbID || groupType != mediaType {
+			continue
+		}
+		units, err := wordGroupService.ListWordUnits(group.ID)
+		if err != nil {
+			return nil, "", fmt.Errorf("获取词组 %s 的规则失败: %v", group.Title, err)
+		}
+		for _, unit := range units {
+			note, err := models.ParseUnitNote(unit.Note)
+			if err != nil || note == nil || note.Recipe != name || note.RecipeData == nil {
+				continue
+			}
+			data := note.RecipeData
+			r := &recipe{
+				ToolVersion: data.ToolVersion,
+				Title:       group.Title,
+				MediaType:   data.MediaType,
+				TMDBID:      data.TMDBID,
+				FileTitle:   data.FileTitle,
+				Answers:     data.Answers,
+				name:        name,
+			}
+			return r, fmt.Sprintf("服务器 %s 的词组 %s（ID: %d）", wordGroupService.Name(), group.Title, group.ID), nil
+		}
+	}
+	return nil, "", fmt.Errorf("服务器 %s 上也没有记录该配方内容的规则", wordGroupService.Name())
+}
+
+// saveRecipe 将配方写入配方目录，回答包含上传时的选择；失败时只提示，不影响本次运行的结果
+func saveRecipe(r *recipe) {
+	if r == nil {
+		return
+	}
+	path, err := writeRecipe(r)
+	if err != nil {
+		fmt.Printf("保存配方失败: %v\n", err)
+		return
+	}
+	fmt.Printf("\n配方已保存：%s\n", path)
+	fmt.Printf("重放：rename-by-tmdb --recipe %s\n", r.name)
+}
+
+// writeRecipe 写入配方文件，同名配方被覆盖
+func writeRecipe(r *recipe) (string, error) {
+	dir, err := utils.GetRecipeDir()
+	if err != nil {
+		return "", err
+	}
+	r.CreatedAt = time.Now()
+	r.Answers = utils.RecordedAnswers()
+
+	data, err := yaml.Marshal(r)
+	if err != nil {
+		return "", fmt.Errorf("YAML编码失败: %v", err)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("创建配方目录失败: %v", err)
+	}
+	path := filepath.Join(dir, r.name+".yaml")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return "", fmt.Errorf("写入配方失败: %v", err)
+	}
+	return path, nil
+}
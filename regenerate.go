@@ -0,0 +1,206 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/harry/rename-by-tmdb/internal/models"
+	"github.com/harry/rename-by-tmdb/internal/services"
+	"github.com/harry/rename-by-tmdb/internal/utils"
+)
+
+// regeneratedUnit 表示按备注中的生成参数重新生成的规则
+type regeneratedUnit struct {
+	Old        models.WordUnit
+	New        models.WordUnit
+	OldVersion int
+	Adopted    bool // 旧规则没有生成参数，由反向解析得到
+}
+
+// groupRegeneration 表示一个词组中需要重新生成的规则
+type groupRegeneration struct {
+	Group     models.WordGroup
+	Changed   []regeneratedUnit
+	Unchanged int
+	Failed    []string // 无法重新生成的规则及原因
+}
+
+// regenerateGroupUnits 按备注中的生成参数用当前生成器重新生成词组中的规则，保留规则ID和启用状态
+// 没有生成参数的旧规则先反向解析出生成参数，重新生成后写入备注
+func regenerateGroupUnits(group models.WordGroup, units []models.WordUnit) (*groupRegeneration, error) {
+	naming, err := parseNaming(group.Title)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &groupRegeneration{Group: group}
+	for _, unit := range units {
+		note, err := models.ParseUnitNote(unit.Note)
+		if err != nil {
+			result.Failed = append(result.Failed, fmt.Sprintf("%s: %v", unit.BeReplaced, err))
+			continue
+		}
+		adopted := false
+		if note == nil {
+			note, err = interpretUnit(unit)
+			if err != nil {
+				result.Failed = append(result.Failed, fmt.Sprintf("%s: 没有生成参数且无法反向解析，%v", unit.BeReplaced, err))
+				continue
+			}
+			adopted = true
+		}
+		if note.TMDBID != "" && note.TMDBID != naming.TMDBID {
+			result.Failed = append(result.Failed, fmt.Sprintf("%s: 备注中的 tmdbid=%s 与词组不一致", unit.BeReplaced, note.TMDBID))
+			continue
+		}
+
+		rule, err := buildRule(naming, *note)
+		if err != nil {
+			result.Failed = append(result.Failed, fmt.Sprintf("%s: %v", unit.BeReplaced, err))
+			continue
+		}
+
+		regenerated := rule.Unit
+		regenerated.ID = unit.ID
+		regenerated.WordGroupID = unit.WordGroupID
+		regenerated.Enabled = unit.Enabled
+		if regenerated == unit {
+			result.Unchanged++
+			continue
+		}
+		result.Changed = append(result.Changed, regeneratedUnit{Old: unit, New: regenerated, OldVersion: note.Version, Adopted: adopted})
+	}
+	return result, nil
+}
+
+// printRegeneration 显示重新生成后有变化的规则
+func printRegeneration(result *groupRegeneration) {
+	for _, change := range result.Changed {
+		if change.Adopted {
+			fmt.Printf("  ~ 规则 ID %d（旧规则，写入反向解析的生成参数）\n", change.Old.ID)
+		} else {
+			fmt.Printf("  ~ 规则 ID %d（生成器版本 %d → %d）\n", change.Old.ID, change.OldVersion, models.GeneratorVersion)
+		}
+		printFieldChange("被替换词", change.Old.BeReplaced, change.New.BeReplaced)
+		printFieldChange("替换词", change.Old.Replace, change.New.Replace)
+		printFieldChange("前定位词", change.Old.Front, change.New.Front)
+		printFieldChange("后定位词", change.Old.Back, change.New.Back)
+		printFieldChange("偏移量", change.Old.Offset, change.New.Offset)
+	}
+	for _, failure := range result.Failed {
+		fmt.Printf("  ! 无法重新生成 %s\n", failure)
+	}
+}
+
+// printFieldChange 字段有变化时显示新旧值
+func printFieldChange(name, oldValue, newValue string) {
+	if oldValue == newValue {
+		return
+	}
+	fmt.Printf("    %s：%s\n      → %s\n", name, oldValue, newValue)
+}
+
+// listRegenerateGroups 获取要重新生成的词组，未指定ID时为所有带 tmdbid 的词组
+func listRegenerateGroups(wordGroupService *services.WordGroupService, groupIDs []int) ([]models.WordGroup, error) {
+	if len(groupIDs) == 0 {
+		return wordGroupService.ListWordGroups("tmdbid=")
+	}
+
+	groups, err := wordGroupService.ListWordGroups("")
+	if err != nil {
+		return nil, err
+	}
+	var selected []models.WordGroup
+	for _, id := range groupIDs {
+		found := false
+		for _, group := range groups {
+			if group.ID == id {
+				selected = append(selected, group)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("词组 %d 不存在", id)
+		}
+	}
+	return selected, nil
+}
+
+// runRegenerate regenerate 命令：读取规则备注中的生成参数，用当前生成器重新生成规则并更新到服务器
+func runRegenerate(args []string) error {
+	flags := flag.NewFlagSet("regenerate", flag.ExitOnError)
+	dryRun := flags.Bool("n", false, "只列出会变化的规则，不做修改")
+	assumeYes := flags.Bool("y", false, "不再逐个确认直接更新")
+	groups := flags.String("group", "", "要重新生成的词组ID，多个用逗号分隔，不指定时处理所有带 tmdbid 的词组")
+	flags.Parse(args)
+
+	groupIDs, err := parseGroupIDs(*groups)
+	if err != nil {
+		return err
+	}
+
+	wordGroupService, err := newWordGroupService()
+	if err != nil {
+		return fmt.Errorf("创建词组服务失败: %v", err)
+	}
+
+	wordGroups, err := listRegenerateGroups(wordGroupService, groupIDs)
+	if err != nil {
+		return fmt.Errorf("获取词组列表失败: %v", err)
+	}
+
+	updated, pending := 0, 0
+	for _, group := range wordGroups {
+		units, err := wordGroupService.ListWordUnits(group.ID)
+		if err != nil {
+			return fmt.Errorf("获取词组规则失败: %v", err)
+		}
+
+		result, err := regenerateGroupUnits(group, units)
+		if err != nil {
+			fmt.Printf("词组 %s（ID: %d）%v，跳过\n", group.Title, group.ID, err)
+			continue
+		}
+		if len(result.Changed) == 0 && len(result.Failed) == 0 {
+			continue
+		}
+
+		fmt.Printf("\n词组 %s（ID: %d）：%d 条规则有变化，%d 条无变化\n",
+			group.Title, group.ID, len(result.Changed), result.Unchanged)
+		printRegeneration(result)
+
+		if len(result.Changed) == 0 {
+			continue
+		}
+		pending += len(result.Changed)
+		if *dryRun {
+			continue
+		}
+		if !*assumeYes {
+			confirmed, err := utils.GetConfirmation("是否更新该词组的规则？(y/N): ")
+			if err != nil {
+				return fmt.Errorf("错误: %v", err)
+			}
+			if !confirmed {
+				continue
+			}
+		}
+
+		for _, change := range result.Changed {
+			if err := wordGroupService.UpdateWordUnit(change.New); err != nil {
+				return fmt.Errorf("更新规则 %s 失败: %v", change.Old.BeReplaced, err)
+			}
+			updated++
+		}
+	}
+
+	if pending == 0 {
+		fmt.Println("所有规则与当前生成器的结果一致")
+		return nil
+	}
+	if !*dryRun {
+		fmt.Printf("\n已更新 %d 条规则\n", updated)
+	}
+	return nil
+}
@@ -0,0 +1,438 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/harry/rename-by-tmdb/internal/models"
+	"github.com/harry/rename-by-tmdb/internal/services"
+	"github.com/harry/rename-by-tmdb/internal/utils"
+)
+
+// 反向解析替换词：剧集为 名称.S季数E集数.年份.{[tmdbid=ID;type=类型]}，集数为 \1、补0的集数或多集区间
+var (
+	tvReplaceRegexp    = regexp.MustCompile(`^(.+?)\.S(\d{2,})E(\\1|\d+|\d+-E\d+)\.(\d{4}|)\.\{\[tmdbid=(\d+);type=(\w+)\]\}$`)
+	movieReplaceRegexp = regexp.MustCompile(`^(.+?)\.(\d{4}|)\.(?:(.+?)\.)?\{\[tmdbid=(\d+);type=(\w+)\]\}$`)
+)
+
+// 反向解析被替换词中标题之后的部分，与 buildRule 中各模式的格式一一对应
+var (
+	rangeWithSeasonRegexp = regexp.MustCompile(`^` + regexp.QuoteMeta(".*S") + `(\d{2,})` +
+		regexp.QuoteMeta(episodeMarkerPattern) + `\(\(([0-9|]+)\)\)$`)
+	rangeRegexp     = regexp.MustCompile(`^` + regexp.QuoteMeta(`.*?(?:S\d{2})?`+episodeMarkerPattern) + `\(\(([0-9|]+)\)\)$`)
+	partRangeRegexp = regexp.MustCompile(`^` + regexp.QuoteMeta(".*?") + partSeasonTemplate +
+		regexp.QuoteMeta(episodeMarkerPattern) + `\(\(([0-9|]+)\)\)` + regexp.QuoteMeta("(?!.*"+partMarkerPattern+")") + `$`)
+	partRegexp = regexp.MustCompile(`^` + regexp.QuoteMeta(".*?") + partSeasonTemplate +
+		regexp.QuoteMeta(episodeMarkerPattern) + `(\d+)` + regexp.QuoteMeta(".*?"+partMarkerPattern) + `(\d+)` + regexp.QuoteMeta(".*") + `$`)
+	multiWithSeasonRegexp = regexp.MustCompile(`^` + regexp.QuoteMeta(".*S") + `(\d{2,})` + multiEpisodeTemplate + `$`)
+	multiRegexp           = regexp.MustCompile(`^` + regexp.QuoteMeta(`.*?(?:S\d{2})?`) + multiEpisodeTemplate + `$`)
+	datePartRegexp        = regexp.MustCompile(`^\.\*(.+)` + regexp.QuoteMeta(".*?"+sameDatePartPattern) + `(\d+)\.\*$`)
+	dateEpisodeRegexp     = regexp.MustCompile(`^` + regexp.QuoteMeta("(?=.*?"+episodeHintPattern+"0*") + `\d+` +
+		regexp.QuoteMeta("(?:[^0-9]|$))") + `\.\*(.+)\.\*$`)
+	dateRegexp   = regexp.MustCompile(`^\.\*([^?].*)\.\*$`)
+	lazyRegexp   = regexp.MustCompile(`^\.\*\?(.+)\.\*$`)
+	titleRegexp  = regexp.MustCompile(`^\(\?i:(.+)\)$`)
+	numberRegexp = regexp.MustCompile(`\d+`)
+)
+
+// partSeasonTemplate part模式被替换词中季数部分的反向解析正则，第一个捕获组为空时表示要求季数标记
+const partSeasonTemplate = `(\(\?:)?S(\d{2,})(?:\)\?)?`
+
+// multiEpisodeTemplate 多集区间模式的反向解析正则，用占位集数生成模式后将占位集数替换为捕获组
+var multiEpisodeTemplate = templatePattern(utils.GenerateMultiEpisodePattern(111, 222))
+
+// templatePattern 将按占位数字 111、222 生成的片段转义为正则，并把占位数字替换为捕获组
+func templatePattern(fragment string) string {
+	quoted := regexp.QuoteMeta(fragment)
+	return strings.NewReplacer("111", `(\d+)`, "222", `(\d+)`).Replace(quoted)
+}
+
+// splitFileTitle 将被替换词拆分为文件名标题和其后的模式，标题经过 QuoteMeta 转义，以第一个未转义的 .* 或 (?= 结束
+func splitFileTitle(beReplaced string) (string, string, bool) {
+	var title strings.Builder
+	for i := 0; i < len(beReplaced); i++ {
+		switch {
+		case beReplaced[i] == '\\' && i+1 < len(beReplaced):
+			i++
+			title.WriteByte(beReplaced[i])
+		case strings.HasPrefix(beReplaced[i:], ".*"), strings.HasPrefix(beReplaced[i:], "(?="):
+			// 标题之后是 .* 或按集数区分同日多集时的前瞻
+			return title.String(), beReplaced[i:], true
+		case strings.ContainsRune(`.+*?()|[]{}^$`, rune(beReplaced[i])):
+			// 标题中的元字符都已转义，出现未转义的元字符说明不是本工具生成的
+			return "", "", false
+		default:
+			title.WriteByte(beReplaced[i])
+		}
+	}
+	return "", "", false
+}
+
+// parseRangeAlternatives 解析集数区间模式中的集数列表，返回起止集数和补0位数，集数必须连续
+func parseRangeAlternatives(alternatives string) (int, int, int, error) {
+	parts := strings.Split(alternatives, "|")
+	start, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("无效的集数 '%s'", parts[0])
+	}
+	for i, part := range parts {
+		episode, err := strconv.Atoi(part)
+		if err != nil || episode != start+i {
+			return 0, 0, 0, fmt.Errorf("集数区间 %s 不连续", alternatives)
+		}
+	}
+
+	digits := 1
+	if len(parts[0]) > 1 && parts[0][0] == '0' {
+		digits = len(parts[0])
+	}
+	return start, start + len(parts) - 1, digits, nil
+}
+
+// parseDateAlternatives 解析播出日期模式，还原播出日期、日期格式和容差天数
+func parseDateAlternatives(pattern string) (string, []string, int, error) {
+	var alternatives []string
+	if strings.HasPrefix(pattern, "(?:") && strings.HasSuffix(pattern, ")") {
+		alternatives = strings.Split(pattern[3:len(pattern)-1], "|")
+	} else {
+		alternatives = []string{pattern}
+	}
+
+	var formats []string
+	var minDate, maxDate time.Time
+	for _, alternative := range alternatives {
+		text := strings.ReplaceAll(alternative, `\`, "")
+		matched := false
+		for _, format := range utils.DateFormats {
+			date, err := time.Parse(format.Layout, text)
+			if err != nil || date.Format(format.Layout) != text {
+				continue
+			}
+			matched = true
+			if !containsString(formats, format.Name) {
+				formats = append(formats, format.Name)
+			}
+			// MM.DD 不包含年份，只用其他格式确定日期范围
+			if date.Year() == 0 {
+				break
+			}
+			if minDate.IsZero() || date.Before(minDate) {
+				minDate = date
+			}
+			if maxDate.IsZero() || date.After(maxDate) {
+				maxDate = date
+			}
+			break
+		}
+		if !matched {
+			return "", nil, 0, fmt.Errorf("无法识别的日期 '%s'", text)
+		}
+	}
+	if minDate.IsZero() {
+		return "", nil, 0, fmt.Errorf("日期格式不包含年份，无法还原播出日期")
+	}
+
+	tolerance := int(maxDate.Sub(minDate).Hours()/24) / 2
+	airDate := minDate.AddDate(0, 0, tolerance).Format("2006-01-02")
+	return airDate, formats, tolerance, nil
+}
+
+// parseTitleAlternatives 解析集名匹配模式，还原规范化的集名
+func parseTitleAlternatives(pattern string) ([]string, error) {
+	var titles []string
+	for _, alternative := range splitUnescaped(pattern, '|') {
+		var words []string
+		for _, word := range strings.Split(alternative, `[^\p{L}\p{N}]*`) {
+			words = append(words, strings.ReplaceAll(word, `\`, ""))
+		}
+		title := strings.Join(words, " ")
+		if title == "" {
+			return nil, fmt.Errorf("集名为空")
+		}
+		titles = append(titles, title)
+	}
+	return titles, nil
+}
+
+// splitUnescaped 按未转义的分隔符拆分字符串
+func splitUnescaped(text string, separator byte) []string {
+	var parts []string
+	start := 0
+	for i := 0; i < len(text); i++ {
+		if text[i] == '\\' {
+			i++
+			continue
+		}
+		if text[i] == separator {
+			parts = append(parts, text[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, text[start:])
+}
+
+// specialMarkerTemplate 表示一类特别篇标记模式的反向解析正则
+type specialMarkerTemplate struct {
+	Kind    string // SP、OVA、番外，小数集数为空
+	Single  bool
+	Pattern *regexp.Regexp
+}
+
+// specialMarkerTemplates 各类特别篇标记模式的反向解析正则，按占位编号生成
+var specialMarkerTemplates = func() []specialMarkerTemplate {
+	templates := []specialMarkerTemplate{{Pattern: templateMarker("111.222", false)}}
+	for _, kind := range []string{"SP", "OVA", "番外"} {
+		for _, single := range []bool{false, true} {
+			templates = append(templates, specialMarkerTemplate{Kind: kind, Single: single, Pattern: templateMarker(kind+"111", single)})
+		}
+	}
+	return templates
+}()
+
+// templateMarker 按占位编号生成特别篇标记模式的反向解析正则
+func templateMarker(marker string, single bool) *regexp.Regexp {
+	pattern, _ := specialMarkerPattern(marker, single)
+	return regexp.MustCompile(`^` + templatePattern(pattern) + `$`)
+}
+
+// parseSpecialMarker 按各类特别篇标记的模式反向匹配，还原特别篇标记和编号是否可省略
+func parseSpecialMarker(pattern string) (string, bool, bool) {
+	for _, template := range specialMarkerTemplates {
+		matches := template.Pattern.FindStringSubmatch(pattern)
+		if matches == nil {
+			continue
+		}
+		if template.Kind == "" {
+			return matches[1] + "." + matches[2], false, true
+		}
+		return template.Kind + matches[1], template.Single, true
+	}
+	return "", false, false
+}
+
+// containsString 判断切片中是否包含指定字符串
+func containsString(values []string, target string) bool {
+	for _, value := range values {
+		if value == target {
+			return true
+		}
+	}
+	return false
+}
+
+// interpretUnit 反向解析本工具生成的规则，还原文件名标题、季数、集数区间、补0位数、偏移量和生成模式
+// 解析出的参数按当前生成器重新生成后必须与原规则完全一致，否则视为无法解析（如手动修改过的规则）
+func interpretUnit(unit models.WordUnit) (*models.UnitNote, error) {
+	if unit.Type == models.WordUnitTypeBlock {
+		note := &models.UnitNote{Mode: models.NoteModeBlock, Pattern: unit.BeReplaced}
+		if !unit.Regex || unit.BeReplaced == "" {
+			return nil, fmt.Errorf("不是正则屏蔽词")
+		}
+		return note, nil
+	}
+	if unit.Type != models.WordUnitTypeReplace && unit.Type != models.WordUnitTypeReplaceOffset {
+		return nil, fmt.Errorf("不支持的规则类型：%s", unit.Type)
+	}
+
+	fileTitle, rest, ok := splitFileTitle(unit.BeReplaced)
+	if !ok {
+		return nil, fmt.Errorf("被替换词不是以文件名标题开头")
+	}
+	offset, err := services.ParseOffset(unit.Offset)
+	if err != nil {
+		return nil, err
+	}
+
+	note, naming, err := interpretPattern(fileTitle, rest, unit.Replace, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	// 按解析出的参数重新生成，与原规则比较
+	rule, err := buildRule(naming, *note)
+	if err != nil {
+		return nil, err
+	}
+	generated := rule.Unit
+	if generated.BeReplaced != unit.BeReplaced || generated.Replace != unit.Replace ||
+		generated.Front != unit.Front || generated.Back != unit.Back ||
+		generated.Offset != unit.Offset || generated.Type != unit.Type || generated.Regex != unit.Regex {
+		return nil, fmt.Errorf("按解析出的参数（%s模式）重新生成的规则与原规则不一致", note.Mode)
+	}
+
+	note.TMDBID = naming.TMDBID
+	note.MediaType = naming.MediaType
+	return note, nil
+}
+
+// interpretPattern 按被替换词和替换词的格式判断生成模式并还原生成参数
+func interpretPattern(fileTitle, rest, replace string, offset int) (*models.UnitNote, mediaNaming, error) {
+	// 电影：标题.*，替换词不含季数
+	if rest == ".*" {
+		matches := movieReplaceRegexp.FindStringSubmatch(replace)
+		if matches == nil || matches[5] != "movie" {
+			return nil, mediaNaming{}, fmt.Errorf("无法解析电影的替换词")
+		}
+		naming := mediaNaming{Name: matches[1], Year: matches[2], TMDBID: matches[4], MediaType: matches[5]}
+		return &models.UnitNote{Mode: models.NoteModeMovie, FileTitle: fileTitle, PartInfo: matches[3]}, naming, nil
+	}
+
+	matches := tvReplaceRegexp.FindStringSubmatch(replace)
+	if matches == nil {
+		return nil, mediaNaming{}, fmt.Errorf("无法解析替换词")
+	}
+	naming := mediaNaming{Name: matches[1], Year: matches[4], TMDBID: matches[5], MediaType: matches[6]}
+	season, _ := strconv.Atoi(matches[2])
+	episodeField := matches[3]
+	episode, _ := strconv.Atoi(strings.SplitN(episodeField, "-", 2)[0])
+	note := &models.UnitNote{FileTitle: fileTitle, Season: season, Offset: offset}
+
+	if groups := rangeWithSeasonRegexp.FindStringSubmatch(rest); groups != nil {
+		note.Mode = models.NoteModeRange
+		note.HasSeason = true
+		return note, naming, setEpisodeRange(note, groups[2])
+	}
+	if groups := rangeRegexp.FindStringSubmatch(rest); groups != nil {
+		note.Mode = models.NoteModeRange
+		return note, naming, setEpisodeRange(note, groups[1])
+	}
+	if groups := partRangeRegexp.FindStringSubmatch(rest); groups != nil {
+		note.Mode = models.NoteModePartRange
+		note.HasSeason = groups[1] == ""
+		return note, naming, setEpisodeRange(note, groups[3])
+	}
+	if groups := partRegexp.FindStringSubmatch(rest); groups != nil {
+		note.Mode = models.NoteModePart
+		note.HasSeason = groups[1] == ""
+		note.Episode, _ = strconv.Atoi(groups[3])
+		note.Digits = len(groups[3])
+		note.Part, _ = strconv.Atoi(groups[4])
+		return note, naming, nil
+	}
+	for _, multi := range []*regexp.Regexp{multiWithSeasonRegexp, multiRegexp} {
+		groups := multi.FindStringSubmatch(rest)
+		if groups == nil {
+			continue
+		}
+		note.Mode = models.NoteModeMulti
+		note.HasSeason = multi == multiWithSeasonRegexp
+		note.Start, _ = strconv.Atoi(groups[len(groups)-2])
+		note.End, _ = strconv.Atoi(groups[len(groups)-1])
+		// 多集规则没有偏移量字段，偏移量由替换词中的实际集数得出
+		note.Offset = episode - note.Start
+		return note, naming, nil
+	}
+
+	// 特别篇和集名匹配：标题.*?模式.*
+	if groups := lazyRegexp.FindStringSubmatch(rest); groups != nil {
+		if titles := titleRegexp.FindStringSubmatch(groups[1]); titles != nil {
+			parsed, err := parseTitleAlternatives(titles[1])
+			if err != nil {
+				return nil, naming, err
+			}
+			note.Mode = models.NoteModeTitle
+			note.Episode = episode
+			note.Titles = parsed
+			return note, naming, nil
+		}
+		marker, single, ok := parseSpecialMarker(groups[1])
+		if !ok {
+			return nil, naming, fmt.Errorf("无法识别的特别篇标记模式")
+		}
+		note.Mode = models.NoteModeSpecial
+		note.Season = 0
+		note.Episode = episode
+		note.Marker = marker
+		note.Single = single
+		return note, naming, nil
+	}
+
+	// 日期模式：标题.*日期.*，同日多集时后面带有part或集数提示
+	var datePattern string
+	note.Episode = episode
+	if groups := datePartRegexp.FindStringSubmatch(rest); groups != nil {
+		datePattern = groups[1]
+		note.SameDate = "part"
+		note.Part, _ = strconv.Atoi(groups[2])
+	} else if groups := dateEpisodeRegexp.FindStringSubmatch(rest); groups != nil {
+		datePattern = groups[1]
+		note.SameDate = "episode"
+	} else if groups := dateRegexp.FindStringSubmatch(rest); groups != nil {
+		datePattern = groups[1]
+		if strings.Contains(episodeField, "-") {
+			note.SameDate = "merge"
+			note.End, _ = strconv.Atoi(numberRegexp.FindAllString(episodeField, -1)[1])
+		}
+	} else {
+		return nil, naming, fmt.Errorf("无法识别的被替换词格式")
+	}
+
+	alternatives, ok := utils.TrimDateBoundary(datePattern)
+	if !ok {
+		return nil, naming, fmt.Errorf("日期前后缺少数字边界")
+	}
+	airDate, formats, tolerance, err := parseDateAlternatives(alternatives)
+	if err != nil {
+		return nil, naming, err
+	}
+	note.Mode = models.NoteModeDate
+	note.AirDate = airDate
+	note.DateFormats = formats
+	note.Tolerance = tolerance
+	return note, naming, nil
+}
+
+// setEpisodeRange 解析集数区间模式，设置生成参数中的起止集数和补0位数
+func setEpisodeRange(note *models.UnitNote, alternatives string) error {
+	start, end, digits, err := parseRangeAlternatives(alternatives)
+	if err != nil {
+		return err
+	}
+	note.Start, note.End, note.Digits = start, end, digits
+	return nil
+}
+
+// unitAdoption 同步时反向解析词组中没有生成参数的旧规则，每条规则只解析一次，并记录无法识别的规则
+type unitAdoption struct {
+	inferred     map[int]*models.UnitNote
+	unrecognized []models.UnrecognizedUnit
+}
+
+// newUnitAdoption 创建一次同步使用的反向解析缓存
+func newUnitAdoption() *unitAdoption {
+	return &unitAdoption{inferred: make(map[int]*models.UnitNote)}
+}
+
+// key 返回同步时现有规则的键：没有生成参数的旧规则能反向解析时，按解析出的生成参数匹配生成的规则，
+// 使旧规则被更新而不是作为过期规则处理；无法解析的规则按原内容匹配，并记入无法识别的规则
+func (a *unitAdoption) key(unit models.WordUnit) string {
+	if note, err := models.ParseUnitNote(unit.Note); err == nil && note == nil {
+		if inferred := a.interpret(unit); inferred != nil {
+			unit.Note = inferred.Encode()
+		}
+	}
+	return services.WordUnitKey(unit)
+}
+
+// interpret 反向解析规则并缓存结果，无法解析时返回 nil
+func (a *unitAdoption) interpret(unit models.WordUnit) *models.UnitNote {
+	if inferred, ok := a.inferred[unit.ID]; ok {
+		return inferred
+	}
+	inferred, err := interpretUnit(unit)
+	if err != nil {
+		a.unrecognized = append(a.unrecognized, models.UnrecognizedUnit{Unit: unit, Reason: err.Error()})
+	}
+	a.inferred[unit.ID] = inferred
+	return inferred
+}
+
+// printUnrecognized 列出无法识别的旧规则及原因
+func printUnrecognized(units []models.UnrecognizedUnit, indent string) {
+	for _, item := range units {
+		fmt.Printf("%s? 无法识别：%s → %s（%s）\n", indent, item.Unit.BeReplaced, item.Unit.Replace, item.Reason)
+	}
+}
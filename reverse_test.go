@@ -0,0 +1,152 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/harry/rename-by-tmdb/internal/models"
+	"github.com/harry/rename-by-tmdb/internal/services"
+)
+
+var (
+	testNaming      = mediaNaming{Name: "Show", Year: "2020", TMDBID: "100", MediaType: "tv"}
+	testMovieNaming = mediaNaming{Name: "Movie", Year: "2021", TMDBID: "200", MediaType: "movie"}
+)
+
+// TestInterpretUnit 生成的规则去掉备注后，应能反向解析出相同的生成参数（特别篇标记和集名使用规范形式）
+func TestInterpretUnit(t *testing.T) {
+	tests := []struct {
+		name string
+		note models.UnitNote
+	}{
+		{name: "电影", note: models.UnitNote{Mode: models.NoteModeMovie, FileTitle: "Movie.File"}},
+		{name: "电影part", note: models.UnitNote{Mode: models.NoteModeMovie, FileTitle: "Movie.File", PartInfo: "part1"}},
+		{name: "区间", note: models.UnitNote{Mode: models.NoteModeRange, FileTitle: "Show.File", Season: 1, HasSeason: true, Start: 1, End: 12, Digits: 2}},
+		{name: "区间不含季数", note: models.UnitNote{Mode: models.NoteModeRange, FileTitle: "Show.File", Season: 2, Start: 13, End: 24, Digits: 1}},
+		{name: "区间偏移", note: models.UnitNote{Mode: models.NoteModeRange, FileTitle: "Show.File", Season: 2, Start: 13, End: 24, Digits: 1, Offset: -12}},
+		{name: "part", note: models.UnitNote{Mode: models.NoteModePart, FileTitle: "Show.File", Season: 1, Episode: 2, Part: 1, Digits: 2}},
+		{name: "part偏移", note: models.UnitNote{Mode: models.NoteModePart, FileTitle: "Show.File", Season: 1, HasSeason: true, Episode: 5, Part: 2, Digits: 2, Offset: 1}},
+		{name: "part之间的区间", note: models.UnitNote{Mode: models.NoteModePartRange, FileTitle: "Show.File", Season: 1, Start: 3, End: 4, Digits: 2}},
+		{name: "多集", note: models.UnitNote{Mode: models.NoteModeMulti, FileTitle: "Show.File", Season: 1, HasSeason: true, Start: 1, End: 2}},
+		{name: "多集偏移", note: models.UnitNote{Mode: models.NoteModeMulti, FileTitle: "Show.File", Season: 1, Start: 3, End: 4, Offset: 10}},
+		{name: "日期", note: models.UnitNote{Mode: models.NoteModeDate, FileTitle: "Show.File", Season: 1, Episode: 1, AirDate: "2020-12-01", DateFormats: []string{"YYYYMMDD", "YYMMDD"}}},
+		{name: "日期容差", note: models.UnitNote{Mode: models.NoteModeDate, FileTitle: "Show.File", Season: 1, Episode: 1, AirDate: "2020-12-01", DateFormats: []string{"YYYY.MM.DD"}, Tolerance: 1}},
+		{name: "同日多集合并", note: models.UnitNote{Mode: models.NoteModeDate, FileTitle: "Show.File", Season: 1, Episode: 1, End: 2, AirDate: "2020-12-01", DateFormats: []string{"YYYYMMDD"}, SameDate: "merge"}},
+		{name: "同日多集按part", note: models.UnitNote{Mode: models.NoteModeDate, FileTitle: "Show.File", Season: 1, Episode: 2, Part: 2, AirDate: "2020-12-01", DateFormats: []string{"YYYYMMDD"}, SameDate: "part"}},
+		{name: "同日多集按集数", note: models.UnitNote{Mode: models.NoteModeDate, FileTitle: "Show.File", Season: 1, Episode: 2, AirDate: "2020-12-01", DateFormats: []string{"YYYYMMDD"}, SameDate: "episode"}},
+		{name: "特别篇", note: models.UnitNote{Mode: models.NoteModeSpecial, FileTitle: "Show.File", Episode: 1, Marker: "SP1"}},
+		{name: "唯一的特别篇", note: models.UnitNote{Mode: models.NoteModeSpecial, FileTitle: "Show.File", Episode: 2, Marker: "OVA1", Single: true}},
+		{name: "小数集数特别篇", note: models.UnitNote{Mode: models.NoteModeSpecial, FileTitle: "Show.File", Episode: 3, Marker: "12.5"}},
+		{name: "集名", note: models.UnitNote{Mode: models.NoteModeTitle, FileTitle: "Show.File", Season: 1, Episode: 3, Titles: []string{"pilot"}}},
+		{name: "屏蔽词", note: models.UnitNote{Mode: models.NoteModeBlock, Pattern: `【www\.example\.com】`}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			naming := testNaming
+			if tt.note.Mode == models.NoteModeMovie {
+				naming = testMovieNaming
+			}
+			rule, err := buildRule(naming, tt.note)
+			if err != nil {
+				t.Fatalf("buildRule() error = %v", err)
+			}
+			unit := rule.Unit
+			unit.Note = ""
+
+			got, err := interpretUnit(unit)
+			if err != nil {
+				t.Fatalf("interpretUnit(%s → %s) error = %v", unit.BeReplaced, unit.Replace, err)
+			}
+			want := tt.note
+			if want.Mode != models.NoteModeBlock {
+				want.TMDBID, want.MediaType = naming.TMDBID, naming.MediaType
+			}
+			if !reflect.DeepEqual(*got, want) {
+				t.Errorf("interpretUnit() = %+v\n期望 %+v", *got, want)
+			}
+		})
+	}
+}
+
+// TestInterpretUnitUnrecognized 不是由生成器生成或生成后被修改的规则不能反向解析
+func TestInterpretUnitUnrecognized(t *testing.T) {
+	generated, err := buildRule(testNaming, models.UnitNote{Mode: models.NoteModeRange, FileTitle: "Show.File", Season: 1, HasSeason: true, Start: 1, End: 12, Digits: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	modified := generated.Unit
+	modified.Note = ""
+	modified.BeReplaced = strings.Replace(modified.BeReplaced, "12", "13", 1)
+
+	offsetRule, err := buildRule(testNaming, models.UnitNote{Mode: models.NoteModeRange, FileTitle: "Show.File", Season: 2, Start: 13, End: 24, Digits: 1, Offset: -12})
+	if err != nil {
+		t.Fatal(err)
+	}
+	movedFront := offsetRule.Unit
+	movedFront.Note = ""
+	movedFront.Front = "Show.E"
+
+	tests := []struct {
+		name    string
+		unit    models.WordUnit
+		wantErr string
+	}{
+		{name: "手写的规则", unit: services.NewWordUnit(0, `Foo.*E(\d+)`, `Bar.S01E\1`, "", "", 0), wantErr: "无法解析替换词"},
+		{name: "修改过的规则", unit: modified, wantErr: "不连续"},
+		{name: "修改过定位词的规则", unit: movedFront, wantErr: "重新生成的规则与原规则不一致"},
+		{name: "非正则屏蔽词", unit: services.NewBlockWordUnit(0, "广告", false), wantErr: "不是正则屏蔽词"},
+		{name: "不支持的类型", unit: models.WordUnit{Type: models.WordUnitTypeOffset, BeReplaced: "Show.*", Front: "E", Back: ".", Offset: "EP+1"}, wantErr: "不支持的规则类型"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := interpretUnit(tt.unit)
+			if err == nil {
+				t.Fatalf("interpretUnit() = %+v, 期望无法识别", got)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("interpretUnit() error = %v, 期望包含 %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestUnitAdoption 同步时没有备注的旧规则按反向解析的生成参数与生成的规则匹配，无法识别的规则视为过期
+func TestUnitAdoption(t *testing.T) {
+	var generated []models.WordUnit
+	for _, note := range []models.UnitNote{
+		{Mode: models.NoteModeRange, FileTitle: "Show.File", Season: 1, HasSeason: true, Start: 1, End: 12, Digits: 2},
+		{Mode: models.NoteModeRange, FileTitle: "Show.File", Season: 2, HasSeason: true, Start: 1, End: 10, Digits: 2},
+	} {
+		rule, err := buildRule(testNaming, note)
+		if err != nil {
+			t.Fatal(err)
+		}
+		generated = append(generated, rule.Unit)
+	}
+
+	legacy := generated[0]
+	legacy.ID, legacy.Note = 1, ""
+	manual := services.NewWordUnit(0, `Foo.*E(\d+)`, `Bar.S01E\1`, "", "", 0)
+	manual.ID = 2
+	existing := []models.WordUnit{legacy, manual}
+
+	adoption := newUnitAdoption()
+	plan := services.DiffWordUnitsWithKey(existing, generated, adoption.key)
+
+	// 旧规则补上备注后更新，第二季新增，手写的规则过期
+	if len(plan.Update) != 1 || plan.Update[0].ID != 1 || plan.Update[0].Note != generated[0].Note {
+		t.Errorf("Update = %+v", plan.Update)
+	}
+	if len(plan.Create) != 1 || plan.Create[0].Note != generated[1].Note {
+		t.Errorf("Create = %+v", plan.Create)
+	}
+	if len(plan.Obsolete) != 1 || plan.Obsolete[0].ID != 2 {
+		t.Errorf("Obsolete = %+v", plan.Obsolete)
+	}
+	if len(adoption.unrecognized) != 1 || adoption.unrecognized[0].Unit.ID != 2 {
+		t.Errorf("unrecognized = %+v", adoption.unrecognized)
+	}
+}
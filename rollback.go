@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/harry/rename-by-tmdb/internal/models"
+	"github.com/harry/rename-by-tmdb/internal/services"
+	"github.com/harry/rename-by-tmdb/internal/utils"
+)
+
+// runTracker 记录本次运行在服务器上创建的词组和规则，上传失败时用于回滚
+type runTracker struct {
+	createdGroups []models.WordGroup
+	createdUnits  []models.WordUnit
+	audit         *auditLog // 不为空时删除操作写入审计日志
+	undoOf        string    // 撤销的运行ID，为空表示回滚本次运行
+}
+
+// GroupCreated 实现 services.ChangeRecorder
+func (t *runTracker) GroupCreated(group models.WordGroup) {
+	t.createdGroups = append(t.createdGroups, group)
+}
+
+// UnitCreated 实现 services.ChangeRecorder
+func (t *runTracker) UnitCreated(unit models.WordUnit) {
+	t.createdUnits = append(t.createdUnits, unit)
+}
+
+// isEmpty 判断本次运行是否尚未创建任何内容
+func (t *runTracker) isEmpty() bool {
+	return len(t.createdGroups) == 0 && len(t.createdUnits) == 0
+}
+
+// rollback 按创建的逆序删除本次创建的规则，再删除本次创建的词组，返回删除失败的数量
+func (t *runTracker) rollback(wordGroupService *services.WordGroupService) int {
+	undoOf := t.undoOf
+	if undoOf == "" && t.audit != nil {
+		undoOf = t.audit.runID
+	}
+
+	failed := 0
+	for i := len(t.createdUnits) - 1; i >= 0; i-- {
+		unit := t.createdUnits[i]
+		if unit.ID == 0 {
+			fmt.Printf("规则 %s 缺少ID，无法回滚\n", unit.BeReplaced)
+			failed++
+			continue
+		}
+		if err := wordGroupService.DeleteWordUnit(unit.ID); err != nil {
+			fmt.Printf("删除规则 %s（ID: %d）失败: %v\n", unit.BeReplaced, unit.ID, err)
+			failed++
+			continue
+		}
+		fmt.Printf("已删除规则：%s（ID: %d）\n", unit.BeReplaced, unit.ID)
+		if t.audit != nil {
+			t.audit.unitDeleted(unit, undoOf)
+		}
+	}
+
+	for i := len(t.createdGroups) - 1; i >= 0; i-- {
+		group := t.createdGroups[i]
+		// 撤销之前的运行时，词组中可能有之后的运行添加的规则，此时保留词组
+		if t.undoOf != "" {
+			units, err := wordGroupService.ListWordUnits(group.ID)
+			if err != nil {
+				fmt.Printf("获取词组 %s（ID: %d）的规则失败: %v\n", group.Title, group.ID, err)
+				failed++
+				continue
+			}
+			if len(units) > 0 {
+				fmt.Printf("词组 %s（ID: %d）中还有 %d 条其他运行添加的规则，保留词组\n", group.Title, group.ID, len(units))
+				continue
+			}
+		}
+		if err := wordGroupService.DeleteWordGroup(group.ID); err != nil {
+			fmt.Printf("删除词组 %s（ID: %d）失败: %v\n", group.Title, group.ID, err)
+			failed++
+			continue
+		}
+		fmt.Printf("已删除词组：%s（ID: %d）\n", group.Title, group.ID)
+		if t.audit != nil {
+			t.audit.groupDeleted(group, undoOf)
+		}
+	}
+
+	return failed
+}
+
+// handleFailure 上传失败时报告本次已创建的内容，并按配置自动回滚或询问用户是否回滚，返回原始错误
+func (t *runTracker) handleFailure(wordGroupService *services.WordGroupService, uploadErr error) error {
+	if t.isEmpty() {
+		return uploadErr
+	}
+
+	fmt.Printf("\n上传失败: %v\n", uploadErr)
+	fmt.Printf("本次运行已创建 %d 个词组、%d 条规则\n", len(t.createdGroups), len(t.createdUnits))
+
+	doRollback := utils.IsAutoRollbackEnabled()
+	if !doRollback {
+		var err error
+		doRollback, err = utils.GetRollbackChoice(wordGroupService.Name())
+		if err != nil {
+			return fmt.Errorf("%v（读取回滚选择失败: %v）", uploadErr, err)
+		}
+	}
+
+	if !doRollback {
+		fmt.Println("未回滚，服务器上保留了本次已创建的词组和规则")
+		return uploadErr
+	}
+
+	fmt.Printf("\n=== 回滚 ===\n")
+	if failed := t.rollback(wordGroupService); failed > 0 {
+		fmt.Printf("回滚未完成，有 %d 项删除失败，请手动处理\n", failed)
+	} else {
+		fmt.Println("回滚完成，本次创建的词组和规则已全部删除")
+	}
+	return uploadErr
+}
@@ -6,6 +6,8 @@ SCRIPT_DIR="$( cd "$( dirname "${BASH_SOURCE[0]}" )" && pwd )"
 PROJECT_ROOT="$( cd "$SCRIPT_DIR/.." && pwd )"
 # 设置输出目录
 OUTPUT_DIR="$PROJECT_ROOT/dist"
+# 版本号，写入程序（配方中记录生成时的版本）和打包文件名
+VERSION="v1.0.5"
 
 echo "Building from $PROJECT_ROOT"
 echo "Output directory: $OUTPUT_DIR"
@@ -35,13 +37,13 @@ build_binary() {
 
     if [ "$cmd" = "rename" ]; then
         # 构建主程序
-        go build -o "$OUTPUT_DIR/${binary_name}-${os}-${arch}${ext}" "$PROJECT_ROOT"
+        go build -ldflags "-X main.version=$VERSION" -o "$OUTPUT_DIR/${binary_name}-${os}-${arch}${ext}" "$PROJECT_ROOT"
         
         # 打包文件
         if [ "$os" = "windows" ]; then
-            (cd "$OUTPUT_DIR" && zip "${binary_name}-v1.0.5-${os}-${arch}.zip" "${binary_name}-${os}-${arch}${ext}" "$PROJECT_ROOT/README.md" "$PROJECT_ROOT/.env.example")
+            (cd "$OUTPUT_DIR" && zip "${binary_name}-${VERSION}-${os}-${arch}.zip" "${binary_name}-${os}-${arch}${ext}" "$PROJECT_ROOT/README.md" "$PROJECT_ROOT/.env.example")
         else
-            tar -czf "$OUTPUT_DIR/${binary_name}-v1.0.5-${os}-${arch}.tar.gz" -C "$OUTPUT_DIR" "${binary_name}-${os}-${arch}" -C "$PROJECT_ROOT" "README.md" ".env.example"
+            tar -czf "$OUTPUT_DIR/${binary_name}-${VERSION}-${os}-${arch}.tar.gz" -C "$OUTPUT_DIR" "${binary_name}-${os}-${arch}" -C "$PROJECT_ROOT" "README.md" ".env.example"
         fi
     elif [ "$cmd" = "list" ]; then
         # 构建list命令
@@ -49,9 +51,9 @@ build_binary() {
         
         # 打包文件
         if [ "$os" = "windows" ]; then
-            (cd "$OUTPUT_DIR" && zip "${list_binary_name}-v1.0.5-${os}-${arch}.zip" "${list_binary_name}-${os}-${arch}${ext}")
+            (cd "$OUTPUT_DIR" && zip "${list_binary_name}-${VERSION}-${os}-${arch}.zip" "${list_binary_name}-${os}-${arch}${ext}")
         else
-            tar -czf "$OUTPUT_DIR/${list_binary_name}-v1.0.5-${os}-${arch}.tar.gz" -C "$OUTPUT_DIR" "${list_binary_name}-${os}-${arch}"
+            tar -czf "$OUTPUT_DIR/${list_binary_name}-${VERSION}-${os}-${arch}.tar.gz" -C "$OUTPUT_DIR" "${list_binary_name}-${os}-${arch}"
         fi
     fi
 
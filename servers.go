@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/harry/rename-by-tmdb/internal/services"
+	"github.com/harry/rename-by-tmdb/internal/utils"
+)
+
+// serverFlag 全局参数 --server 指定的服务器名称，多个用逗号分隔，all 表示所有服务器
+var serverFlag string
+
+// extractServerFlag 从命令行参数中取出全局参数 --server（可出现在任意位置），返回其余参数
+func extractServerFlag(args []string) ([]string, string, error) {
+	var rest []string
+	server := ""
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--server" || arg == "-server":
+			if i+1 >= len(args) {
+				return nil, "", fmt.Errorf("--server 需要指定服务器名称")
+			}
+			server = args[i+1]
+			i++
+		case strings.HasPrefix(arg, "--server="):
+			server = strings.TrimPrefix(arg, "--server=")
+		case strings.HasPrefix(arg, "-server="):
+			server = strings.TrimPrefix(arg, "-server=")
+		default:
+			rest = append(rest, arg)
+		}
+	}
+	return rest, server, nil
+}
+
+// serverFailure 表示无法使用的服务器及原因（如缺少地址或令牌）
+type serverFailure struct {
+	Name string
+	Err  error
+}
+
+// newWordGroupServices 为 --server 选择的每个服务器创建词组服务
+// 配置不完整的服务器列在返回的 failures 中，不影响其他服务器
+func newWordGroupServices() ([]*services.WordGroupService, []serverFailure, error) {
+	profiles, err := utils.SelectServerProfiles(serverFlag)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var wordGroupServices []*services.WordGroupService
+	var failures []serverFailure
+	for _, profile := range profiles {
+		wordGroupService, err := services.NewWordGroupServiceForProfile(profile)
+		if err != nil {
+			failures = append(failures, serverFailure{Name: profile.Name, Err: err})
+			continue
+		}
+		wordGroupServices = append(wordGroupServices, wordGroupService)
+	}
+	return wordGroupServices, failures, nil
+}
+
+// newWordGroupService 为 --server 选择的服务器创建词组服务，只允许选择一个服务器
+func newWordGroupService() (*services.WordGroupService, error) {
+	wordGroupServices, failures, err := newWordGroupServices()
+	if err != nil {
+		return nil, err
+	}
+	if len(wordGroupServices) == 0 && len(failures) == 1 {
+		return nil, failures[0].Err
+	}
+	if len(wordGroupServices) != 1 || len(failures) > 0 {
+		return nil, fmt.Errorf("该命令只能用于一个服务器，请使用 --server 指定")
+	}
+	return wordGroupServices[0], nil
+}
+
+// newWordGroupServiceForURL 创建指定地址的服务器的词组服务，用于执行计划文件、撤销等记录了服务器地址的操作
+// 指定了 --server 时使用指定的服务器，否则按地址查找配置的服务器
+func newWordGroupServiceForURL(apiBaseURL string) (*services.WordGroupService, error) {
+	if serverFlag != "" {
+		return newWordGroupService()
+	}
+
+	profiles, err := utils.GetServerProfiles()
+	if err != nil {
+		return nil, err
+	}
+	for _, profile := range profiles {
+		wordGroupService, err := services.NewWordGroupServiceForProfile(profile)
+		if err != nil {
+			continue
+		}
+		if wordGroupService.BaseURL() == apiBaseURL {
+			return wordGroupService, nil
+		}
+	}
+	return nil, fmt.Errorf("未配置地址为 %s 的服务器", apiBaseURL)
+}
@@ -0,0 +1,291 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/harry/rename-by-tmdb/internal/models"
+	"github.com/harry/rename-by-tmdb/internal/services"
+	"github.com/harry/rename-by-tmdb/internal/utils"
+)
+
+// specialMarkerRegexp 匹配特别篇标记：SP01、OVA1、番外1 或小数集数 12.5
+var specialMarkerRegexp = regexp.MustCompile(`^(?i:(SP|OVA|番外)(\d+)|(\d+)\.(\d+))$`)
+
+// specialMapping 表示文件名中的特别篇标记到TMDB第0季某一集的映射
+type specialMapping struct {
+	Marker  string // 特别篇标记，如 SP01、OVA1、番外1、12.5
+	Episode models.TMDBEpisode
+}
+
+// specialMarkerPattern 根据特别篇标记生成匹配文件名的正则片段，single表示该类标记只有一个，此时编号可省略
+func specialMarkerPattern(marker string, single bool) (string, error) {
+	matches := specialMarkerRegexp.FindStringSubmatch(marker)
+	if matches == nil {
+		return "", fmt.Errorf("无效的特别篇标记 '%s'，应为 SP01、OVA1、番外1 或 12.5 等格式", marker)
+	}
+
+	// 小数集数：12.5
+	if matches[3] != "" {
+		return fmt.Sprintf("(?:^|[^0-9.])(?:E|Ep|EP|[Ee]pisode|[Ee]p|第)?0*%s\\.%s(?:[^0-9]|$)", matches[3], matches[4]), nil
+	}
+
+	number, _ := strconv.Atoi(matches[2])
+	var keyword string
+	switch strings.ToUpper(matches[1]) {
+	case "SP":
+		// 英文关键字前后都不能是字母，避免匹配 Spider、Spring、Nova 等单词的一部分
+		keyword = "(?:^|[^A-Za-z])(?:SP|Sp|sp|SPECIAL|Special|special)"
+	case "OVA":
+		keyword = "(?:^|[^A-Za-z])(?:OVA|Ova|ova|OAD|Oad|oad)"
+	default:
+		keyword = "番外(?:篇)?"
+	}
+
+	if single {
+		// 只有一个该类标记时编号可省略，省略编号时关键字后不能紧跟字母或数字
+		return fmt.Sprintf("%s(?:[ ._-]?0*%d(?:[^0-9]|$)|(?:[^0-9A-Za-z]|$))", keyword, number), nil
+	}
+	return fmt.Sprintf("%s[ ._-]?0*%d(?:[^0-9]|$)", keyword, number), nil
+}
+
+// specialMarkerKind 返回特别篇标记的类别（SP、OVA、番外或小数）
+func specialMarkerKind(marker string) string {
+	matches := specialMarkerRegexp.FindStringSubmatch(marker)
+	if matches == nil {
+		return ""
+	}
+	if matches[3] != "" {
+		return "decimal"
+	}
+	return strings.ToUpper(matches[1])
+}
+
+// buildSpecialMappings 根据第0季的集名和播出日期生成默认的特别篇映射
+// SP编号对应第0季集数；集名包含OVA/OAD或番外的按出现顺序编号；有播出日期的按其前一个正片集数生成小数集数
+func buildSpecialMappings(specials []models.TMDBEpisode, regularEpisodes []models.TMDBEpisode) []specialMapping {
+	// 正片按播出日期排序，用于查找特别篇之前播出的最后一集
+	var aired []models.TMDBEpisode
+	for _, episode := range regularEpisodes {
+		if episode.AirDate != "" {
+			aired = append(aired, episode)
+		}
+	}
+	sort.SliceStable(aired, func(i, j int) bool {
+		return aired[i].AirDate < aired[j].AirDate
+	})
+
+	var mappings []specialMapping
+	ovaCount, extraCount := 0, 0
+	decimalCount := make(map[int]int)
+	for _, special := range specials {
+		mappings = append(mappings, specialMapping{
+			Marker:  fmt.Sprintf("SP%02d", special.EpisodeNumber),
+			Episode: special,
+		})
+
+		name := strings.ToUpper(special.Name)
+		if strings.Contains(name, "OVA") || strings.Contains(name, "OAD") {
+			ovaCount++
+			mappings = append(mappings, specialMapping{
+				Marker:  fmt.Sprintf("OVA%d", ovaCount),
+				Episode: special,
+			})
+		} else if strings.Contains(special.Name, "番外") {
+			extraCount++
+			mappings = append(mappings, specialMapping{
+				Marker:  fmt.Sprintf("番外%d", extraCount),
+				Episode: special,
+			})
+		}
+
+		if special.AirDate == "" {
+			continue
+		}
+
+		// 找到特别篇播出前（含同日）播出的最后一集正片
+		index := sort.Search(len(aired), func(i int) bool {
+			return aired[i].AirDate > special.AirDate
+		})
+		if index == 0 {
+			continue
+		}
+		previous := aired[index-1].EpisodeNumber
+		decimalCount[previous]++
+		if decimalCount[previous] > 4 {
+			continue
+		}
+		mappings = append(mappings, specialMapping{
+			Marker:  fmt.Sprintf("%d.%d", previous, 4+decimalCount[previous]),
+			Episode: special,
+		})
+	}
+
+	return mappings
+}
+
+// printSpecialMappings 显示特别篇映射表
+func printSpecialMappings(out io.Writer, specials []models.TMDBEpisode, mappings []specialMapping) {
+	fmt.Fprintf(out, "\n=== 特别篇映射表 ===\n")
+	fmt.Fprintf(out, "%-8s %-12s %-24s %s\n", "集数", "播出日期", "标记", "名称")
+	for _, special := range specials {
+		var markers []string
+		for _, mapping := range mappings {
+			if mapping.Episode.EpisodeNumber == special.EpisodeNumber {
+				markers = append(markers, mapping.Marker)
+			}
+		}
+		airDate := special.AirDate
+		if airDate == "" {
+			airDate = "-"
+		}
+		fmt.Fprintf(out, "S00E%02d   %-12s %-24s %s\n", special.EpisodeNumber, airDate, strings.Join(markers, ", "), special.Name)
+	}
+}
+
+// applySpecialOverrides 应用用户输入的映射修改（格式为 标记=集数，多个用;分隔），集数为0表示删除该标记
+func applySpecialOverrides(mappings []specialMapping, specials []models.TMDBEpisode, input string) ([]specialMapping, error) {
+	episodes := make(map[int]models.TMDBEpisode)
+	for _, special := range specials {
+		episodes[special.EpisodeNumber] = special
+	}
+
+	for _, override := range strings.Split(input, ";") {
+		override = strings.TrimSpace(override)
+		if override == "" {
+			continue
+		}
+
+		parts := strings.Split(override, "=")
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("无效的格式 '%s'，应为 '标记=集数'", override)
+		}
+
+		marker := strings.TrimSpace(parts[0])
+		if _, err := specialMarkerPattern(marker, false); err != nil {
+			return nil, err
+		}
+		episodeNum, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+		if err != nil {
+			return nil, fmt.Errorf("无效的集数 '%s': %v", parts[1], err)
+		}
+
+		// 先移除该标记原有的映射
+		var kept []specialMapping
+		for _, mapping := range mappings {
+			if !strings.EqualFold(mapping.Marker, marker) {
+				kept = append(kept, mapping)
+			}
+		}
+		mappings = kept
+
+		if episodeNum == 0 {
+			continue
+		}
+		episode, exists := episodes[episodeNum]
+		if !exists {
+			return nil, fmt.Errorf("第0季不存在第%d集", episodeNum)
+		}
+		mappings = append(mappings, specialMapping{Marker: marker, Episode: episode})
+	}
+
+	return mappings, nil
+}
+
+// handleSpecials 特别篇模式：按集名和播出日期将文件名中的特别篇标记映射到第0季的具体集数
+func handleSpecials(tmdbService *services.TMDBService, out io.Writer,
+	show *models.TMDBShow, naming mediaNaming, fileTitle string) ([]generatedRule, error) {
+	// 获取第0季及所有正片季的集数信息
+	var specials, regularEpisodes []models.TMDBEpisode
+	for _, season := range show.Seasons {
+		seasonDetails, err := tmdbService.FetchSeasonDetails(naming.TMDBID, season.SeasonNumber)
+		if err != nil {
+			fmt.Fprintf(out, "获取第 %d 季信息失败: %v\n", season.SeasonNumber, err)
+			continue
+		}
+		if season.SeasonNumber == 0 {
+			specials = seasonDetails.Episodes
+		} else {
+			regularEpisodes = append(regularEpisodes, seasonDetails.Episodes...)
+		}
+	}
+
+	if len(specials) == 0 {
+		return nil, fmt.Errorf("该剧集在TMDB中没有特别篇（第0季）")
+	}
+
+	// 生成默认映射并由用户确认
+	mappings := buildSpecialMappings(specials, regularEpisodes)
+	var changes []string
+	for {
+		printSpecialMappings(out, specials, mappings)
+
+		input, err := utils.GetSpecialMappingChange()
+		if err != nil {
+			return nil, fmt.Errorf("错误: %v", err)
+		}
+		if input == "" || strings.EqualFold(input, "y") {
+			break
+		}
+
+		changed, err := applySpecialOverrides(mappings, specials, input)
+		if err != nil {
+			// 重放时配方中的修改无效，无法重新输入
+			if utils.IsReplaying() {
+				return nil, fmt.Errorf("错误: %v", err)
+			}
+			fmt.Fprintf(out, "%v，请重新输入\n", err)
+			continue
+		}
+		mappings = changed
+		changes = append(changes, input)
+
+		// 重放时配方中记录的是全部修改，应用一次即可
+		if utils.IsReplaying() {
+			printSpecialMappings(out, specials, mappings)
+			break
+		}
+	}
+	utils.RecordSpecialMappingChanges(changes)
+
+	// 统计每类标记的数量，只有一个时编号可省略（如 OVA）
+	kindCount := make(map[string]int)
+	for _, mapping := range mappings {
+		kindCount[specialMarkerKind(mapping.Marker)]++
+	}
+
+	var rules []generatedRule
+	fmt.Fprintf(out, "\n=== %s 特别篇重命名正则表达式 ===\n", show.Name)
+	for _, mapping := range mappings {
+		// 被替换词：标题+特别篇标记；替换词：剧集名称.S00E集数.年份.{[tmdbid=ID;type=tv]}
+		kind := specialMarkerKind(mapping.Marker)
+		rule, err := buildRule(naming, models.UnitNote{
+			Mode:      models.NoteModeSpecial,
+			FileTitle: fileTitle,
+			Episode:   mapping.Episode.EpisodeNumber,
+			Marker:    mapping.Marker,
+			Single:    kind != "decimal" && kindCount[kind] == 1,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		fmt.Fprintf(out, "\n%s → S00E%02d %s:\n", mapping.Marker, mapping.Episode.EpisodeNumber, mapping.Episode.Name)
+		fmt.Fprintf(out, "被替换词：\n%s\n", rule.Unit.BeReplaced)
+		fmt.Fprintf(out, "替换词：\n%s\n", rule.Unit.Replace)
+
+		// 收集替换规则，生成完成后统一上传
+		rules = append(rules, rule)
+	}
+
+	fmt.Fprintln(out, "\n注意：")
+	fmt.Fprintln(out, "1. 特别篇模式：按第0季的集名和播出日期，将 SP、OVA、Special、番外 等标记映射到具体的 S00Exx")
+	fmt.Fprintln(out, "2. 小数集数（如 12.5）按播出日期映射到该集之后播出的特别篇")
+	fmt.Fprintln(out, "3. 同类标记只有一个时，文件名中可省略编号（如 OVA）")
+
+	return rules, nil
+}
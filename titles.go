@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/harry/rename-by-tmdb/internal/models"
+	"github.com/harry/rename-by-tmdb/internal/services"
+	"github.com/harry/rename-by-tmdb/internal/utils"
+)
+
+// titleEpisode 表示参与集名匹配的一集
+type titleEpisode struct {
+	SeasonNumber int
+	Episode      models.TMDBEpisode
+	Titles       []string // 中文集名及其他语言的集名
+}
+
+// handleEpisodeTitles 集名匹配模式：为每一集生成一条匹配规范化集名的替换规则
+func handleEpisodeTitles(tmdbService *services.TMDBService, out io.Writer,
+	show *models.TMDBShow, naming mediaNaming, fileTitle string) ([]generatedRule, error) {
+	languages, err := utils.GetTitleLanguages()
+	if err != nil {
+		return nil, fmt.Errorf("错误: %v", err)
+	}
+
+	specificSeasons, generateAllSeasons, err := utils.GetSpecificSeasons()
+	if err != nil {
+		return nil, fmt.Errorf("错误: %v", err)
+	}
+	var includeSpecialSeason bool
+	if generateAllSeasons {
+		includeSpecialSeason, err = utils.GetIncludeSpecialSeason()
+		if err != nil {
+			return nil, fmt.Errorf("错误: %v", err)
+		}
+	}
+
+	// 收集所有需要生成规则的集数及其集名
+	var episodes []titleEpisode
+	for _, season := range show.Seasons {
+		if !generateAllSeasons && !containsInt(specificSeasons, season.SeasonNumber) {
+			continue
+		}
+		if season.SeasonNumber == 0 && generateAllSeasons && !includeSpecialSeason {
+			continue
+		}
+
+		seasonDetails, err := tmdbService.FetchSeasonWithTranslations(naming.TMDBID, season.SeasonNumber, languages)
+		if err != nil {
+			fmt.Fprintf(out, "获取第 %d 季信息失败: %v\n", season.SeasonNumber, err)
+			continue
+		}
+
+		for _, episode := range seasonDetails.Episodes {
+			var titles []string
+			if episode.Name != "" && !utils.IsGenericEpisodeName(episode.Name) {
+				titles = append(titles, episode.Name)
+			}
+			for _, language := range languages {
+				name := episode.Translations[language]
+				if name != "" && !utils.IsGenericEpisodeName(name) {
+					titles = append(titles, name)
+				}
+			}
+
+			if len(titles) == 0 {
+				fmt.Fprintf(out, "第 %d 季第%d集：没有可用的集名，跳过\n", season.SeasonNumber, episode.EpisodeNumber)
+				continue
+			}
+			episodes = append(episodes, titleEpisode{
+				SeasonNumber: season.SeasonNumber,
+				Episode:      episode,
+				Titles:       titles,
+			})
+		}
+	}
+
+	if len(episodes) == 0 {
+		return nil, fmt.Errorf("没有找到任何可用于匹配的集名")
+	}
+
+	// 检查规范化后的集名是否冲突
+	owners := make(map[string][]string)
+	for _, episode := range episodes {
+		label := fmt.Sprintf("S%02dE%02d", episode.SeasonNumber, episode.Episode.EpisodeNumber)
+		seen := make(map[string]bool)
+		for _, title := range episode.Titles {
+			normalized := utils.NormalizeTitle(title)
+			if normalized == "" || seen[normalized] {
+				continue
+			}
+			seen[normalized] = true
+			owners[normalized] = append(owners[normalized], label)
+		}
+	}
+	var collisions []string
+	for normalized, labels := range owners {
+		if len(labels) > 1 {
+			collisions = append(collisions, normalized)
+		}
+	}
+	sort.Strings(collisions)
+	for _, normalized := range collisions {
+		fmt.Fprintf(out, "警告：集名 \"%s\" 在 %s 中重复，这些集数的规则会互相冲突\n",
+			normalized, strings.Join(owners[normalized], "、"))
+	}
+
+	var rules []generatedRule
+	fmt.Fprintf(out, "\n=== %s 集名匹配重命名正则表达式 ===\n", show.Name)
+	for _, episode := range episodes {
+		// 被替换词：标题+规范化集名（忽略大小写和分隔符）；替换词：剧集名称.S季数E集数.年份.{[tmdbid=ID;type=tv]}
+		rule, err := buildRule(naming, models.UnitNote{
+			Mode:      models.NoteModeTitle,
+			FileTitle: fileTitle,
+			Season:    episode.SeasonNumber,
+			Episode:   episode.Episode.EpisodeNumber,
+			Titles:    episode.Titles,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		fmt.Fprintf(out, "\n第 %d 季第%d集 (%s):\n", episode.SeasonNumber, episode.Episode.EpisodeNumber, strings.Join(episode.Titles, " / "))
+		fmt.Fprintf(out, "被替换词：\n%s\n", rule.Unit.BeReplaced)
+		fmt.Fprintf(out, "替换词：\n%s\n", rule.Unit.Replace)
+
+		// 收集替换规则，生成完成后统一上传
+		rules = append(rules, rule)
+	}
+
+	fmt.Fprintln(out, "\n注意：")
+	fmt.Fprintln(out, "1. 集名匹配模式：按TMDB集名匹配文件名，每集生成独立的替换规则")
+	fmt.Fprintln(out, "2. 集名匹配忽略大小写，单词之间允许空格、点号等任意分隔符")
+	fmt.Fprintln(out, "3. TMDB占位集名（如\"第 5 集\"）无法用于匹配，对应集数将被跳过")
+
+	return rules, nil
+}
+
+// containsInt 判断切片中是否包含指定整数
+func containsInt(values []int, target int) bool {
+	for _, value := range values {
+		if value == target {
+			return true
+		}
+	}
+	return false
+}
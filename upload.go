@@ -0,0 +1,212 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/harry/rename-by-tmdb/internal/models"
+	"github.com/harry/rename-by-tmdb/internal/services"
+	"github.com/harry/rename-by-tmdb/internal/utils"
+)
+
+// generatedRule 表示一条生成的替换规则
+type generatedRule struct {
+	Label string // 规则说明，如"第 1 季"
+	Unit  models.WordUnit
+}
+
+// ruleSet 表示一次运行生成的词组及其替换规则
+type ruleSet struct {
+	Title     string // 词组标题（命名格式）
+	TMDBID    string
+	MediaType string // movie 或 tv
+	FileTitle string // 原文件名中的标题部分
+	Rules     []generatedRule
+	Recipe    *recipe // 本次生成的配方，运行结束后写入配方目录
+}
+
+// newRule 构建一条生成的替换规则，所属词组在上传时确定
+func newRule(label, beReplaced, replace, front, back string, offset int) generatedRule {
+	return generatedRule{
+		Label: label,
+		Unit:  services.NewWordUnit(0, beReplaced, replace, front, back, offset),
+	}
+}
+
+// units 返回规则集中的所有替换规则，并设置所属词组
+func (r *ruleSet) units(groupID int) []models.WordUnit {
+	var units []models.WordUnit
+	for _, rule := range r.Rules {
+		unit := rule.Unit
+		unit.WordGroupID = groupID
+		units = append(units, unit)
+	}
+	return units
+}
+
+// uploadRules 上传生成的替换规则：词组不存在时创建词组并添加所有规则，已存在时按用户选择同步或追加
+// 选择了多个服务器时依次上传到每个服务器，某个服务器失败不影响其他服务器
+func uploadRules(set *ruleSet) error {
+	if !utils.IsUploadEnabled() {
+		return nil
+	}
+	if len(set.Rules) == 0 {
+		fmt.Println("\n没有生成任何替换规则，跳过上传")
+		return nil
+	}
+
+	wordGroupServices, failures, err := newWordGroupServices()
+	if err != nil {
+		return fmt.Errorf("创建词组服务失败: %v", err)
+	}
+	if len(wordGroupServices) == 1 && len(failures) == 0 {
+		return uploadToServer(wordGroupServices[0], set)
+	}
+	for _, failure := range failures {
+		fmt.Printf("\n服务器 %s 配置无效，跳过: %v\n", failure.Name, failure.Err)
+	}
+
+	errs := make([]error, len(wordGroupServices))
+	for i, wordGroupService := range wordGroupServices {
+		fmt.Printf("\n=== 上传到服务器：%s（%s）===\n", wordGroupService.Name(), wordGroupService.BaseURL())
+		errs[i] = uploadToServer(wordGroupService, set)
+		if errs[i] != nil {
+			fmt.Printf("服务器 %s 上传失败: %v\n", wordGroupService.Name(), errs[i])
+		}
+	}
+
+	fmt.Printf("\n=== 各服务器上传结果 ===\n")
+	failed := len(failures)
+	for _, failure := range failures {
+		fmt.Printf("%s：失败（%v）\n", failure.Name, failure.Err)
+	}
+	for i, wordGroupService := range wordGroupServices {
+		if errs[i] != nil {
+			failed++
+			fmt.Printf("%s：失败（%v）\n", wordGroupService.Name(), errs[i])
+		} else {
+			fmt.Printf("%s：成功\n", wordGroupService.Name())
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d 个服务器上传失败", failed)
+	}
+	return nil
+}
+
+// uploadToServer 将规则上传到一个服务器，并记录本次创建的内容以便失败时回滚和事后撤销
+func uploadToServer(wordGroupService *services.WordGroupService, set *ruleSet) error {
+	tracker, audit := newRunRecorders(wordGroupService)
+	if audit != nil {
+		audit.setMedia(set.TMDBID, set.MediaType)
+	}
+
+	if err := uploadRuleSet(wordGroupService, set); err != nil {
+		return tracker.handleFailure(wordGroupService, err)
+	}
+	printRunID(tracker, audit)
+	return nil
+}
+
+// uploadRuleSet 查找或创建词组，并追加或同步规则
+func uploadRuleSet(wordGroupService *services.WordGroupService, set *ruleSet) error {
+	namingFormat, rules := set.Title, set.Rules
+
+	// 查找是否存在相同的命名格式
+	wordGroup, err := findExistingWordGroup(wordGroupService, namingFormat)
+	if err != nil {
+		return err
+	}
+
+	if wordGroup == nil {
+		// 创建新词组
+		wordGroup, err = wordGroupService.CreateWordGroup(namingFormat)
+		if err != nil {
+			return fmt.Errorf("创建词组失败: %v", err)
+		}
+		fmt.Printf("\n词组创建成功，ID: %d\n", wordGroup.ID)
+		return appendRules(wordGroupService, wordGroup, rules)
+	}
+
+	fmt.Printf("\n使用已存在的词组，ID: %d\n", wordGroup.ID)
+	useSync, err := utils.GetSyncChoice(wordGroupService.Name())
+	if err != nil {
+		return fmt.Errorf("错误: %v", err)
+	}
+	if !useSync {
+		return appendRules(wordGroupService, wordGroup, rules)
+	}
+	return syncRules(wordGroupService, wordGroup, set)
+}
+
+// appendRules 将所有规则直接追加到词组
+func appendRules(wordGroupService *services.WordGroupService, wordGroup *models.WordGroup, rules []generatedRule) error {
+	var units []models.WordUnit
+	for _, rule := range rules {
+		units = append(units, rule.Unit)
+	}
+	created, err := wordGroupService.CreateWordUnits(wordGroup.ID, units)
+	for i := range created {
+		fmt.Printf("%s替换规则上传成功\n", rules[i].Label)
+	}
+	if err != nil && len(created) < len(rules) {
+		return fmt.Errorf("上传%s替换规则失败: %v", rules[len(created)].Label, err)
+	}
+	return err
+}
+
+// syncRules 比较生成的规则与词组现有规则，新增缺失的、更新变化的，并删除或禁用过期的
+func syncRules(wordGroupService *services.WordGroupService, wordGroup *models.WordGroup, set *ruleSet) error {
+	existing, err := wordGroupService.ListWordUnits(wordGroup.ID)
+	if err != nil {
+		return fmt.Errorf("获取词组现有规则失败: %v", err)
+	}
+
+	adoption := newUnitAdoption()
+	plan := services.DiffWordUnitsWithKey(existing, set.units(wordGroup.ID), adoption.key)
+
+	// 显示同步计划
+	fmt.Printf("\n=== 同步计划 ===\n")
+	for _, unit := range plan.Create {
+		fmt.Printf("新增：%s → %s\n", unit.BeReplaced, unit.Replace)
+	}
+	for _, unit := range plan.Update {
+		fmt.Printf("更新：%s → %s\n", unit.BeReplaced, unit.Replace)
+	}
+	for _, unit := range plan.Obsolete {
+		fmt.Printf("过期：%s → %s\n", unit.BeReplaced, unit.Replace)
+	}
+	printUnrecognized(adoption.unrecognized, "")
+	fmt.Printf("新增 %d 条，更新 %d 条，过期 %d 条，未变化 %d 条，无法识别 %d 条（按过期规则处理）\n",
+		len(plan.Create), len(plan.Update), len(plan.Obsolete), len(plan.Unchanged), len(adoption.unrecognized))
+
+	obsoleteAction := services.ObsoleteDisable
+	if len(plan.Obsolete) > 0 {
+		deleteObsolete, err := utils.GetDeleteObsoleteChoice(wordGroupService.Name())
+		if err != nil {
+			return fmt.Errorf("错误: %v", err)
+		}
+		if deleteObsolete {
+			obsoleteAction = services.ObsoleteDelete
+		}
+	}
+
+	result, err := wordGroupService.ApplySyncPlan(wordGroup.ID, plan, obsoleteAction)
+	printSyncResult(result)
+	if err != nil {
+		return fmt.Errorf("同步规则失败: %v", err)
+	}
+	return nil
+}
+
+// printSyncResult 显示同步结果汇总
+func printSyncResult(result *services.SyncResult) {
+	if result == nil {
+		return
+	}
+	fmt.Printf("\n=== 同步结果 ===\n")
+	fmt.Printf("新增：%d 条\n", result.Created)
+	fmt.Printf("更新：%d 条\n", result.Updated)
+	fmt.Printf("删除：%d 条\n", result.Deleted)
+	fmt.Printf("禁用：%d 条\n", result.Disabled)
+	fmt.Printf("未变化：%d 条\n", result.Unchanged)
+}
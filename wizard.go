@@ -0,0 +1,546 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/harry/rename-by-tmdb/internal/models"
+	"github.com/harry/rename-by-tmdb/internal/services"
+	"github.com/harry/rename-by-tmdb/internal/utils"
+)
+
+// 向导中媒体类型、TMDB ID 和文件标题的标识，这三项记录在配方中而不是回答中
+const (
+	wizardMediaType = "media_type"
+	wizardTMDBID    = "tmdb_id"
+	wizardFileTitle = "file_title"
+)
+
+// wizardRecentSteps 向导中显示的最近已回答步骤数
+const wizardRecentSteps = 6
+
+// wizardContextLines 每个问题最多显示的说明行数
+const wizardContextLines = 10
+
+// wizardResponseCache 向导中缓存的TMDB响应条数，足够容纳一部剧集的基本信息和所有季
+const wizardResponseCache = 64
+
+// errWizardCancelled 用户按 Ctrl+C 取消向导
+var errWizardCancelled = errors.New("已取消")
+
+// wizardStep 向导中的一个步骤
+type wizardStep struct {
+	Key     string
+	Label   string   // 已回答列表中显示的名称
+	Prompt  string   // 问题
+	Context []string // 提问前的输出，如可选的日期格式、特别篇映射
+}
+
+// previewPage 预览中的一页，按季（电影、屏蔽词各为一页）分组
+type previewPage struct {
+	Title string
+	Rules []generatedRule
+}
+
+// wizard 全屏向导：步骤由生成器在重放模式下实际提出的问题决定，与交互模式的提问完全一致
+type wizard struct {
+	tmdbService *services.TMDBService
+	screen      *utils.Screen
+	identity    map[string]string // 媒体类型选项（1 或 2）、TMDB ID 和文件标题
+	answers     map[string]string // 各问题的回答，键为问题标识
+	mediaName   string            // TMDB名称，TMDB ID 校验通过后显示
+	steps       []wizardStep
+	pos         int // 当前步骤，等于步骤数时为确认步骤
+	input       string
+	inputError  string
+	preview     *ruleSet
+	previewErr  string
+	previewPage int // 预览中选择的页
+}
+
+// newWizard 创建向导，只包含媒体类型、TMDB ID 和文件标题三个步骤，其余步骤在输入这三项后生成
+func newWizard(tmdbService *services.TMDBService) *wizard {
+	w := &wizard{
+		tmdbService: tmdbService,
+		identity:    make(map[string]string),
+		answers:     make(map[string]string),
+	}
+	w.steps = w.identitySteps()
+	return w
+}
+
+// identitySteps 返回媒体类型、TMDB ID 和文件标题三个固定步骤
+func (w *wizard) identitySteps() []wizardStep {
+	idLabel, example := "剧集ID", "One.Piece"
+	if w.identity[wizardMediaType] == "1" {
+		idLabel, example = "电影ID", "The.Matrix"
+	}
+	return []wizardStep{
+		{Key: wizardMediaType, Label: "媒体类型", Prompt: "请选择媒体类型（1 电影、2 剧集）"},
+		{Key: wizardTMDBID, Label: idLabel, Prompt: "请输入" + idLabel},
+		{Key: wizardFileTitle, Label: "文件标题", Prompt: fmt.Sprintf("请输入当前文件名中的标题部分（例如：%s）", example)},
+	}
+}
+
+// value 返回步骤当前的值
+func (w *wizard) value(key string) string {
+	switch key {
+	case wizardMediaType, wizardTMDBID, wizardFileTitle:
+		return w.identity[key]
+	}
+	return w.answers[key]
+}
+
+// identityComplete 判断媒体类型、TMDB ID 和文件标题是否都已输入并校验
+func (w *wizard) identityComplete() bool {
+	return w.identity[wizardMediaType] != "" && w.mediaName != "" && w.identity[wizardFileTitle] != ""
+}
+
+// recipe 按向导中的输入创建配方，只包含实际提出的问题的回答
+func (w *wizard) recipe() *recipe {
+	r := &recipe{
+		TMDBID:    w.identity[wizardTMDBID],
+		FileTitle: w.identity[wizardFileTitle],
+		Answers:   make(map[string]string),
+	}
+	r.MediaType = "tv"
+	if w.identity[wizardMediaType] == "1" {
+		r.MediaType = "movie"
+	}
+	for _, step := range w.steps {
+		if value, exists := w.answers[step.Key]; exists {
+			r.Answers[step.Key] = value
+		}
+	}
+	return r
+}
+
+// fetchName 获取TMDB名称，同时校验ID是否存在
+func (w *wizard) fetchName(id string) (string, error) {
+	if w.identity[wizardMediaType] == "1" {
+		movie, err := w.tmdbService.FetchMovieInfo(id)
+		if err != nil {
+			return "", fmt.Errorf("获取电影信息失败: %v", err)
+		}
+		return movie.Title, nil
+	}
+	show, err := w.tmdbService.FetchShowInfo(id)
+	if err != nil {
+		return "", fmt.Errorf("获取剧集信息失败: %v", err)
+	}
+	return show.Name, nil
+}
+
+// dryRun 在重放模式下按给定的回答生成规则，生成过程中的输出和提问写入缓冲区，两次提问之间的新输出作为后一个问题的说明
+func (w *wizard) dryRun(answers map[string]string) (*ruleSet, []utils.ReplayQuestion, map[string][]string, error) {
+	var output bytes.Buffer
+	contexts := make(map[string][]string)
+	offset := 0
+	utils.OnReplayQuestion = func(key string) {
+		if _, exists := contexts[key]; !exists {
+			contexts[key] = contextLines(string(output.Bytes()[offset:]))
+		}
+		offset = output.Len()
+	}
+
+	// 重放时不读取输入，提问和回答与生成器的输出写入同一缓冲区
+	previous := utils.SetPrompter(utils.NewPrompter(strings.NewReader(""), &output))
+	activeRecipe = w.recipe()
+	utils.SetReplayAnswers(answers)
+	defer func() {
+		utils.SetPrompter(previous)
+		activeRecipe = nil
+		utils.StopReplay()
+		utils.OnReplayQuestion = nil
+	}()
+
+	set, _, err := buildRuleSet(w.tmdbService, &output)
+	return set, utils.ReplayTrace(), contexts, err
+}
+
+// contextLines 取问题之前输出的非空行，最多保留最后几行；输出的最后一行是重放时显示的问题和回答，不包括在内
+func contextLines(output string) []string {
+	var lines []string
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimRight(line, " \r")
+		if strings.TrimSpace(line) != "" {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) > 0 {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) > wizardContextLines {
+		lines = lines[len(lines)-wizardContextLines:]
+	}
+	return lines
+}
+
+// refresh 按已确认的输入重新生成规则，更新步骤列表和预览，出错时返回出错的问题标识和错误
+func (w *wizard) refresh() (string, error) {
+	set, trace, contexts, err := w.dryRun(w.answers)
+
+	w.steps = w.identitySteps()
+	seen := make(map[string]bool)
+	for _, question := range trace {
+		// 同一问题在同一范围内只显示一个步骤
+		if seen[question.Key] {
+			continue
+		}
+		seen[question.Key] = true
+		w.steps = append(w.steps, wizardStep{
+			Key:     question.Key,
+			Label:   utils.AnswerUsage(question.Key),
+			Prompt:  strings.TrimSuffix(strings.TrimSpace(question.Prompt), ":"),
+			Context: contexts[question.Key],
+		})
+	}
+
+	w.setPreview(set, trace, err)
+	if err != nil && len(trace) > 0 {
+		return trace[len(trace)-1].Key, err
+	}
+	return "", err
+}
+
+// setPreview 设置预览
+func (w *wizard) setPreview(set *ruleSet, trace []utils.ReplayQuestion, err error) {
+	w.preview, w.previewErr = set, ""
+	if err != nil {
+		w.previewErr = wizardError(err)
+		if len(trace) > 0 {
+			// 尚未回答的问题使用默认值出错时（如part剧集信息），提示先回答该问题
+			last := trace[len(trace)-1].Key
+			current := w.pos < len(w.steps) && w.steps[w.pos].Key == last
+			_, answered := w.answers[last]
+			if (!current && !answered) || (current && strings.TrimSpace(w.input) == "") {
+				w.previewErr = fmt.Sprintf("回答「%s」后显示预览", utils.AnswerUsage(last))
+			}
+		}
+	}
+	if pages := previewPages(w.preview); w.previewPage >= len(pages) {
+		w.previewPage = max(0, len(pages)-1)
+	}
+}
+
+// submit 确认当前步骤的输入：校验后重新生成规则，出错时停留在当前步骤并显示错误
+func (w *wizard) submit() {
+	step := w.steps[w.pos]
+	value := strings.TrimSpace(w.input)
+	w.inputError = ""
+
+	switch step.Key {
+	case wizardMediaType:
+		if value != "1" && value != "2" {
+			w.inputError = "无效的选项，请输入1或2"
+			return
+		}
+		if value != w.identity[wizardMediaType] {
+			w.identity[wizardMediaType] = value
+			w.mediaName = ""
+		}
+	case wizardTMDBID:
+		if value == "" {
+			w.inputError = "ID不能为空"
+			return
+		}
+		name, err := w.fetchName(value)
+		if err != nil {
+			w.inputError = err.Error()
+			return
+		}
+		w.identity[wizardTMDBID] = value
+		w.mediaName = name
+	case wizardFileTitle:
+		if value == "" {
+			w.inputError = "文件标题不能为空"
+			return
+		}
+		w.identity[wizardFileTitle] = value
+	default:
+		w.answers[step.Key] = value
+	}
+
+	if !w.identityComplete() {
+		w.steps = w.identitySteps()
+		w.preview, w.previewErr = nil, ""
+		w.moveTo(w.pos + 1)
+		return
+	}
+	if failed, err := w.refresh(); err != nil && failed == step.Key {
+		w.inputError = wizardError(err)
+		return
+	}
+	w.moveTo(w.pos + 1)
+}
+
+// wizardError 返回在向导中显示的错误，去掉生成器错误的“错误: ”前缀
+func wizardError(err error) string {
+	return strings.TrimPrefix(err.Error(), "错误: ")
+}
+
+// moveTo 移动到指定步骤，输入框显示该步骤当前的值
+func (w *wizard) moveTo(pos int) {
+	w.pos = max(0, min(pos, len(w.steps)))
+	w.inputError = ""
+	if w.pos < len(w.steps) {
+		w.input = w.value(w.steps[w.pos].Key)
+	}
+}
+
+// run 处理按键直到用户在确认步骤按回车（返回配方）或按 Ctrl+C 取消
+func (w *wizard) run() (*recipe, error) {
+	// 从配方开始时先校验并生成预览，各步骤显示配方中的值
+	if w.identity[wizardTMDBID] != "" {
+		if name, err := w.fetchName(w.identity[wizardTMDBID]); err == nil {
+			w.mediaName = name
+		}
+		if w.identityComplete() {
+			w.refresh()
+		}
+	}
+	w.moveTo(0)
+
+	for {
+		w.render()
+		key, err := w.screen.ReadKey()
+		if err != nil {
+			return nil, err
+		}
+
+		switch key.Type {
+		case utils.KeyInterrupt:
+			return nil, errWizardCancelled
+		case utils.KeyEnter:
+			if w.pos < len(w.steps) {
+				w.submit()
+			} else if w.preview != nil && w.previewErr == "" {
+				return w.recipe(), nil
+			}
+		case utils.KeyUp, utils.KeyEscape, utils.KeyBackTab:
+			if w.pos > 0 {
+				w.moveTo(w.pos - 1)
+			}
+		case utils.KeyLeft:
+			w.previewPage = max(0, w.previewPage-1)
+		case utils.KeyRight:
+			w.previewPage = min(max(0, len(previewPages(w.preview))-1), w.previewPage+1)
+		case utils.KeyBackspace:
+			if w.pos < len(w.steps) && w.input != "" {
+				runes := []rune(w.input)
+				w.input = string(runes[:len(runes)-1])
+			}
+		case utils.KeyClearLine:
+			if w.pos < len(w.steps) {
+				w.input = ""
+			}
+		case utils.KeyText:
+			if w.pos < len(w.steps) {
+				w.input += key.Text
+			}
+		}
+	}
+}
+
+// render 绘制向导：上方为已回答的步骤和当前问题，下方为生成规则的预览
+func (w *wizard) render() {
+	width, height := w.screen.Size()
+	separator := utils.ScreenLine{Text: strings.Repeat("─", width), Style: utils.StyleDim}
+
+	title := "rename-by-tmdb 向导"
+	if w.mediaName != "" {
+		title += " · " + w.mediaName
+	}
+	if w.pos < len(w.steps) {
+		title += fmt.Sprintf(" · 步骤 %d/%d", w.pos+1, len(w.steps))
+	} else {
+		title += " · 确认"
+	}
+	lines := []utils.ScreenLine{{Text: title, Style: utils.StyleBold}, separator}
+
+	cursorRow, cursorCol := -1, 0
+	footer := "Enter 下一步  ↑/Esc 上一步  ←/→ 切换预览  Ctrl+U 清空  Ctrl+C 退出"
+	if w.pos < len(w.steps) {
+		start := max(0, w.pos-wizardRecentSteps)
+		if start > 0 {
+			lines = append(lines, utils.ScreenLine{Text: fmt.Sprintf("  …（已回答 %d 步）", start), Style: utils.StyleDim})
+		}
+		for i := start; i < w.pos; i++ {
+			lines = append(lines, w.answeredLine(w.steps[i]))
+		}
+
+		step := w.steps[w.pos]
+		for _, line := range step.Context {
+			lines = append(lines, utils.ScreenLine{Text: "  " + line, Style: utils.StyleDim})
+		}
+		lines = append(lines, utils.ScreenLine{Text: "› " + step.Prompt, Style: utils.StyleAccent})
+		prompt := "  > "
+		lines = append(lines, utils.ScreenLine{Text: prompt + w.input})
+		cursorRow, cursorCol = len(lines)-1, utils.DisplayWidth(prompt+w.input)
+		if w.inputError != "" {
+			lines = append(lines, utils.ScreenLine{Text: "  ✗ " + w.inputError, Style: utils.StyleError})
+		}
+	} else {
+		footer = "Enter 确认生成  ↑/Esc 返回修改  ←/→ 切换预览  Ctrl+C 退出"
+		lines = append(lines, utils.ScreenLine{Text: "请确认以下输入，确认后生成规则（启用上传时随后上传）：", Style: utils.StyleBold})
+		start := max(0, len(w.steps)-wizardRecentSteps*2)
+		if start > 0 {
+			lines = append(lines, utils.ScreenLine{Text: fmt.Sprintf("  …（另有 %d 步）", start), Style: utils.StyleDim})
+		}
+		for _, step := range w.steps[start:] {
+			lines = append(lines, w.answeredLine(step))
+		}
+		if w.previewErr != "" {
+			lines = append(lines, utils.ScreenLine{Text: "  ✗ 生成失败，请返回修改：" + w.previewErr, Style: utils.StyleError})
+		}
+	}
+	lines = append(lines, separator)
+
+	// 预览占用剩余的高度，最后一行为按键说明
+	lines = append(lines, w.previewLines(height-len(lines)-1)...)
+	for len(lines) < height-1 {
+		lines = append(lines, utils.ScreenLine{})
+	}
+	lines = append(lines, utils.ScreenLine{Text: footer, Style: utils.StyleDim})
+	w.screen.Draw(lines, cursorRow, cursorCol)
+}
+
+// answeredLine 返回已回答步骤的显示行，空回答表示使用默认值
+func (w *wizard) answeredLine(step wizardStep) utils.ScreenLine {
+	value := w.value(step.Key)
+	switch {
+	case step.Key == wizardTMDBID && w.mediaName != "":
+		value = fmt.Sprintf("%s（%s）", value, w.mediaName)
+	case value == "":
+		value = "（默认）"
+	}
+	return utils.ScreenLine{Text: fmt.Sprintf("  ✓ %s：%s", step.Label, value)}
+}
+
+// previewLines 返回预览区的内容，最多 height 行
+func (w *wizard) previewLines(height int) []utils.ScreenLine {
+	if height <= 0 {
+		return nil
+	}
+
+	var lines []utils.ScreenLine
+	pages := previewPages(w.preview)
+	switch {
+	case w.previewErr != "":
+		lines = append(lines, utils.ScreenLine{Text: "预览：" + w.previewErr, Style: utils.StyleError})
+	case len(pages) == 0:
+		lines = append(lines, utils.ScreenLine{Text: "预览：输入媒体类型、ID 和文件标题后显示生成的规则", Style: utils.StyleDim})
+	default:
+		page := pages[w.previewPage]
+		lines = append(lines, utils.ScreenLine{
+			Text:  fmt.Sprintf("预览：%s（%d/%d）  共 %d 条规则", page.Title, w.previewPage+1, len(pages), len(w.preview.Rules)),
+			Style: utils.StyleBold,
+		})
+		for _, rule := range page.Rules {
+			lines = append(lines, utils.ScreenLine{Text: "  " + rule.Label, Style: utils.StyleAccent})
+			lines = append(lines, utils.ScreenLine{Text: "    被替换词：" + rule.Unit.BeReplaced})
+			if rule.Unit.Replace != "" {
+				lines = append(lines, utils.ScreenLine{Text: "    替换词：  " + rule.Unit.Replace})
+			}
+			if rule.Unit.Offset != "" {
+				lines = append(lines, utils.ScreenLine{Text: "    偏移量：  " + rule.Unit.Offset})
+			}
+		}
+	}
+
+	if len(lines) > height {
+		lines = lines[:height]
+		lines[height-1] = utils.ScreenLine{Text: "  …", Style: utils.StyleDim}
+	}
+	return lines
+}
+
+// previewPages 按季将规则分页，电影和屏蔽词规则各为一页，页按规则出现的顺序排列
+func previewPages(set *ruleSet) []previewPage {
+	if set == nil {
+		return nil
+	}
+
+	var pages []previewPage
+	index := make(map[string]int)
+	for _, rule := range set.Rules {
+		title := "其他规则"
+		if note, _ := models.ParseUnitNote(rule.Unit.Note); note != nil {
+			switch {
+			case note.Mode == models.NoteModeMovie:
+				title = "电影"
+			case note.Mode == models.NoteModeBlock:
+				title = "屏蔽词"
+			case note.Season == 0:
+				title = "第0季（特别篇）"
+			default:
+				title = fmt.Sprintf("第%d季", note.Season)
+			}
+		}
+		i, exists := index[title]
+		if !exists {
+			i = len(pages)
+			index[title] = i
+			pages = append(pages, previewPage{Title: title})
+		}
+		pages[i].Rules = append(pages[i].Rules, rule)
+	}
+	return pages
+}
+
+// runWizard wizard 命令：全屏向导逐步输入，可返回修改任意一步，每一步确认后预览生成的规则，确认后生成并上传
+func runWizard(tmdbService *services.TMDBService, args []string, recipePath string) error {
+	flags := flag.NewFlagSet("wizard", flag.ExitOnError)
+	flags.Parse(args)
+
+	// 每一步确认后都会重新生成规则，缓存TMDB响应避免重复请求
+	tmdbService.EnableResponseCache(wizardResponseCache)
+
+	// 可从配方或预设开始，各步骤显示其中的值
+	w := newWizard(tmdbService)
+	if recipePath != "" {
+		r, _, err := loadRecipe(recipePath)
+		if err != nil {
+			return err
+		}
+		w.identity[wizardMediaType] = r.mediaTypeOption()
+		w.identity[wizardTMDBID] = r.TMDBID
+		w.identity[wizardFileTitle] = r.FileTitle
+		for key, value := range r.Answers {
+			w.answers[key] = value
+		}
+	} else {
+		for key, value := range utils.PresetAnswers() {
+			w.answers[key] = value
+		}
+	}
+
+	screen, err := utils.OpenScreen(os.Stdin, os.Stdout)
+	if err != nil {
+		return fmt.Errorf("无法启动向导: %v，非交互环境请使用 --recipe 重放配方", err)
+	}
+	w.screen = screen
+	r, err := w.run()
+	screen.Close()
+	if err != nil {
+		return err
+	}
+
+	// 按向导中的输入正式生成规则，输出与交互模式相同；上传时的选择仍然询问
+	activeRecipe = r
+	utils.SetReplayAnswers(r.Answers)
+	set, err := generateRuleSet(tmdbService)
+	utils.StopReplay()
+	activeRecipe = nil
+	if err != nil {
+		return err
+	}
+
+	uploadErr := uploadRules(set)
+	saveRecipe(set.Recipe)
+	return uploadErr
+}